@@ -10,6 +10,8 @@ import (
 	"syscall"
 	"time"
 
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository"
 	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
 	rr "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
@@ -20,6 +22,10 @@ import (
 	jwt "github.com/DarrelA/starter-go-postgresql/internal/infrastructure/jwt"
 	envLogger "github.com/DarrelA/starter-go-postgresql/internal/infrastructure/logger"
 	logger "github.com/DarrelA/starter-go-postgresql/internal/infrastructure/logger/zerolog"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/mailer"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/oidc"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/ratelimit"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/webauthn"
 
 	interfaceSvc "github.com/DarrelA/starter-go-postgresql/internal/interface/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/interface/transport/http"
@@ -35,13 +41,18 @@ func main() {
 	logFile := envLogger.CreateAppLog("/docker_wd/logs/app.log")
 	logger.NewZeroLogger(logFile)
 	config := initializeEnv()
-	redisConn, redisUserRepo, postgresConn, postgresUserRepo := initializeDatabases(config)
+	redisConn, redisUserRepo, redisLoginAttemptRepo, redisOAuthRepo, rateLimitRepo,
+		postgresConn, postgresUserRepo, postgresRoleRepo, postgresWebAuthnRepo, postgresIdentityRepo,
+		postgresOAuthClientRepo, postgresAPIClientRepo, postgresAPIKeyRepo := initializeDatabases(config)
 
 	// Use `WaitGroup` when you just need to wait for tasks to complete without exchanging data.
 	// Use channels when you need to signal task completion and possibly exchange data.
 	var wg sync.WaitGroup
 	wg.Add(1)
-	appServiceInstance := initializeServer(&wg, config, redisUserRepo, postgresUserRepo)
+	appServiceInstance := initializeServer(
+		&wg, config, redisUserRepo, redisLoginAttemptRepo, redisOAuthRepo, rateLimitRepo,
+		postgresUserRepo, postgresRoleRepo, postgresWebAuthnRepo, postgresIdentityRepo, postgresOAuthClientRepo,
+		postgresAPIClientRepo, postgresAPIKeyRepo)
 
 	wg.Wait()
 	waitForShutdown(appServiceInstance, redisConn, postgresConn)
@@ -58,6 +69,11 @@ func initializeEnv() *config.EnvConfig {
 	envConfig.LoadJWTConfig()
 	envConfig.LoadCORSConfig()
 	envConfig.LoadOAuth2Config()
+	envConfig.LoadMailerConfig()
+	envConfig.LoadAccountConfig()
+	envConfig.LoadRateLimitConfig()
+	envConfig.LoadWebAuthnConfig()
+	envConfig.LoadOAuthServerConfig()
 	config, ok := envConfig.(*config.EnvConfig)
 	if !ok {
 		log.Error().Msg("failed to load environment configuration")
@@ -67,47 +83,110 @@ func initializeEnv() *config.EnvConfig {
 }
 
 func initializeDatabases(config *config.EnvConfig) (
-	repo.InMemoryDB, rr.RedisUserRepository,
-	repo.RDBMS, rp.PostgresUserRepository,
+	repo.InMemoryDB, rr.RedisUserRepository, rr.RedisLoginAttemptRepository, rr.RedisOAuthRepository,
+	rr.RateLimitRepository, repo.RDBMS, rp.PostgresUserRepository, rp.PostgresRoleRepository,
+	rp.PostgresWebAuthnRepository, rp.PostgresIdentityRepository, rp.PostgresOAuthClientRepository,
+	rp.PostgresAPIClientRepository, rp.PostgresAPIKeyRepository,
 ) {
 	redisDB := &redis.RedisDB{}
 	redisConnection := redisDB.ConnectToRedis(config.RedisDBConfig)
 	redisDBInstance := redisConnection.(*redis.RedisDB) // Type assert redisDB to *redis.RedisDB
 	redisUserRepo := redis.NewUserRepository(redisDBInstance)
+	redisLoginAttemptRepo := redis.NewLoginAttemptRepository(redisDBInstance)
+	redisOAuthRepo := redis.NewOAuthRepository(redisDBInstance)
+	rateLimitRepo := ratelimit.NewRateLimitRepository(config.RateLimitConfig, redisDBInstance)
 
 	postgresDB := &postgres.PostgresDB{}
 	postgresConnection := postgresDB.ConnectToPostgres(config.PostgresDBConfig)
 	postgresDBInstance := postgresConnection.(*postgres.PostgresDB) // Type assert postgresDB to *postgres.PostgresDB
 	postgresUserRepo := postgres.NewUserRepository(postgresDBInstance.Dbpool)
+	postgresRoleRepo := postgres.NewRoleRepository(postgresDBInstance.Dbpool)
+	postgresWebAuthnRepo := postgres.NewWebAuthnRepository(postgresDBInstance.Dbpool)
+	postgresIdentityRepo := postgres.NewIdentityRepository(postgresDBInstance.Dbpool)
+	postgresOAuthClientRepo := postgres.NewOAuthClientRepository(postgresDBInstance.Dbpool)
+	postgresAPIClientRepo := postgres.NewAPIClientRepository(postgresDBInstance.Dbpool)
+	postgresAPIKeyRepo := postgres.NewAPIKeyRepository(postgresDBInstance.Dbpool)
 	postgresSeedRepo := postgres.NewSeedRepository(postgresDBInstance.Dbpool, config.Env)
-	postgresSeedRepo.Seed(postgresUserRepo)
+	postgresSeedRepo.Seed(postgresUserRepo, postgresRoleRepo)
 
-	return redisDBInstance, redisUserRepo, postgresConnection, postgresUserRepo
+	return redisDBInstance, redisUserRepo, redisLoginAttemptRepo, redisOAuthRepo, rateLimitRepo,
+		postgresConnection, postgresUserRepo, postgresRoleRepo, postgresWebAuthnRepo, postgresIdentityRepo,
+		postgresOAuthClientRepo, postgresAPIClientRepo, postgresAPIKeyRepo
 }
 
 func initializeServer(
 	wg *sync.WaitGroup, config *config.EnvConfig,
-	redisUserRepo rr.RedisUserRepository, postgresUserRepo rp.PostgresUserRepository,
+	redisUserRepo rr.RedisUserRepository, redisLoginAttemptRepo rr.RedisLoginAttemptRepository,
+	redisOAuthRepo rr.RedisOAuthRepository, rateLimitRepo rr.RateLimitRepository,
+	postgresUserRepo rp.PostgresUserRepository, postgresRoleRepo rp.PostgresRoleRepository,
+	postgresWebAuthnRepo rp.PostgresWebAuthnRepository, postgresIdentityRepo rp.PostgresIdentityRepository,
+	postgresOAuthClientRepo rp.PostgresOAuthClientRepository, postgresAPIClientRepo rp.PostgresAPIClientRepository,
+	postgresAPIKeyRepo rp.PostgresAPIKeyRepository,
 ) *fiber.App {
 	defer wg.Done()
-	userService := interfaceSvc.NewUserService(config.JWTConfig, postgresUserRepo)
-	userUseCase := http.NewUserUseCase()
+	userService := interfaceSvc.NewUserService(
+		config.JWTConfig, config.AccountConfig, postgresUserRepo, postgresRoleRepo, postgresIdentityRepo)
+	userUseCase := http.NewUserUseCase(redisUserRepo, userService, postgresWebAuthnRepo)
 	tokenService := jwt.NewTokenService()
-	authUseCase := http.NewAuthUseCase(redisUserRepo, userService, tokenService)
-	googleOAuth2UseCase := oauth2.NewGoogleOAuth2(config.OAuth2Config)
+	userMailer := mailer.NewMailer(config.MailerConfig)
+	authUseCase := http.NewAuthUseCase(redisUserRepo, userService, tokenService, userMailer, config.AccountConfig)
+	webAuthnService := webauthn.NewWebAuthnService(config.WebAuthnConfig)
+	webAuthnUseCase := http.NewWebAuthnUseCase(redisUserRepo, userService, tokenService, config.AccountConfig,
+		webAuthnService, postgresWebAuthnRepo, config.WebAuthnConfig)
+	adminUseCase := http.NewAdminUseCase(redisUserRepo, userService)
+	googleOAuth2UseCase := oauth2.NewGoogleOAuth2(config.OAuth2Config, redisUserRepo, userService, authUseCase)
+	gitHubOAuth2UseCase := oauth2.NewGitHubOAuth2(config.OAuth2Config, redisUserRepo, userService, authUseCase)
+	oidcOAuth2UseCase := oauth2.NewOIDCOAuth2(config.OAuth2Config, oidc.NewOIDCProviders(config.OAuth2Config),
+		redisUserRepo, userService, authUseCase)
+	oauthServerUseCase := http.NewOAuthServerUseCase(postgresOAuthClientRepo, postgresAPIClientRepo, redisOAuthRepo,
+		redisUserRepo, userService, tokenService, config.OAuthServerConfig)
+	oauthClientUseCase := http.NewOAuthClientUseCase(postgresOAuthClientRepo)
+	apiClientUseCase := http.NewAPIClientUseCase(postgresAPIClientRepo)
+	apiKeyUseCase := http.NewAPIKeyUseCase(postgresAPIKeyRepo, postgresRoleRepo)
 
 	appServiceInstance := http.NewRouter(
-		config, redisUserRepo, tokenService,
-		userService, userUseCase,
-		authUseCase, googleOAuth2UseCase,
+		config, redisUserRepo, redisLoginAttemptRepo, rateLimitRepo, postgresRoleRepo, postgresAPIKeyRepo,
+		postgresAPIClientRepo, tokenService, userService, userUseCase,
+		authUseCase, webAuthnUseCase, adminUseCase, googleOAuth2UseCase, gitHubOAuth2UseCase,
+		oidcOAuth2UseCase, oauthServerUseCase, oauthClientUseCase, apiClientUseCase, apiKeyUseCase,
 	)
 
 	go func() {
 		http.StartServer(appServiceInstance, config.Port)
 	}()
+
+	go purgeDeletedUsers(userService, config.AccountConfig)
 	return appServiceInstance
 }
 
+// purgeDeletedUsers deletes for good the accounts whose deletion grace period is over, once every `PurgeInterval`.
+func purgeDeletedUsers(userService appSvc.UserService, accountConfig *entity.AccountConfig) {
+	if accountConfig.PurgeInterval <= 0 {
+		log.Warn().Msg("ACCOUNT_PURGE_INTERVAL is not set; deleted accounts will not be purged")
+		return
+	}
+
+	purge := func() {
+		count, err := userService.PurgeDeletedUsers()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge deleted accounts")
+			return
+		}
+
+		if count > 0 {
+			log.Info().Int64("count", count).Msg("purged deleted accounts")
+		}
+	}
+
+	ticker := time.NewTicker(accountConfig.PurgeInterval)
+	defer ticker.Stop()
+
+	purge()
+	for range ticker.C {
+		purge()
+	}
+}
+
 func waitForShutdown(appServiceInstance *fiber.App, redisConn repo.InMemoryDB, postgresConn repo.RDBMS) {
 	sigChan := make(chan os.Signal, 1) // Create a channel to listen for OS signals
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -1,16 +1,29 @@
 #!/bin/bash
 
-# Generate 2048-bit RSA Private Key for Access Token
-openssl genpkey -algorithm RSA -out access_private_key.pem -pkeyopt rsa_keygen_bits:2048
+# Usage: ./refresh_token_keygen.sh [RS256|ES256|EdDSA]
+# The algorithm must match JWT_SIGNING_ALG in the .env file (defaults to RS256)
+ALG=${1:-RS256}
+
+generate_private_key() {
+    case "$ALG" in
+        RS256) openssl genpkey -algorithm RSA -out "$1" -pkeyopt rsa_keygen_bits:2048 ;;
+        ES256) openssl genpkey -algorithm EC -out "$1" -pkeyopt ec_paramgen_curve:P-256 ;;
+        EdDSA) openssl genpkey -algorithm ED25519 -out "$1" ;;
+        *) echo "Unsupported algorithm: $ALG (use RS256, ES256 or EdDSA)" && exit 1 ;;
+    esac
+}
+
+# Generate the Private Key for Access Token
+generate_private_key access_private_key.pem
 
 # Extract the Public Key from the Private Key for Access Token
-openssl rsa -pubout -in access_private_key.pem -out access_public_key.pem
+openssl pkey -pubout -in access_private_key.pem -out access_public_key.pem
 
-# Generate 2048-bit RSA Private Key for Refresh Token
-openssl genpkey -algorithm RSA -out refresh_private_key.pem -pkeyopt rsa_keygen_bits:2048
+# Generate the Private Key for Refresh Token
+generate_private_key refresh_private_key.pem
 
 # Extract the Public Key from the Private Key for Refresh Token
-openssl rsa -pubout -in refresh_private_key.pem -out refresh_public_key.pem
+openssl pkey -pubout -in refresh_private_key.pem -out refresh_public_key.pem
 
 # Encode the Private and Public Keys to Base64 (using cat to ensure compatibility)
 access_private_key_base64=$(cat access_private_key.pem | base64 | tr -d '\n')
@@ -20,6 +33,8 @@ refresh_public_key_base64=$(cat refresh_public_key.pem | base64 | tr -d '\n')
 
 # Output the keys to a .txt file in the desired format
 cat <<EOL > refresh_token_keys.txt
+JWT_SIGNING_ALG=$ALG
+
 ACCESS_TOKEN_PRIVATE_KEY=$access_private_key_base64
 
 ACCESS_TOKEN_PUBLIC_KEY=$access_public_key_base64
@@ -32,4 +47,4 @@ EOL
 # Clean up the generated key files
 rm access_private_key.pem access_public_key.pem refresh_private_key.pem refresh_public_key.pem
 
-echo "Keys generated and saved to refresh_token_keys.txt"
\ No newline at end of file
+echo "$ALG keys generated and saved to refresh_token_keys.txt"
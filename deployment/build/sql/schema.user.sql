@@ -6,8 +6,163 @@ CREATE TABLE IF NOT EXISTS
     user_uuid UUID UNIQUE DEFAULT uuid_generate_v4 (),
     first_name VARCHAR(255) NOT NULL,
     last_name VARCHAR(255) NOT NULL,
-    password VARCHAR(255) NOT NULL,
-    email VARCHAR(255) UNIQUE NOT NULL,
+    password VARCHAR(255), -- NULL for users who only login with an external identity
+    email VARCHAR(255) NOT NULL,
+    email_verified_at TIMESTAMP,
+    totp_secret TEXT,
+    totp_enabled_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    deleted_at TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
     updated_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
-  );
\ No newline at end of file
+  );
+
+-- Columns added after the initial release
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ALTER COLUMN password DROP NOT NULL;
+
+-- Soft-deleted users free their email address, so that it can register again during the grace period
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email) WHERE deleted_at IS NULL;
+
+-- Every update of a user bumps `updated_at`, which the profile update relies on to reject stale writes
+CREATE OR REPLACE FUNCTION set_updated_at () RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = now() AT TIME ZONE 'UTC';
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER users_set_updated_at BEFORE UPDATE ON users
+FOR EACH ROW EXECUTE FUNCTION set_updated_at ();
+
+-- Single-use recovery codes of the two-factor authentication, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS
+  mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    UNIQUE (user_uuid, code_hash)
+  );
+
+
+-- Accounts of external identity providers, e.g. Google, that the user logs in with
+CREATE TABLE IF NOT EXISTS
+  user_identities (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    linked_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    UNIQUE (provider, subject)
+  );
+
+CREATE INDEX IF NOT EXISTS user_identities_user_uuid_idx ON user_identities (user_uuid);
+
+-- Passkeys of the user; `credential_id` is chosen by the authenticator
+CREATE TABLE IF NOT EXISTS
+  webauthn_credentials (
+    id SERIAL PRIMARY KEY,
+    credential_id BYTEA UNIQUE NOT NULL,
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(32) NOT NULL,
+    aaguid BYTEA,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    last_used_at TIMESTAMP
+  );
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_uuid_idx ON webauthn_credentials (user_uuid);
+
+-- Role-based access control; roles are embedded in the access token and resolved to permissions on use
+CREATE TABLE IF NOT EXISTS
+  roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) UNIQUE NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+  );
+
+CREATE TABLE IF NOT EXISTS
+  permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+  );
+
+CREATE TABLE IF NOT EXISTS
+  role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+  );
+
+CREATE TABLE IF NOT EXISTS
+  user_roles (
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    PRIMARY KEY (user_uuid, role_id)
+  );
+
+-- Apps that delegate the login of their users to this service; public clients have no secret
+CREATE TABLE IF NOT EXISTS
+  oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) UNIQUE NOT NULL,
+    client_secret_hash VARCHAR(64), -- SHA-256 of the secret, NULL for public clients
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+  );
+
+-- Scopes that the user has allowed a client, so that the consent is only asked for new scopes
+CREATE TABLE IF NOT EXISTS
+  oauth_consents (
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    granted_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    PRIMARY KEY (user_uuid, client_id)
+  );
+
+-- Backend services that get tokens for themselves with the client credentials grant, without a user
+CREATE TABLE IF NOT EXISTS
+  api_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) UNIQUE NOT NULL,
+    client_secret_hash VARCHAR(64) NOT NULL, -- SHA-256 of the secret
+    name VARCHAR(255) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+  );
+
+-- Personal API keys of the users; only the prefix is kept in clear so that the user can tell their keys apart
+CREATE TABLE IF NOT EXISTS
+  api_keys (
+    id SERIAL PRIMARY KEY,
+    key_id UUID UNIQUE NOT NULL DEFAULT uuid_generate_v4 (),
+    user_uuid UUID NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL, -- SHA-256 of the key
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP, -- NULL for keys that do not expire
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+  );
+
+CREATE INDEX IF NOT EXISTS api_keys_user_uuid_idx ON api_keys (user_uuid);
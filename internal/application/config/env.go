@@ -11,4 +11,9 @@ type LoadEnvConfig interface {
 	LoadJWTConfig()
 	LoadCORSConfig()
 	LoadOAuth2Config()
+	LoadMailerConfig()
+	LoadAccountConfig()
+	LoadRateLimitConfig()
+	LoadWebAuthnConfig()
+	LoadOAuthServerConfig()
 }
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// APIKeyInput creates a personal API key; keys without `expires_at` do not expire.
+type APIKeyInput struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"dive,permission"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,future"`
+}
+
+// APIKeyRecord is the view of a personal API key for its user.
+type APIKeyRecord struct {
+	ID         string     `json:"id"`
+	Key        string     `json:"key,omitempty"` // Only returned once, when the key is created
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyPrincipal is set by the `Deserializer` next to the `UserRecord` when the request is made with an API key.
+type APIKeyPrincipal struct {
+	ID     string   `json:"id"`
+	Scopes []string `json:"scopes"`
+}
@@ -0,0 +1,67 @@
+package dto
+
+import "time"
+
+// OAuthClientInput registers an OAuth client; public clients get no secret and must use PKCE.
+type OAuthClientInput struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+}
+
+// OAuthClientRecord is the view of an OAuth client for admins.
+type OAuthClientRecord struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"` // Only returned once, when the client is created
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// APIClientInput registers a backend service that gets tokens with the client credentials grant.
+type APIClientInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIClientRecord is the view of an API client for admins.
+type APIClientRecord struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"` // Only returned once, when the client is created
+	Name         string    `json:"name"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+/*
+ClientPrincipal is set by the `Deserializer` instead of a `UserRecord`
+when the request is made by an API client with its own access token.
+*/
+type ClientPrincipal struct {
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+// ConsentRequestRecord is what the consent page shows the user before they allow a client.
+type ConsentRequestRecord struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	Scopes     []string `json:"scopes"`
+}
+
+type ConsentInput struct {
+	Approve bool `json:"approve"`
+}
+
+// TokenResponse is the successful response of the token endpoint (RFC 6749 section 5.1).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
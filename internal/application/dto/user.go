@@ -18,11 +18,58 @@ type LoginInput struct {
 	Password string `json:"password" validate:"required,max=100"`
 }
 
+type EmailInput struct {
+	Email string `json:"email" validate:"required,max=100,email"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required,len=64,hexadecimal"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=100,passwd"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=100"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=100,passwd"`
+}
+
+/*
+UpdateUserInput is a partial update of the user; empty fields are left unchanged.
+`UpdatedAt` is the `updated_at` of the `UserRecord` that the update is based on.
+*/
+type UpdateUserInput struct {
+	FirstName string    `json:"first_name" validate:"omitempty,min=2,max=50,alpha"`
+	LastName  string    `json:"last_name" validate:"omitempty,min=2,max=50,alpha"`
+	UpdatedAt time.Time `json:"updated_at" validate:"required"`
+}
+
+// DeleteAccountInput confirms the deletion of the account with the password of the user, if the account has one.
+type DeleteAccountInput struct {
+	Password string `json:"password" validate:"omitempty,max=100"`
+}
+
+type TOTPCodeInput struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// MFALoginInput finishes a login with either a TOTP code or a recovery code.
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" validate:"required,len=64,hexadecimal"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
 type UserResponse struct {
-	UUID      *uuid.UUID `json:"uuid"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Email     string     `json:"email"`
+	UUID            *uuid.UUID `json:"uuid"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at"`
+	DisabledAt      *time.Time `json:"-"`
 }
 
 type UserRecord struct {
@@ -32,4 +79,76 @@ type UserRecord struct {
 	Email     string     `json:"email"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
+	Roles     []string   `json:"roles"` // As of the issuance of the access token
+}
+
+type SessionRecord struct {
+	ID              string    `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	IPAddress       string    `json:"ip_address"`
+	UserAgent       string    `json:"user_agent"`
+	Current         bool      `json:"current"`
+}
+
+// ListUsersInput holds the query string of the admin user listing; dates are RFC 3339 or `YYYY-MM-DD`.
+type ListUsersInput struct {
+	Email         string `query:"email"`
+	Name          string `query:"name"`
+	CreatedAfter  string `query:"created_after"`
+	CreatedBefore string `query:"created_before"`
+	Cursor        string `query:"cursor"`
+	Limit         int    `query:"limit"`
+}
+
+// AdminUserRecord is the view of a user for admins.
+type AdminUserRecord struct {
+	UUID            *uuid.UUID `json:"uuid"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Roles           []string   `json:"roles,omitempty"`
+}
+
+// UserPage is a page of the admin user listing; `NextCursor` is empty on the last page.
+type UserPage struct {
+	Users      []*AdminUserRecord `json:"users"`
+	NextCursor string             `json:"next_cursor"`
+}
+
+/*
+UserExport is the personal data archive of the user.
+Credentials such as the password hash, the TOTP secret and the recovery codes are never exported;
+`TOTPEnabledAt` and the passkeys tell which ones exist.
+*/
+type UserExport struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	User       *AdminUserRecord `json:"user"`
+	Identities []IdentityRecord `json:"identities"`
+	Passkeys   []PasskeyRecord  `json:"passkeys"`
+	Sessions   []SessionRecord  `json:"sessions"`
+}
+
+// IdentityRecord is an account at an external identity provider that the user logs in with.
+type IdentityRecord struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type PasskeyRecord struct {
+	ID              string     `json:"id"` // Base64url encoded credential ID
+	AttestationType string     `json:"attestation_type"`
+	AAGUID          string     `json:"aaguid"` // Identifies the model of the authenticator
+	Transports      []string   `json:"transports"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
 }
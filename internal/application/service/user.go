@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
@@ -11,4 +13,26 @@ type UserService interface {
 	CreateUser(payload dto.RegisterInput) (*dto.UserResponse, *restErr.RestErr)
 	GetUserByEmail(u dto.LoginInput) (*dto.UserResponse, *restErr.RestErr)
 	GetUserByUUID(userUuid string) (*entity.User, *restErr.RestErr)
+	FindUserByEmail(email string) (*entity.User, *restErr.RestErr)
+	FindOrCreateExternalUser(externalUser *entity.ExternalUser) (*entity.User, *restErr.RestErr)
+	LinkExternalIdentity(userUuid string, externalUser *entity.ExternalUser) *restErr.RestErr
+	UnlinkIdentity(userUuid string, provider string, subject string, hasPasskeys bool) *restErr.RestErr
+	GetIdentities(userUuid string) ([]dto.IdentityRecord, *restErr.RestErr)
+	VerifyEmail(userUuid string) *restErr.RestErr
+	ResetPassword(userUuid string, newPassword string) *restErr.RestErr
+	ChangePassword(userUuid string, payload dto.ChangePasswordInput) *restErr.RestErr
+	UpdateUser(userUuid string, payload dto.UpdateUserInput) (*entity.User, *restErr.RestErr)
+	DeleteAccount(userUuid string, payload dto.DeleteAccountInput, loggedInAt time.Time) *restErr.RestErr
+	PurgeDeletedUsers() (int64, *restErr.RestErr)
+	ExportUser(userUuid string) (*dto.UserExport, *restErr.RestErr)
+	EnrollTOTP(userUuid string) (*dto.TOTPEnrollment, *restErr.RestErr)
+	ConfirmTOTP(userUuid string, code string) ([]string, *restErr.RestErr)
+	ValidateTOTP(userUuid string, code string) (int64, *restErr.RestErr)
+	UseRecoveryCode(userUuid string, code string) *restErr.RestErr
+	GetUserRoles(userUuid string) ([]string, *restErr.RestErr)
+
+	ListUsers(payload dto.ListUsersInput) (*dto.UserPage, *restErr.RestErr)
+	GetAdminUserRecord(userUuid string) (*dto.AdminUserRecord, *restErr.RestErr)
+	SetUserDisabled(userUuid string, disabled bool) (*dto.AdminUserRecord, *restErr.RestErr)
+	DeleteUser(userUuid string) *restErr.RestErr
 }
@@ -0,0 +1,23 @@
+package usecase
+
+import "github.com/gofiber/fiber/v2"
+
+type AdminUseCase interface {
+	ListUsers(c *fiber.Ctx) error
+	GetUser(c *fiber.Ctx) error
+	DisableUser(c *fiber.Ctx) error
+	EnableUser(c *fiber.Ctx) error
+	DeleteUser(c *fiber.Ctx) error
+}
+
+type OAuthClientUseCase interface {
+	CreateClient(c *fiber.Ctx) error
+	ListClients(c *fiber.Ctx) error
+	DeleteClient(c *fiber.Ctx) error
+}
+
+type APIClientUseCase interface {
+	CreateAPIClient(c *fiber.Ctx) error
+	ListAPIClients(c *fiber.Ctx) error
+	DeleteAPIClient(c *fiber.Ctx) error
+}
@@ -1,15 +1,53 @@
 package usecase
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+)
 
 type AuthUseCase interface {
 	Register(c *fiber.Ctx) error
 	Login(c *fiber.Ctx) error
+	LoginMFA(c *fiber.Ctx) error
 	RefreshAccessToken(c *fiber.Ctx) error
 	Logout(c *fiber.Ctx) error
+	VerifyEmail(c *fiber.Ctx) error
+	ResendVerificationEmail(c *fiber.Ctx) error
+	ForgotPassword(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
+	ChangePassword(c *fiber.Ctx) error
+	GetJWKS(c *fiber.Ctx) error
+	GetSessions(c *fiber.Ctx) error
+	RevokeSession(c *fiber.Ctx) error
+	LogoutAll(c *fiber.Ctx) error
+	EnrollTOTP(c *fiber.Ctx) error
+	ConfirmTOTP(c *fiber.Ctx) error
+
+	// Let the other login methods, e.g. OAuth2 providers, finish a login like `Login` does
+	IssueSession(c *fiber.Ctx, userUUID string) (*entity.Token, *restErr.RestErr)
+	IssueMFAChallenge(userUUID string) (string, *restErr.RestErr)
+}
+
+type WebAuthnUseCase interface {
+	BeginRegistration(c *fiber.Ctx) error
+	FinishRegistration(c *fiber.Ctx) error
+	BeginLogin(c *fiber.Ctx) error
+	FinishLogin(c *fiber.Ctx) error
 }
 
 type OAuth2UseCase interface {
 	Login(c *fiber.Ctx) error
 	Callback(c *fiber.Ctx) error
+	Link(c *fiber.Ctx) error // Links the external account to the logged in user instead of logging in
+}
+
+// The `OAuthServerUseCase` lets other apps delegate the login of their users to this service.
+type OAuthServerUseCase interface {
+	Authorize(c *fiber.Ctx) error
+	GetConsentRequest(c *fiber.Ctx) error
+	AnswerConsentRequest(c *fiber.Ctx) error
+	Token(c *fiber.Ctx) error
+	UserInfo(c *fiber.Ctx) error
+	GetOpenIDConfiguration(c *fiber.Ctx) error
 }
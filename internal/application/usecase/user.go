@@ -4,4 +4,15 @@ import "github.com/gofiber/fiber/v2"
 
 type UserUseCase interface {
 	GetUserRecord(c *fiber.Ctx) error
+	UpdateUserRecord(c *fiber.Ctx) error
+	DeleteAccount(c *fiber.Ctx) error
+	ExportUserData(c *fiber.Ctx) error
+	GetIdentities(c *fiber.Ctx) error
+	UnlinkIdentity(c *fiber.Ctx) error
+}
+
+type APIKeyUseCase interface {
+	CreateAPIKey(c *fiber.Ctx) error
+	ListAPIKeys(c *fiber.Ctx) error
+	RevokeAPIKey(c *fiber.Ctx) error
 }
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+/*
+APIKey is a personal key that a user sends instead of an access token, e.g. from scripts.
+Its scopes are the permissions it is limited to, on top of the ones that the roles of the user grant.
+*/
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserUUID   string     `json:"user_uuid"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the key, shown so that the user can recognize it
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -4,14 +4,19 @@ import "time"
 
 type (
 	EnvConfig struct {
-		Env              string
-		Port             string
-		BaseURLsConfig   *BaseURLsConfig
-		PostgresDBConfig *PostgresDBConfig
-		RedisDBConfig    *RedisDBConfig
-		JWTConfig        *JWTConfig
-		CORSConfig       *CORSConfig
-		OAuth2Config     *OAuth2Config
+		Env               string
+		Port              string
+		BaseURLsConfig    *BaseURLsConfig
+		PostgresDBConfig  *PostgresDBConfig
+		RedisDBConfig     *RedisDBConfig
+		JWTConfig         *JWTConfig
+		CORSConfig        *CORSConfig
+		OAuth2Config      *OAuth2Config
+		MailerConfig      *MailerConfig
+		AccountConfig     *AccountConfig
+		RateLimitConfig   *RateLimitConfig
+		WebAuthnConfig    *WebAuthnConfig
+		OAuthServerConfig *OAuthServerConfig
 	}
 
 	BaseURLsConfig struct {
@@ -34,28 +39,125 @@ type (
 	}
 
 	JWTConfig struct {
-		Path                   string
-		Domain                 string
-		Secure                 bool
-		HttpOnly               bool
-		AccessTokenPrivateKey  string
-		AccessTokenPublicKey   string
-		AccessTokenExpiredIn   time.Duration
-		AccessTokenMaxAge      int
-		RefreshTokenPrivateKey string
-		RefreshTokenPublicKey  string
-		RefreshTokenExpiredIn  time.Duration
-		RefreshTokenMaxAge     int
+		Path                  string
+		Domain                string
+		Secure                bool
+		HttpOnly              bool
+		DefaultScope          []string // Scope of the tokens issued for a login
+		AccessTokenKeyRing    *JWTKeyRing
+		AccessTokenExpiredIn  time.Duration
+		AccessTokenMaxAge     int
+		RefreshTokenKeyRing   *JWTKeyRing
+		RefreshTokenExpiredIn time.Duration
+		RefreshTokenMaxAge    int
+	}
+
+	/*
+		JWTKeyRing holds the keys of one token type.
+		New tokens are always signed with the active `PrivateKey`, while tokens are verified against
+		`PublicKey` and any `RetiredPublicKeys`, selected by the `kid` header of the token.
+		Keeping a retired key lets tokens issued before a rotation stay valid until they expire.
+
+		`Issuer` and `Audience` are stamped on new tokens and required on validated tokens,
+		so that a token of one type or deployment is not accepted as another.
+	*/
+	JWTKeyRing struct {
+		Algorithm         string // Signing algorithm of `PrivateKey`: RS256, ES256 or EdDSA
+		PrivateKey        string
+		PublicKey         string
+		RetiredPublicKeys []string
+		Issuer            string
+		Audience          string
+		Leeway            time.Duration // Clock skew tolerated on `exp`, `nbf` and `iat`
 	}
 
 	CORSConfig struct {
 		AllowedOrigins string
 	}
 
+	MailerConfig struct {
+		Mailer       string // smtp, file or memory
+		From         string
+		FileDir      string
+		SMTPHost     string
+		SMTPPort     string
+		SMTPUsername string
+		SMTPPassword string
+	}
+
+	AccountConfig struct {
+		RequireEmailVerification   bool // Blocks `Login` until the email address is verified
+		EmailVerificationExpiredIn time.Duration
+		PasswordResetExpiredIn     time.Duration
+		PasswordResetURL           string // Page of the client that submits the reset token with the new password
+
+		// Failed logins are counted per account and per client IP
+		MaxLoginAttempts      int           // Failures per account before it is locked out
+		MaxLoginAttemptsPerIP int           // Failures per client IP before it is locked out
+		LoginBackoffBase      time.Duration // Delay after the first failure of an account, doubled on each failure
+		LoginLockoutDuration  time.Duration
+
+		TOTPIssuer            string // Shown next to the account in authenticator apps
+		TOTPEncryptionKey     string // Base64 encoded 32-byte AES-256 key that encrypts the TOTP secrets at rest
+		MFAChallengeExpiredIn time.Duration
+
+		DeletionGracePeriod time.Duration // Time before a deleted account is purged for good
+		PurgeInterval       time.Duration // How often the accounts past their grace period are purged
+		RecentLoginMaxAge   time.Duration // Age of the login that confirms deleting an account without a password
+	}
+
+	RateLimitConfig struct {
+		Store string         // redis, or memory for a single instance
+		Auth  *RateLimitRule // Public user routes, keyed by client IP
+		User  *RateLimitRule // Authenticated user routes, keyed by user UUID
+	}
+
+	// RateLimitRule allows `Limit` requests per sliding `Window`; a zero limit disables it.
+	RateLimitRule struct {
+		Limit  int
+		Window time.Duration
+	}
+
+	WebAuthnConfig struct {
+		RPID          string   // Domain of the relying party that passkeys are bound to, without scheme and port
+		RPDisplayName string   // Shown by the authenticator when creating a passkey
+		RPOrigins     []string // Origins of the clients allowed to run the ceremonies
+		Timeout       time.Duration
+	}
+
 	OAuth2Config struct {
 		GoogleRedirectURL  string
 		GoogleClientID     string
 		GoogleClientSecret string
 		Scopes             []string
+
+		GitHubRedirectURL  string
+		GitHubClientID     string
+		GitHubClientSecret string
+		GitHubScopes       []string
+
+		PostLoginRedirectURL string        // Page of the client that the browser is sent to once logged in
+		StateExpiredIn       time.Duration // Time the user has to login at the provider
+		OIDCProviders        []*OIDCProviderConfig
+	}
+
+	// OIDCProviderConfig is an OpenID Connect provider whose endpoints come from the discovery document of its issuer.
+	OIDCProviderConfig struct {
+		Name         string // Used in the routes, e.g. /auth/:provider/login, and as the provider of the linked identities
+		IssuerURL    string
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Scopes       []string
+	}
+
+	// OAuthServerConfig configures this service as an OAuth 2.0 / OpenID Connect provider of other apps.
+	OAuthServerConfig struct {
+		LoginURL                      string        // Page of the SPA that logs the user in and returns to `return_to`
+		ConsentURL                    string        // Page of the SPA that asks the user to approve a client
+		AuthorizationRequestExpiredIn time.Duration // Time the user has to login and consent
+		AuthorizationCodeExpiredIn    time.Duration
+		IDTokenExpiredIn              time.Duration
+		ClientCredentialsExpiredIn    time.Duration // Lifetime of the access tokens of API clients, which cannot be refreshed
 	}
 )
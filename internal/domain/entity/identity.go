@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"` // Stable ID of the account at the provider; emails can change
+	UserUUID string    `json:"-"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+/*
+OAuth2State is what an OAuth2 login keeps between the redirect to the provider and the callback.
+It is looked up by the hash of the random `state` parameter, which is also kept in a cookie of the browser.
+*/
+type OAuth2State struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`            // PKCE
+	Nonce        string `json:"nonce"`                    // Echoed in the ID token to tie it to this login
+	LinkUserUUID string `json:"link_user_uuid,omitempty"` // Set when a logged in user links the account instead
+}
+
+// ExternalUser is the profile of the user returned by an identity provider after a login.
+type ExternalUser struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
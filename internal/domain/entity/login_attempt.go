@@ -0,0 +1,9 @@
+package entity
+
+import "time"
+
+// LoginAttempts counts the consecutive failed logins of an account or a client IP.
+type LoginAttempts struct {
+	Count        int64
+	LastFailedAt time.Time
+}
@@ -0,0 +1,7 @@
+package entity
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
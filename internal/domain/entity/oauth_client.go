@@ -0,0 +1,62 @@
+package entity
+
+import "time"
+
+// Scopes of OpenID Connect that release the claims of the user to a client.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+/*
+OAuthClient is an app that delegates the login of its users to this service.
+Public clients, e.g. SPAs and mobile apps, cannot keep a secret and have no `SecretHash`; they rely on PKCE alone.
+*/
+type OAuthClient struct {
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"` // Scopes the client may request
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+/*
+APIClient is a backend service that calls APIs on its own behalf with the client credentials grant.
+Its access tokens have the client ID as their subject, as there is no user.
+*/
+type APIClient struct {
+	ClientID   string    `json:"client_id"`
+	SecretHash string    `json:"-"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"` // Scopes the client may request
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+/*
+AuthorizationGrant is what the user allowed a client to do. It is carried from the consent request
+to the authorization code and then to each refresh token, which are all looked up by the hash of their token.
+*/
+type AuthorizationGrant struct {
+	ClientID        string   `json:"client_id"`
+	UserUUID        string   `json:"user_uuid"`
+	Scope           []string `json:"scope"`
+	RedirectURI     string   `json:"redirect_uri,omitempty"`
+	RedirectURISent bool     `json:"redirect_uri_sent,omitempty"` // The token request must then send it again
+	State           string   `json:"state,omitempty"`
+	Nonce           string   `json:"nonce,omitempty"`          // Echoed in the ID token
+	CodeChallenge   string   `json:"code_challenge,omitempty"` // S256 PKCE challenge of the authorization code
+}
+
+// IDToken is the OpenID Connect ID token about the user, issued to the client the user logged in to.
+type IDToken struct {
+	Subject  string
+	ClientID string
+	Nonce    string
+	Claims   map[string]interface{} // Claims of the user released by the granted scopes, e.g. `email`
+}
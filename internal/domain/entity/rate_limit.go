@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// RateLimit is the outcome of counting one request against a `RateLimitRule`.
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // Until the oldest request in the window expires and frees a slot
+}
@@ -0,0 +1,36 @@
+package entity
+
+// Permissions are named `<resource>:<action>` and granted to users through their roles.
+const (
+	RoleAdmin = "admin"
+
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionUsersDelete = "users:delete"
+
+	PermissionOAuthClientsRead  = "oauth_clients:read"
+	PermissionOAuthClientsWrite = "oauth_clients:write"
+	PermissionAPIClientsRead    = "api_clients:read"
+	PermissionAPIClientsWrite   = "api_clients:write"
+)
+
+// Permissions are all the permissions that roles and the scopes of API keys can list.
+var Permissions = []string{
+	PermissionUsersRead, PermissionUsersWrite, PermissionUsersDelete,
+	PermissionOAuthClientsRead, PermissionOAuthClientsWrite, PermissionAPIClientsRead, PermissionAPIClientsWrite,
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// DefaultRoles are created on every start, whatever the env, and are never removed by the seeding.
+var DefaultRoles = []*Role{
+	{
+		Name:        RoleAdmin,
+		Description: "Manages the users, the OAuth clients and the API clients of the service",
+		Permissions: Permissions,
+	},
+}
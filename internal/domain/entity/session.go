@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+/*
+A `Session` is the metadata of a token family, i.e. one login on one device.
+Its `ID` is the family ID shared by every access and refresh token issued from that login.
+*/
+type Session struct {
+	ID              string    `json:"id"`
+	UserUUID        string    `json:"user_uuid"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	IPAddress       string    `json:"ip_address"`
+	UserAgent       string    `json:"user_agent"`
+}
@@ -5,4 +5,26 @@ type Token struct {
 	TokenUUID string
 	UserUUID  string
 	ExpiresIn *int64
+	Issuer    string
+	Audience  []string
+	Scope     []string
+	Roles     []string // Only set on access tokens
+	ClientID  string   // Only set on access tokens issued to an OAuth client
+}
+
+// JWK is a public JSON Web Key as published in the JWKS document (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // EC and OKP
+	X   string `json:"x,omitempty"`   // EC and OKP
+	Y   string `json:"y,omitempty"`   // EC
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
 }
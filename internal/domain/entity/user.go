@@ -15,4 +15,23 @@ type User struct {
 	Email     string     `json:"email"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPSecret      string     `json:"-"` // Encrypted; set on enrollment and only in use once `TOTPEnabledAt` is set
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at"`
+	DisabledAt      *time.Time `json:"disabled_at"` // Disabled users cannot login or use their tokens
+	DeletedAt       *time.Time `json:"deleted_at"`  // Soft-deleted users are purged after the grace period
+}
+
+/*
+UserFilter narrows down the users listed by admins.
+Users are listed from the newest, and `BeforeID` continues a listing after the last user of the previous page.
+*/
+type UserFilter struct {
+	Email         string // Case-insensitive substring
+	Name          string // Case-insensitive substring of the full name
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	BeforeID      int64
+	Limit         int
 }
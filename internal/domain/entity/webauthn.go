@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebAuthnCredential is a passkey registered by a user; `ID` is the credential ID chosen by the authenticator.
+type WebAuthnCredential struct {
+	ID              []byte     `json:"id"`
+	UserUUID        string     `json:"-"`
+	PublicKey       []byte     `json:"-"`
+	AttestationType string     `json:"attestation_type"`
+	AAGUID          []byte     `json:"aaguid"`
+	SignCount       uint32     `json:"sign_count"`
+	Transports      []string   `json:"transports"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
+
+/*
+WebAuthnCeremony is the first half of a registration or login ceremony.
+`Options` are passed to `navigator.credentials` by the client while `SessionData`
+is kept by the server until the response of the authenticator comes back.
+*/
+type WebAuthnCeremony struct {
+	Options     json.RawMessage
+	SessionData []byte
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresAPIClientRepository` interface defines the persistence of the API clients that use the client credentials grant.
+type PostgresAPIClientRepository interface {
+	SaveAPIClient(client *entity.APIClient) *restErr.RestErr
+	GetAPIClient(clientID string) (*entity.APIClient, *restErr.RestErr)
+	GetAPIClients() ([]*entity.APIClient, *restErr.RestErr)
+	DeleteAPIClient(clientID string) *restErr.RestErr
+}
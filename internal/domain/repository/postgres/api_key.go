@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresAPIKeyRepository` interface defines the persistence of the personal API keys of the users.
+type PostgresAPIKeyRepository interface {
+	SaveAPIKey(apiKey *entity.APIKey) *restErr.RestErr
+	GetAPIKeys(userUUID string) ([]*entity.APIKey, *restErr.RestErr)
+	GetAPIKeyByHash(keyHash string) (*entity.APIKey, *restErr.RestErr)
+	// DeleteAPIKey only deletes the key if it belongs to the user
+	DeleteAPIKey(userUUID string, keyID string) *restErr.RestErr
+	UpdateAPIKeyLastUsed(keyID string, ipAddress string) *restErr.RestErr
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresIdentityRepository` interface defines the persistence of the external identities of the users.
+type PostgresIdentityRepository interface {
+	SaveIdentity(identity *entity.UserIdentity) *restErr.RestErr
+	GetIdentity(provider string, subject string) (*entity.UserIdentity, *restErr.RestErr)
+	GetIdentities(userUUID string) ([]*entity.UserIdentity, *restErr.RestErr)
+
+	// The identity is only deleted if the user keeps another way to login: a password, a passkey or another identity
+	DeleteIdentity(identity *entity.UserIdentity, hasPasskeys bool) *restErr.RestErr
+}
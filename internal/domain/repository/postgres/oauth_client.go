@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresOAuthClientRepository` interface defines the persistence of the OAuth clients and of the consents of users.
+type PostgresOAuthClientRepository interface {
+	SaveClient(client *entity.OAuthClient) *restErr.RestErr
+	GetClient(clientID string) (*entity.OAuthClient, *restErr.RestErr)
+	GetClients() ([]*entity.OAuthClient, *restErr.RestErr)
+	DeleteClient(clientID string) *restErr.RestErr
+
+	// GetConsent returns the scopes that the user has allowed the client, which are empty before the first consent
+	GetConsent(userUUID string, clientID string) ([]string, *restErr.RestErr)
+	// SaveConsent adds the scopes to the ones that the user has already allowed the client
+	SaveConsent(userUUID string, clientID string, scopes []string) *restErr.RestErr
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresRoleRepository` interface defines the persistence of roles, their permissions and the roles of users.
+type PostgresRoleRepository interface {
+	// SaveRole creates the role, or adds the missing permissions to an existing one
+	SaveRole(role *entity.Role) *restErr.RestErr
+	AssignRole(userUUID string, roleName string) *restErr.RestErr
+	GetUserRoles(userUUID string) ([]string, *restErr.RestErr)
+	GetPermissions(roleNames []string) ([]string, *restErr.RestErr)
+}
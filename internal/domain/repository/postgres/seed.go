@@ -1,5 +1,5 @@
 package repository
 
 type PostgresSeedRepository interface {
-	Seed(ur PostgresUserRepository)
+	Seed(ur PostgresUserRepository, rr PostgresRoleRepository)
 }
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
 )
@@ -15,4 +17,21 @@ type PostgresUserRepository interface {
 	SaveUser(user *entity.User) *restErr.RestErr
 	GetUserByEmail(user *entity.User) *restErr.RestErr
 	GetUserByUUID(user *entity.User) *restErr.RestErr
+	VerifyEmail(user *entity.User) *restErr.RestErr
+	UpdatePassword(user *entity.User) *restErr.RestErr
+	UpdateUser(user *entity.User, lastUpdatedAt time.Time) *restErr.RestErr
+
+	// The TOTP secret is saved on enrollment and only enabled, with fresh recovery codes, once confirmed
+	SetTOTPSecret(user *entity.User) *restErr.RestErr
+	EnableTOTP(user *entity.User, recoveryCodeHashes []string) *restErr.RestErr
+	UseRecoveryCode(user *entity.User, codeHash string) *restErr.RestErr
+
+	// Admin user management
+	ListUsers(filter *entity.UserFilter) ([]*entity.User, *restErr.RestErr)
+	SetDisabled(user *entity.User, disabled bool) *restErr.RestErr
+	DeleteUser(user *entity.User) *restErr.RestErr
+
+	// Self-service deletion is a soft delete, purged once the grace period is over
+	SoftDeleteUser(user *entity.User) *restErr.RestErr
+	PurgeDeletedUsers(deletedBefore time.Time) (int64, *restErr.RestErr)
 }
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// The `PostgresWebAuthnRepository` interface defines the persistence of the passkeys of the users.
+type PostgresWebAuthnRepository interface {
+	SaveCredential(credential *entity.WebAuthnCredential) *restErr.RestErr
+	GetCredentials(userUUID string) ([]*entity.WebAuthnCredential, *restErr.RestErr)
+	UpdateCredential(credential *entity.WebAuthnCredential) *restErr.RestErr
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+`RedisLoginAttemptRepository` keeps the failed login counters.
+Each counter expires `ttl` after its latest failure, which also ends any lockout.
+*/
+type RedisLoginAttemptRepository interface {
+	GetLoginAttempts(key string) (*entity.LoginAttempts, *restErr.RestErr)
+	AddFailedLoginAttempt(key string, ttl time.Duration) (*entity.LoginAttempts, *restErr.RestErr)
+	DelLoginAttempts(keys ...string) *restErr.RestErr
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+`RedisOAuthRepository` keeps the grants of the OAuth clients, each looked up by the hash of its opaque token.
+Every token is single-use: a consent request is consumed by the answer of the user,
+an authorization code by the token request, and a refresh token by the refresh that rotates it.
+*/
+type RedisOAuthRepository interface {
+	// The consent request can be read by the consent page before the user answers it
+	SetConsentRequest(requestHash string, grant *entity.AuthorizationGrant, expiresIn int64) *restErr.RestErr
+	GetConsentRequest(requestHash string) (*entity.AuthorizationGrant, *restErr.RestErr)
+	ConsumeConsentRequest(requestHash string) (*entity.AuthorizationGrant, *restErr.RestErr)
+
+	SetAuthorizationCode(codeHash string, grant *entity.AuthorizationGrant, expiresIn int64) *restErr.RestErr
+	ConsumeAuthorizationCode(codeHash string) (*entity.AuthorizationGrant, *restErr.RestErr)
+
+	// The refresh tokens are indexed per user so that `DelUserSessions` revokes them with the sessions
+	SetOAuthRefreshToken(tokenHash string, grant *entity.AuthorizationGrant, expiresIn int64) *restErr.RestErr
+	ConsumeOAuthRefreshToken(tokenHash string) (*entity.AuthorizationGrant, *restErr.RestErr)
+}
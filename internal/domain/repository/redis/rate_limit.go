@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+`RateLimitRepository` counts requests in a sliding window.
+`Allow` only records the request when it is within the limit, so rejected requests do not extend the window.
+*/
+type RateLimitRepository interface {
+	Allow(key string, rule *entity.RateLimitRule) (*entity.RateLimit, *restErr.RestErr)
+}
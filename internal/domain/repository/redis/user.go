@@ -1,9 +1,57 @@
 package repository
 
-import restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
 
 type RedisUserRepository interface {
 	SetUserUUID(tokenUUID string, userUUID string, expiresIn int64) *restErr.RestErr
 	GetUserUUID(tokenUUID string) (string, *restErr.RestErr)
 	DelUserUUID(tokenUUID string, accessTokenUUID string) (int64, *restErr.RestErr)
+
+	/*
+		A token family groups every access and refresh token issued from a single login.
+		Refresh tokens are single-use: `ConsumeRefreshToken` removes the token so that
+		presenting it again can be detected and the whole family revoked with `DelTokenFamily`.
+	*/
+	SetTokenFamily(familyID string, tokenUUID string, expiresIn int64) *restErr.RestErr
+	GetTokenFamily(tokenUUID string) (string, *restErr.RestErr)
+	ConsumeRefreshToken(tokenUUID string) (string, *restErr.RestErr)
+	DelTokenFamily(familyID string) *restErr.RestErr
+
+	/*
+		Each token family is indexed per user as a `Session` so that it can be listed and revoked remotely.
+		`DelUserSessions` also revokes the refresh tokens that the user gave to OAuth clients.
+	*/
+	SetSession(session *entity.Session, expiresIn int64) *restErr.RestErr
+	GetSession(familyID string) (*entity.Session, *restErr.RestErr)
+	GetSessions(userUUID string) ([]*entity.Session, *restErr.RestErr)
+	DelUserSessions(userUUID string, exceptFamilyIDs ...string) *restErr.RestErr
+
+	// Only the latest email verification token of the user is valid, and only once
+	SetEmailVerification(userUUID string, tokenUUID string, expiresIn int64) *restErr.RestErr
+	ConsumeEmailVerification(userUUID string, tokenUUID string) *restErr.RestErr
+
+	// Password reset tokens are looked up by their hash; issuing a new one revokes the previous one
+	SetPasswordReset(userUUID string, tokenHash string, expiresIn int64) *restErr.RestErr
+	ConsumePasswordReset(tokenHash string) (string, *restErr.RestErr)
+
+	/*
+		An MFA challenge links the password step of a login to the second factor step.
+		It allows a limited number of failed codes and completes a single login.
+	*/
+	SetMFAChallenge(tokenHash string, userUUID string, expiresIn int64) *restErr.RestErr
+	GetMFAChallenge(tokenHash string) (string, *restErr.RestErr)
+	AddFailedMFAAttempt(tokenHash string) (int64, *restErr.RestErr)
+	ConsumeMFAChallenge(tokenHash string) *restErr.RestErr
+	UseTOTPStep(userUUID string, step int64) *restErr.RestErr
+
+	// The session data of a passkey ceremony is kept between its two requests and can only be used once
+	SetWebAuthnSession(key string, sessionData []byte, expiresIn int64) *restErr.RestErr
+	ConsumeWebAuthnSession(key string) ([]byte, *restErr.RestErr)
+
+	// The state of an OAuth2 login is kept from the redirect to the provider until the callback, which consumes it
+	SetOAuth2State(stateHash string, state *entity.OAuth2State, expiresIn int64) *restErr.RestErr
+	ConsumeOAuth2State(stateHash string) (*entity.OAuth2State, *restErr.RestErr)
 }
@@ -11,6 +11,9 @@ import (
 The `TokenService` interface define the contract for authentication-related operations.
 */
 type TokenService interface {
-	CreateToken(userUuid string, ttl time.Duration, privateKey string) (*entity.Token, *restErr.RestErr)
-	ValidateToken(token string, publicKey string) (*entity.Token, *restErr.RestErr)
+	CreateToken(userUuid string, scope []string, roles []string, ttl time.Duration, keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr)
+	CreateClientToken(subject string, clientID string, scope []string, ttl time.Duration, keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr)
+	CreateIDToken(idToken *entity.IDToken, ttl time.Duration, keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr)
+	ValidateToken(token string, keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr)
+	GetJWKS(keyRing *entity.JWTKeyRing) (*entity.JWKSet, *restErr.RestErr)
 }
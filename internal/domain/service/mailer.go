@@ -0,0 +1,11 @@
+package service
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// Mailer is the outbound port for transactional emails such as email verification.
+type Mailer interface {
+	Send(mail *entity.Mail) *restErr.RestErr
+}
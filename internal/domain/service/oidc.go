@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+The `OIDCProvider` logs users in with the authorization code flow of an OpenID Connect provider.
+The `state`, PKCE code verifier and `nonce` of a login are generated by the caller and passed to both steps.
+*/
+type OIDCProvider interface {
+	Name() string
+	AuthCodeURL(state, codeVerifier, nonce string) (string, *restErr.RestErr)
+
+	// Exchange redeems the code of the callback and returns the user of the verified ID token
+	Exchange(code, codeVerifier, nonce string) (*entity.ExternalUser, *restErr.RestErr)
+}
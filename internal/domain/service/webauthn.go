@@ -0,0 +1,25 @@
+package service
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+The `WebAuthnService` performs the passkey ceremonies of the relying party.
+Each ceremony is started with `Begin*` and the returned session data must be handed back to `Finish*`
+together with the raw JSON response of the authenticator.
+*/
+type WebAuthnService interface {
+	BeginRegistration(user *entity.User, credentials []*entity.WebAuthnCredential) (
+		*entity.WebAuthnCeremony, *restErr.RestErr)
+	FinishRegistration(user *entity.User, credentials []*entity.WebAuthnCredential, sessionData []byte,
+		response []byte) (*entity.WebAuthnCredential, *restErr.RestErr)
+
+	// Logins use discoverable credentials, so `findUser` resolves the user from the handle returned by the authenticator
+	BeginLogin() (*entity.WebAuthnCeremony, *restErr.RestErr)
+	FinishLogin(sessionData []byte, response []byte, findUser WebAuthnUserFinder) (
+		*entity.User, *entity.WebAuthnCredential, *restErr.RestErr)
+}
+
+type WebAuthnUserFinder func(userUUID string) (*entity.User, []*entity.WebAuthnCredential, *restErr.RestErr)
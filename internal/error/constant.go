@@ -3,13 +3,46 @@ package error
 const (
 	ErrTypeError            = "type error"
 	ErrUUIDError            = "uuid error"
+	ErrJSONParseError       = "json parse error"
 	ErrMsgRedisError        = "redis error"
 	ErrMsgPostgresError     = "postgres error"
+	ErrMsgMailerError       = "mailer error"
 	ErrMsgGoogleOAuth2Error = "google oauth2 error"
+	ErrMsgOIDCError         = "oidc error"
+	ErrMsgGitHubOAuth2Error = "github oauth2 error"
 
-	ErrMsgSomethingWentWrong  = "something went wrong"
-	ErrMsgPleaseLoginAgain    = "please login again"
-	ErrMsgInvalidToken        = "invalid token"
-	ErrMsgInvalidCredentials  = "invalid credentials"
-	ErrMsgEmailIsAlreadyTaken = "email is already taken"
+	ErrMsgSomethingWentWrong        = "something went wrong"
+	ErrMsgPleaseLoginAgain          = "please login again"
+	ErrMsgInvalidToken              = "invalid token"
+	ErrMsgInvalidCredentials        = "invalid credentials"
+	ErrMsgEmailIsAlreadyTaken       = "email is already taken"
+	ErrMsgRefreshTokenReuse         = "refresh token reuse detected; token family revoked"
+	ErrMsgSessionNotFound           = "session not found"
+	ErrMsgEmailNotVerified          = "please verify your email address before logging in"
+	ErrMsgTooManyRequests           = "too many requests; please try again later"
+	ErrMsgTOTPAlreadyEnabled        = "two-factor authentication is already enabled"
+	ErrMsgTOTPNotEnrolled           = "two-factor authentication enrollment has not been started"
+	ErrMsgInvalidMFACode            = "invalid two-factor authentication code"
+	ErrMsgPasskeyExpired            = "the passkey request has expired; please try again"
+	ErrMsgInvalidPasskey            = "invalid passkey"
+	ErrMsgPermissionDenied          = "you do not have permission to perform this action"
+	ErrMsgAccountDisabled           = "this account has been disabled"
+	ErrMsgUserNotFound              = "user not found"
+	ErrMsgIdentityIsAlreadyLinked   = "the external account is already linked to a user"
+	ErrMsgIdentityNotFound          = "external account not found"
+	ErrMsgLastLoginMethod           = "cannot remove the last login method of the account"
+	ErrMsgExternalEmailUnverified   = "the email address of the external account is not verified"
+	ErrMsgExternalAccountUnverified = "please login with the password of this email address and link the external account"
+	ErrMsgRecentLoginRequired       = "please login again to confirm"
+	ErrMsgOIDCProviderNotFound      = "login provider not found"
+	ErrMsgOAuth2StateExpired        = "the login request has expired or was already used; please login again"
+	ErrMsgStaleRecord               = "the record has been updated since it was read; please fetch it again"
+	ErrMsgOAuthClientNotFound       = "oauth client not found"
+	ErrMsgAPIClientNotFound         = "api client not found"
+	ErrMsgUserOnly                  = "this route is only available to users"
+	ErrMsgAPIKeyNotFound            = "api key not found"
+	ErrMsgInvalidAPIKey             = "the api key is invalid or expired"
+	ErrMsgSessionRequired           = "this route requires a login session instead of an api key"
+	ErrMsgAuthorizationExpired      = "the authorization request has expired or was already used; please try again"
+	ErrMsgInvalidGrant              = "the authorization code or refresh token is invalid, expired or was already used"
 )
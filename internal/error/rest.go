@@ -43,6 +43,34 @@ func NewUnauthorizedError(message string) *RestErr {
 	}
 }
 
+func NewForbiddenError(message string) *RestErr {
+	return &RestErr{
+		Message: message,
+		Status:  http.StatusForbidden,
+	}
+}
+
+func NewNotFoundError(message string) *RestErr {
+	return &RestErr{
+		Message: message,
+		Status:  http.StatusNotFound,
+	}
+}
+
+func NewConflictError(message string) *RestErr {
+	return &RestErr{
+		Message: message,
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewTooManyRequestsError(message string) *RestErr {
+	return &RestErr{
+		Message: message,
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
 func NewBadGatewayError(message string) *RestErr {
 	return &RestErr{
 		Message: message,
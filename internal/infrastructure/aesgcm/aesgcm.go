@@ -0,0 +1,74 @@
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgInvalidKey        = "encryption key must be 32 bytes encoded in base64"
+	errMsgInvalidCiphertext = "ciphertext is malformed or was encrypted with another key"
+	errMsgEncryptionError   = "encryption error"
+)
+
+/*
+Encrypt seals the plaintext with AES-256-GCM under the base64 encoded `key`.
+The random nonce is prepended to the ciphertext and the result is base64 encoded for storage.
+*/
+func Encrypt(key string, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		log.Error().Err(err).Msg(errMsgEncryptionError)
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(key string, ciphertext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		log.Error().Msg(errMsgInvalidCiphertext)
+		return "", errors.New(errMsgInvalidCiphertext)
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgInvalidCiphertext)
+		return "", errors.New(errMsgInvalidCiphertext)
+	}
+
+	return string(plaintext), nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decodedKey) != 32 {
+		log.Error().Msg(errMsgInvalidKey)
+		return nil, errors.New(errMsgInvalidKey)
+	}
+
+	block, err := aes.NewCipher(decodedKey)
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Msg(errMsgEncryptionError)
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,47 @@
+package aesgcm
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecrypt(t *testing.T) {
+	for _, test := range decryptTests {
+		t.Run(test.name, func(t *testing.T) {
+			ciphertext, err := Encrypt(test.encryptKey, testSecret)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %v", err)
+			}
+
+			if test.tamper {
+				sealed, _ := base64.StdEncoding.DecodeString(ciphertext)
+				sealed[len(sealed)-1] ^= 0xff
+				ciphertext = base64.StdEncoding.EncodeToString(sealed)
+			}
+
+			plaintext, err := Decrypt(test.decryptKey, ciphertext)
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("expected error '%s', got '%v'", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil || plaintext != testSecret {
+				t.Errorf("expected '%s', got '%s' (%v)", testSecret, plaintext, err)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesRandomNonce(t *testing.T) {
+	first, _ := Encrypt(testKey, testSecret)
+	second, _ := Encrypt(testKey, testSecret)
+	if first == second {
+		t.Errorf("expected different ciphertexts for the same plaintext")
+	}
+
+	if _, err := Encrypt(shortTestKey, testSecret); err == nil || err.Error() != errMsgInvalidKey {
+		t.Errorf("expected error '%s', got '%v'", errMsgInvalidKey, err)
+	}
+}
@@ -0,0 +1,24 @@
+// coverage:ignore file
+// Test file
+package aesgcm
+
+const (
+	testKey      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // "0123456789abcdef0123456789abcdef"
+	otherTestKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=" // "fedcba9876543210fedcba9876543210"
+	shortTestKey = "MDEyMzQ1Njc4OWFiY2RlZg=="                     // "0123456789abcdef"
+	testSecret   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+)
+
+var decryptTests = []struct {
+	name        string
+	encryptKey  string
+	decryptKey  string
+	tamper      bool // Flips the last byte of the ciphertext
+	expectedErr string
+}{
+	{name: "Round trip", encryptKey: testKey, decryptKey: testKey},
+	{name: "Wrong key", encryptKey: testKey, decryptKey: otherTestKey, expectedErr: errMsgInvalidCiphertext},
+	{name: "Tampered ciphertext", encryptKey: testKey, decryptKey: testKey, tamper: true,
+		expectedErr: errMsgInvalidCiphertext},
+	{name: "Short key", encryptKey: testKey, decryptKey: shortTestKey, expectedErr: errMsgInvalidKey},
+}
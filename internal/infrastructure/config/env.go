@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +22,10 @@ const (
 	errMsgVarNotSet       = "%s is not set"
 	errMsgInvalidLogLevel = "%s is[%s]; only 'trace', 'debug', 'info', 'warn', 'error', 'fatal', 'panic' are accepted"
 	errMsgCheckJWTConfig  = "check JWT config: %s"
+	errMsgInvalidJWTAlg   = "%s is[%s]; only 'RS256', 'ES256', or 'EdDSA' are accepted"
+
+	errMsgInvalidOIDCProvider = "OIDC provider [%s] is ignored: %s"
+	errMsgOIDCProvidersFile   = "failed to load the OIDC providers from [%s]"
 )
 
 type EnvConfig struct {
@@ -133,12 +139,31 @@ func (e *EnvConfig) LoadJWTConfig() {
 	e.JWTConfig.Domain = checkEmptyEnvVar("JWT_DOMAIN")
 	loadEnvVariableBool("JWT_SECURE", &e.JWTConfig.Secure)
 	loadEnvVariableBool("JWT_HTTPONLY", &e.JWTConfig.HttpOnly)
-	e.JWTConfig.AccessTokenPrivateKey = checkEmptyEnvVar("ACCESS_TOKEN_PRIVATE_KEY")
-	e.JWTConfig.AccessTokenPublicKey = checkEmptyEnvVar("ACCESS_TOKEN_PUBLIC_KEY")
+	signingAlg := loadSigningAlgorithm("JWT_SIGNING_ALG")
+	issuer := checkEmptyEnvVar("JWT_ISSUER")
+	var leeway time.Duration
+	loadEnvVariableDuration("JWT_LEEWAY", &leeway)
+	e.JWTConfig.DefaultScope = strings.Fields(os.Getenv("JWT_DEFAULT_SCOPE")) // space-delimited as in RFC 6749
+	e.JWTConfig.AccessTokenKeyRing = &entity.JWTKeyRing{
+		Algorithm:         signingAlg,
+		PrivateKey:        checkEmptyEnvVar("ACCESS_TOKEN_PRIVATE_KEY"),
+		PublicKey:         checkEmptyEnvVar("ACCESS_TOKEN_PUBLIC_KEY"),
+		RetiredPublicKeys: loadEnvVariableList("ACCESS_TOKEN_RETIRED_PUBLIC_KEYS"),
+		Issuer:            issuer,
+		Audience:          checkEmptyEnvVar("ACCESS_TOKEN_AUDIENCE"),
+		Leeway:            leeway,
+	}
 	loadEnvVariableDuration("ACCESS_TOKEN_EXPIRED_IN", &e.JWTConfig.AccessTokenExpiredIn)
 	loadEnvVariableInt("ACCESS_TOKEN_MAXAGE", &e.JWTConfig.AccessTokenMaxAge)
-	e.JWTConfig.RefreshTokenPrivateKey = checkEmptyEnvVar("REFRESH_TOKEN_PRIVATE_KEY")
-	e.JWTConfig.RefreshTokenPublicKey = checkEmptyEnvVar("REFRESH_TOKEN_PUBLIC_KEY")
+	e.JWTConfig.RefreshTokenKeyRing = &entity.JWTKeyRing{
+		Algorithm:         signingAlg,
+		PrivateKey:        checkEmptyEnvVar("REFRESH_TOKEN_PRIVATE_KEY"),
+		PublicKey:         checkEmptyEnvVar("REFRESH_TOKEN_PUBLIC_KEY"),
+		RetiredPublicKeys: loadEnvVariableList("REFRESH_TOKEN_RETIRED_PUBLIC_KEYS"),
+		Issuer:            issuer,
+		Audience:          checkEmptyEnvVar("REFRESH_TOKEN_AUDIENCE"),
+		Leeway:            leeway,
+	}
 	loadEnvVariableDuration("REFRESH_TOKEN_EXPIRED_IN", &e.JWTConfig.RefreshTokenExpiredIn)
 	loadEnvVariableInt("REFRESH_TOKEN_MAXAGE", &e.JWTConfig.RefreshTokenMaxAge)
 }
@@ -159,10 +184,178 @@ func (e *EnvConfig) LoadOAuth2Config() {
 		GoogleClientID:     checkEmptyEnvVar("GOOGLE_CLIENT_ID"),
 		GoogleClientSecret: checkEmptyEnvVar("GOOGLE_CLIENT_SECRET"),
 		Scopes: []string{
+			"openid", // Returns an ID token that carries the nonce
 			"https://www.googleapis.com/auth/userinfo.email",
 			"https://www.googleapis.com/auth/userinfo.profile",
 		},
+
+		// GitHub -> Settings -> Developer settings -> OAuth Apps -> Authorization callback URL
+		GitHubRedirectURL:  protocol + domain + ":" + e.Port + "/auth/github_callback",
+		GitHubClientID:     checkEmptyEnvVar("GITHUB_CLIENT_ID"),
+		GitHubClientSecret: checkEmptyEnvVar("GITHUB_CLIENT_SECRET"),
+		GitHubScopes:       []string{"read:user", "user:email"}, // The emails are private unless `user:email` is granted
+
+		PostLoginRedirectURL: checkEmptyEnvVar("OAUTH2_POST_LOGIN_REDIRECT_URL"),
+	}
+
+	loadEnvVariableDuration("OAUTH2_STATE_EXPIRED_IN", &e.OAuth2Config.StateExpiredIn)
+
+	/*
+		Each provider in OIDC_PROVIDERS is configured with the variables prefixed by OIDC_<NAME>_.
+		More providers can be listed in the JSON file at OIDC_PROVIDERS_FILE, e.g. a mounted secret.
+	*/
+	providers := []*entity.OIDCProviderConfig{}
+	for _, name := range loadEnvVariableList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, &entity.OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    checkEmptyEnvVar(prefix + "ISSUER_URL"),
+			ClientID:     checkEmptyEnvVar(prefix + "CLIENT_ID"),
+			ClientSecret: checkEmptyEnvVar(prefix + "CLIENT_SECRET"),
+			Scopes:       loadEnvVariableList(prefix + "SCOPES"),
+		})
+	}
+
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		providers = append(providers, loadOIDCProvidersFile(path)...)
+	}
+
+	e.OAuth2Config.OIDCProviders = []*entity.OIDCProviderConfig{}
+	for _, provider := range providers {
+		provider.Name = strings.ToLower(provider.Name)
+		if !checkOIDCProviderName(provider.Name, e.OAuth2Config.OIDCProviders) {
+			continue
+		}
+
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		provider.RedirectURL = protocol + domain + ":" + e.Port + "/auth/" + provider.Name + "/callback"
+		e.OAuth2Config.OIDCProviders = append(e.OAuth2Config.OIDCProviders, provider)
+	}
+}
+
+/*
+checkOIDCProviderName accepts the name of a provider that is unique among `providers` and the built-in providers,
+since it is the provider of the identities linked with it.
+*/
+func checkOIDCProviderName(name string, providers []*entity.OIDCProviderConfig) bool {
+	switch {
+	case name == "" || strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789") != "":
+		log.Error().Msgf(errMsgInvalidOIDCProvider, name, "only letters and digits are accepted")
+	case name == entity.ProviderGoogle || name == entity.ProviderGitHub:
+		log.Error().Msgf(errMsgInvalidOIDCProvider, name, "the name is used by a built-in provider")
+	case slices.ContainsFunc(providers, func(p *entity.OIDCProviderConfig) bool { return p.Name == name }):
+		log.Error().Msgf(errMsgInvalidOIDCProvider, name, "the name is used by another provider")
+	default:
+		return true
+	}
+	return false
+}
+
+// oidcProviderFile is a provider in the file at OIDC_PROVIDERS_FILE.
+type oidcProviderFile struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuer_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+func loadOIDCProvidersFile(path string) []*entity.OIDCProviderConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Error().Err(err).Msgf(errMsgOIDCProvidersFile, path)
+		return nil
+	}
+
+	var entries []oidcProviderFile
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Error().Err(err).Msgf(errMsgOIDCProvidersFile, path)
+		return nil
 	}
+
+	providers := []*entity.OIDCProviderConfig{}
+	for _, entry := range entries {
+		if entry.IssuerURL == "" || entry.ClientID == "" || entry.ClientSecret == "" {
+			log.Error().Msgf(errMsgInvalidOIDCProvider, entry.Name, "issuer_url, client_id and client_secret are required")
+			continue
+		}
+
+		providers = append(providers, &entity.OIDCProviderConfig{
+			Name:         entry.Name,
+			IssuerURL:    entry.IssuerURL,
+			ClientID:     entry.ClientID,
+			ClientSecret: entry.ClientSecret,
+			Scopes:       entry.Scopes,
+		})
+	}
+	return providers
+}
+
+func (e *EnvConfig) LoadMailerConfig() {
+	e.MailerConfig = &entity.MailerConfig{
+		Mailer:       checkEmptyEnvVar("MAILER"),
+		From:         checkEmptyEnvVar("MAILER_FROM"),
+		FileDir:      os.Getenv("MAILER_FILE_DIR"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+func (e *EnvConfig) LoadAccountConfig() {
+	e.AccountConfig = &entity.AccountConfig{}
+	loadEnvVariableBool("REQUIRE_EMAIL_VERIFICATION", &e.AccountConfig.RequireEmailVerification)
+	loadEnvVariableDuration("EMAIL_VERIFICATION_EXPIRED_IN", &e.AccountConfig.EmailVerificationExpiredIn)
+	loadEnvVariableDuration("PASSWORD_RESET_EXPIRED_IN", &e.AccountConfig.PasswordResetExpiredIn)
+	e.AccountConfig.PasswordResetURL = checkEmptyEnvVar("PASSWORD_RESET_URL")
+	loadEnvVariableInt("LOGIN_MAX_ATTEMPTS", &e.AccountConfig.MaxLoginAttempts)
+	loadEnvVariableInt("LOGIN_MAX_ATTEMPTS_PER_IP", &e.AccountConfig.MaxLoginAttemptsPerIP)
+	loadEnvVariableDuration("LOGIN_BACKOFF_BASE", &e.AccountConfig.LoginBackoffBase)
+	loadEnvVariableDuration("LOGIN_LOCKOUT_DURATION", &e.AccountConfig.LoginLockoutDuration)
+	e.AccountConfig.TOTPIssuer = checkEmptyEnvVar("TOTP_ISSUER")
+	e.AccountConfig.TOTPEncryptionKey = checkEmptyEnvVar("TOTP_ENCRYPTION_KEY")
+	loadEnvVariableDuration("MFA_CHALLENGE_EXPIRED_IN", &e.AccountConfig.MFAChallengeExpiredIn)
+	loadEnvVariableDuration("ACCOUNT_DELETION_GRACE_PERIOD", &e.AccountConfig.DeletionGracePeriod)
+	loadEnvVariableDuration("ACCOUNT_PURGE_INTERVAL", &e.AccountConfig.PurgeInterval)
+	loadEnvVariableDuration("ACCOUNT_RECENT_LOGIN_MAX_AGE", &e.AccountConfig.RecentLoginMaxAge)
+}
+
+func (e *EnvConfig) LoadRateLimitConfig() {
+	e.RateLimitConfig = &entity.RateLimitConfig{
+		Store: checkEmptyEnvVar("RATE_LIMIT_STORE"),
+		Auth:  &entity.RateLimitRule{},
+		User:  &entity.RateLimitRule{},
+	}
+
+	loadEnvVariableInt("RATE_LIMIT_AUTH_REQUESTS", &e.RateLimitConfig.Auth.Limit)
+	loadEnvVariableDuration("RATE_LIMIT_AUTH_WINDOW", &e.RateLimitConfig.Auth.Window)
+	loadEnvVariableInt("RATE_LIMIT_USER_REQUESTS", &e.RateLimitConfig.User.Limit)
+	loadEnvVariableDuration("RATE_LIMIT_USER_WINDOW", &e.RateLimitConfig.User.Window)
+}
+
+func (e *EnvConfig) LoadWebAuthnConfig() {
+	e.WebAuthnConfig = &entity.WebAuthnConfig{
+		RPID:          checkEmptyEnvVar("WEBAUTHN_RP_ID"),
+		RPDisplayName: checkEmptyEnvVar("WEBAUTHN_RP_DISPLAY_NAME"),
+		RPOrigins:     loadEnvVariableList("WEBAUTHN_RP_ORIGINS"),
+	}
+
+	loadEnvVariableDuration("WEBAUTHN_TIMEOUT", &e.WebAuthnConfig.Timeout)
+}
+
+func (e *EnvConfig) LoadOAuthServerConfig() {
+	e.OAuthServerConfig = &entity.OAuthServerConfig{
+		LoginURL:   checkEmptyEnvVar("OAUTH_SERVER_LOGIN_URL"),
+		ConsentURL: checkEmptyEnvVar("OAUTH_SERVER_CONSENT_URL"),
+	}
+
+	loadEnvVariableDuration("OAUTH_SERVER_REQUEST_EXPIRED_IN", &e.OAuthServerConfig.AuthorizationRequestExpiredIn)
+	loadEnvVariableDuration("OAUTH_SERVER_CODE_EXPIRED_IN", &e.OAuthServerConfig.AuthorizationCodeExpiredIn)
+	loadEnvVariableDuration("OAUTH_SERVER_ID_TOKEN_EXPIRED_IN", &e.OAuthServerConfig.IDTokenExpiredIn)
+	loadEnvVariableDuration("OAUTH_SERVER_CLIENT_TOKEN_EXPIRED_IN", &e.OAuthServerConfig.ClientCredentialsExpiredIn)
 }
 
 func checkEmptyEnvVar(envVar string) string {
@@ -202,3 +395,28 @@ func loadEnvVariableDuration(envVar string, target *time.Duration) {
 	}
 	*target = value
 }
+
+// loadSigningAlgorithm defaults to RS256 so that existing RSA key pairs keep working.
+func loadSigningAlgorithm(envVar string) string {
+	signingAlg := os.Getenv(envVar)
+	switch signingAlg {
+	case "RS256", "ES256", "EdDSA":
+		return signingAlg
+	case "":
+		log.Info().Msgf(infoMsgDefaultEnvVar, envVar, "RS256", "all")
+	default:
+		log.Error().Msgf(errMsgInvalidJWTAlg, envVar, signingAlg)
+	}
+	return "RS256"
+}
+
+// loadEnvVariableList splits an optional comma-separated env variable, ignoring empty entries.
+func loadEnvVariableList(envVar string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(envVar), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -194,6 +194,13 @@ func TestLoadJWTConfig(t *testing.T) {
 	os.Setenv("REFRESH_TOKEN_PUBLIC_KEY", "Only checkEmptyEnvVar validation")
 	os.Setenv("REFRESH_TOKEN_EXPIRED_IN", "1200m")
 	os.Setenv("REFRESH_TOKEN_MAXAGE", "600")
+	os.Setenv("ACCESS_TOKEN_RETIRED_PUBLIC_KEYS", "retiredKey1, retiredKey2,")
+	os.Setenv("JWT_SIGNING_ALG", "ES256")
+	os.Setenv("JWT_ISSUER", "http://localhost:8080/auth")
+	os.Setenv("ACCESS_TOKEN_AUDIENCE", "http://localhost:8080/auth/api")
+	os.Setenv("REFRESH_TOKEN_AUDIENCE", "http://localhost:8080/auth/api/v1/users/refresh")
+	os.Setenv("JWT_LEEWAY", "30s")
+	os.Setenv("JWT_DEFAULT_SCOPE", " openid  profile email ")
 
 	defer os.Unsetenv("JWT_PATH")
 	defer os.Unsetenv("JWT_DOMAIN")
@@ -207,6 +214,13 @@ func TestLoadJWTConfig(t *testing.T) {
 	defer os.Unsetenv("REFRESH_TOKEN_PUBLIC_KEY")
 	defer os.Unsetenv("REFRESH_TOKEN_EXPIRED_IN")
 	defer os.Unsetenv("REFRESH_TOKEN_MAXAGE")
+	defer os.Unsetenv("ACCESS_TOKEN_RETIRED_PUBLIC_KEYS")
+	defer os.Unsetenv("JWT_SIGNING_ALG")
+	defer os.Unsetenv("JWT_ISSUER")
+	defer os.Unsetenv("ACCESS_TOKEN_AUDIENCE")
+	defer os.Unsetenv("REFRESH_TOKEN_AUDIENCE")
+	defer os.Unsetenv("JWT_LEEWAY")
+	defer os.Unsetenv("JWT_DEFAULT_SCOPE")
 
 	e.LoadJWTConfig()
 
@@ -226,13 +240,21 @@ func TestLoadJWTConfig(t *testing.T) {
 	if e.JWTConfig.HttpOnly != true {
 		t.Errorf("expected HttpOnly to be 'true', got '%t'", e.JWTConfig.HttpOnly)
 	}
-	if e.JWTConfig.AccessTokenPrivateKey != "Only checkEmptyEnvVar validation" {
-		t.Errorf("expected AccessTokenPrivateKey to be 'Only checkEmptyEnvVar validation', got '%s'",
-			e.JWTConfig.AccessTokenPrivateKey)
+	if e.JWTConfig.AccessTokenKeyRing.Algorithm != "ES256" || e.JWTConfig.RefreshTokenKeyRing.Algorithm != "ES256" {
+		t.Errorf("expected key ring Algorithm to be 'ES256', got '%s' and '%s'",
+			e.JWTConfig.AccessTokenKeyRing.Algorithm, e.JWTConfig.RefreshTokenKeyRing.Algorithm)
 	}
-	if e.JWTConfig.AccessTokenPublicKey != "Only checkEmptyEnvVar validation" {
-		t.Errorf("expected AccessTokenPublicKey to be 'Only checkEmptyEnvVar validation', got '%s'",
-			e.JWTConfig.AccessTokenPublicKey)
+	if e.JWTConfig.AccessTokenKeyRing.PrivateKey != "Only checkEmptyEnvVar validation" {
+		t.Errorf("expected AccessTokenKeyRing.PrivateKey to be 'Only checkEmptyEnvVar validation', got '%s'",
+			e.JWTConfig.AccessTokenKeyRing.PrivateKey)
+	}
+	if e.JWTConfig.AccessTokenKeyRing.PublicKey != "Only checkEmptyEnvVar validation" {
+		t.Errorf("expected AccessTokenKeyRing.PublicKey to be 'Only checkEmptyEnvVar validation', got '%s'",
+			e.JWTConfig.AccessTokenKeyRing.PublicKey)
+	}
+	if retiredKeys := e.JWTConfig.AccessTokenKeyRing.RetiredPublicKeys; len(retiredKeys) != 2 ||
+		retiredKeys[0] != "retiredKey1" || retiredKeys[1] != "retiredKey2" {
+		t.Errorf("expected AccessTokenKeyRing.RetiredPublicKeys to be '[retiredKey1 retiredKey2]', got '%v'", retiredKeys)
 	}
 	if e.JWTConfig.AccessTokenExpiredIn != expiredIn {
 		t.Errorf("expected AccessTokenExpiredIn to be '%s', got '%s'",
@@ -241,11 +263,14 @@ func TestLoadJWTConfig(t *testing.T) {
 	if e.JWTConfig.AccessTokenMaxAge != maxAge {
 		t.Errorf("expected AccessTokenMaxAge to be '%d', got '%d'", maxAge, e.JWTConfig.AccessTokenMaxAge)
 	}
-	if e.JWTConfig.RefreshTokenPrivateKey != "Only checkEmptyEnvVar validation" {
-		t.Errorf("expected RefreshTokenPrivateKey to be 'Only checkEmptyEnvVar validation', got '%s'", e.JWTConfig.RefreshTokenPrivateKey)
+	if e.JWTConfig.RefreshTokenKeyRing.PrivateKey != "Only checkEmptyEnvVar validation" {
+		t.Errorf("expected RefreshTokenKeyRing.PrivateKey to be 'Only checkEmptyEnvVar validation', got '%s'", e.JWTConfig.RefreshTokenKeyRing.PrivateKey)
 	}
-	if e.JWTConfig.RefreshTokenPublicKey != "Only checkEmptyEnvVar validation" {
-		t.Errorf("expected RefreshTokenPublicKey to be 'Only checkEmptyEnvVar validation', got '%s'", e.JWTConfig.RefreshTokenPublicKey)
+	if e.JWTConfig.RefreshTokenKeyRing.PublicKey != "Only checkEmptyEnvVar validation" {
+		t.Errorf("expected RefreshTokenKeyRing.PublicKey to be 'Only checkEmptyEnvVar validation', got '%s'", e.JWTConfig.RefreshTokenKeyRing.PublicKey)
+	}
+	if len(e.JWTConfig.RefreshTokenKeyRing.RetiredPublicKeys) != 0 {
+		t.Errorf("expected RefreshTokenKeyRing.RetiredPublicKeys to be empty, got '%v'", e.JWTConfig.RefreshTokenKeyRing.RetiredPublicKeys)
 	}
 	if e.JWTConfig.RefreshTokenExpiredIn != expiredIn {
 		t.Errorf("expected AccessTokenExpiredIn to be '%s', got '%s'",
@@ -254,6 +279,50 @@ func TestLoadJWTConfig(t *testing.T) {
 	if e.JWTConfig.RefreshTokenMaxAge != maxAge {
 		t.Errorf("expected RefreshTokenMaxAge to be '%d', got '%d'", maxAge, e.JWTConfig.RefreshTokenMaxAge)
 	}
+	if scope := e.JWTConfig.DefaultScope; len(scope) != 3 || scope[0] != "openid" || scope[2] != "email" {
+		t.Errorf("expected DefaultScope to be '[openid profile email]', got '%v'", scope)
+	}
+	for _, keyRing := range []*entity.JWTKeyRing{e.JWTConfig.AccessTokenKeyRing, e.JWTConfig.RefreshTokenKeyRing} {
+		if keyRing.Issuer != "http://localhost:8080/auth" {
+			t.Errorf("expected Issuer to be 'http://localhost:8080/auth', got '%s'", keyRing.Issuer)
+		}
+		if keyRing.Leeway != 30*time.Second {
+			t.Errorf("expected Leeway to be '30s', got '%s'", keyRing.Leeway.String())
+		}
+	}
+	if e.JWTConfig.AccessTokenKeyRing.Audience != "http://localhost:8080/auth/api" {
+		t.Errorf("expected AccessTokenKeyRing.Audience to be 'http://localhost:8080/auth/api', got '%s'",
+			e.JWTConfig.AccessTokenKeyRing.Audience)
+	}
+	if e.JWTConfig.RefreshTokenKeyRing.Audience != "http://localhost:8080/auth/api/v1/users/refresh" {
+		t.Errorf("expected RefreshTokenKeyRing.Audience to be 'http://localhost:8080/auth/api/v1/users/refresh', got '%s'",
+			e.JWTConfig.RefreshTokenKeyRing.Audience)
+	}
+}
+
+func TestLoadSigningAlgorithm(t *testing.T) {
+	tests := []struct {
+		name        string
+		signingAlg  string
+		expectedAlg string
+	}{
+		{name: "RS256", signingAlg: "RS256", expectedAlg: "RS256"},
+		{name: "ES256", signingAlg: "ES256", expectedAlg: "ES256"},
+		{name: "EdDSA", signingAlg: "EdDSA", expectedAlg: "EdDSA"},
+		{name: "Empty", signingAlg: "", expectedAlg: "RS256"},
+		{name: "HMAC is not accepted", signingAlg: "HS256", expectedAlg: "RS256"},
+		{name: "none is not accepted", signingAlg: "none", expectedAlg: "RS256"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("JWT_SIGNING_ALG", test.signingAlg)
+			defer os.Unsetenv("JWT_SIGNING_ALG")
+			if signingAlg := loadSigningAlgorithm("JWT_SIGNING_ALG"); signingAlg != test.expectedAlg {
+				t.Errorf("expected signingAlg to be '%s', got '%s'", test.expectedAlg, signingAlg)
+			}
+		})
+	}
 }
 
 func TestLoadCORSConfig(t *testing.T) {
@@ -271,6 +340,112 @@ func TestLoadCORSConfig(t *testing.T) {
 	}
 }
 
+func TestLoadMailerConfig(t *testing.T) {
+	e := &EnvConfig{}
+	os.Setenv("MAILER", "file")
+	os.Setenv("MAILER_FROM", "no-reply@localhost")
+	os.Setenv("MAILER_FILE_DIR", "/tmp/mail")
+	os.Setenv("SMTP_PORT", "587")
+	defer os.Unsetenv("MAILER")
+	defer os.Unsetenv("MAILER_FROM")
+	defer os.Unsetenv("MAILER_FILE_DIR")
+	defer os.Unsetenv("SMTP_PORT")
+	e.LoadMailerConfig()
+
+	if e.MailerConfig == nil {
+		t.Fatalf("MailerConfig is nil")
+	}
+
+	if e.MailerConfig.Mailer != "file" {
+		t.Errorf("expected Mailer to be 'file', got '%s'", e.MailerConfig.Mailer)
+	}
+	if e.MailerConfig.From != "no-reply@localhost" {
+		t.Errorf("expected From to be 'no-reply@localhost', got '%s'", e.MailerConfig.From)
+	}
+	if e.MailerConfig.FileDir != "/tmp/mail" {
+		t.Errorf("expected FileDir to be '/tmp/mail', got '%s'", e.MailerConfig.FileDir)
+	}
+	if e.MailerConfig.SMTPPort != "587" || e.MailerConfig.SMTPHost != "" {
+		t.Errorf("expected SMTPHost:SMTPPort to be ':587', got '%s:%s'", e.MailerConfig.SMTPHost, e.MailerConfig.SMTPPort)
+	}
+}
+
+func TestLoadAccountConfig(t *testing.T) {
+	e := &EnvConfig{}
+	os.Setenv("REQUIRE_EMAIL_VERIFICATION", "TRUE")
+	os.Setenv("EMAIL_VERIFICATION_EXPIRED_IN", "24h")
+	os.Setenv("PASSWORD_RESET_EXPIRED_IN", "15m")
+	os.Setenv("PASSWORD_RESET_URL", "http://localhost:3030/reset-password")
+	os.Setenv("LOGIN_MAX_ATTEMPTS", "5")
+	os.Setenv("LOGIN_MAX_ATTEMPTS_PER_IP", "50")
+	os.Setenv("LOGIN_BACKOFF_BASE", "1s")
+	os.Setenv("LOGIN_LOCKOUT_DURATION", "15m")
+	os.Setenv("TOTP_ISSUER", "starter-go-postgresql")
+	os.Setenv("TOTP_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	os.Setenv("MFA_CHALLENGE_EXPIRED_IN", "5m")
+	os.Setenv("ACCOUNT_DELETION_GRACE_PERIOD", "720h")
+	os.Setenv("ACCOUNT_PURGE_INTERVAL", "1h")
+	os.Setenv("ACCOUNT_RECENT_LOGIN_MAX_AGE", "5m")
+	defer os.Unsetenv("REQUIRE_EMAIL_VERIFICATION")
+	defer os.Unsetenv("EMAIL_VERIFICATION_EXPIRED_IN")
+	defer os.Unsetenv("PASSWORD_RESET_EXPIRED_IN")
+	defer os.Unsetenv("PASSWORD_RESET_URL")
+	defer os.Unsetenv("LOGIN_MAX_ATTEMPTS")
+	defer os.Unsetenv("LOGIN_MAX_ATTEMPTS_PER_IP")
+	defer os.Unsetenv("LOGIN_BACKOFF_BASE")
+	defer os.Unsetenv("LOGIN_LOCKOUT_DURATION")
+	defer os.Unsetenv("TOTP_ISSUER")
+	defer os.Unsetenv("TOTP_ENCRYPTION_KEY")
+	defer os.Unsetenv("MFA_CHALLENGE_EXPIRED_IN")
+	defer os.Unsetenv("ACCOUNT_DELETION_GRACE_PERIOD")
+	defer os.Unsetenv("ACCOUNT_PURGE_INTERVAL")
+	defer os.Unsetenv("ACCOUNT_RECENT_LOGIN_MAX_AGE")
+	e.LoadAccountConfig()
+
+	if e.AccountConfig == nil {
+		t.Fatalf("AccountConfig is nil")
+	}
+
+	if e.AccountConfig.RequireEmailVerification != true {
+		t.Errorf("expected RequireEmailVerification to be 'true', got '%t'", e.AccountConfig.RequireEmailVerification)
+	}
+	if e.AccountConfig.EmailVerificationExpiredIn != 24*time.Hour {
+		t.Errorf("expected EmailVerificationExpiredIn to be '24h0m0s', got '%s'",
+			e.AccountConfig.EmailVerificationExpiredIn.String())
+	}
+	if e.AccountConfig.PasswordResetExpiredIn != 15*time.Minute {
+		t.Errorf("expected PasswordResetExpiredIn to be '15m0s', got '%s'", e.AccountConfig.PasswordResetExpiredIn.String())
+	}
+	if e.AccountConfig.PasswordResetURL != "http://localhost:3030/reset-password" {
+		t.Errorf("expected PasswordResetURL to be 'http://localhost:3030/reset-password', got '%s'",
+			e.AccountConfig.PasswordResetURL)
+	}
+	if e.AccountConfig.MaxLoginAttempts != 5 || e.AccountConfig.MaxLoginAttemptsPerIP != 50 {
+		t.Errorf("expected MaxLoginAttempts/MaxLoginAttemptsPerIP to be '5/50', got '%d/%d'",
+			e.AccountConfig.MaxLoginAttempts, e.AccountConfig.MaxLoginAttemptsPerIP)
+	}
+	if e.AccountConfig.LoginBackoffBase != time.Second || e.AccountConfig.LoginLockoutDuration != 15*time.Minute {
+		t.Errorf("expected LoginBackoffBase/LoginLockoutDuration to be '1s/15m0s', got '%s/%s'",
+			e.AccountConfig.LoginBackoffBase.String(), e.AccountConfig.LoginLockoutDuration.String())
+	}
+	if e.AccountConfig.TOTPIssuer != "starter-go-postgresql" {
+		t.Errorf("expected TOTPIssuer to be 'starter-go-postgresql', got '%s'", e.AccountConfig.TOTPIssuer)
+	}
+	if e.AccountConfig.TOTPEncryptionKey != "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" {
+		t.Errorf("expected TOTPEncryptionKey to be set, got '%s'", e.AccountConfig.TOTPEncryptionKey)
+	}
+	if e.AccountConfig.MFAChallengeExpiredIn != 5*time.Minute {
+		t.Errorf("expected MFAChallengeExpiredIn to be '5m0s', got '%s'", e.AccountConfig.MFAChallengeExpiredIn.String())
+	}
+	if e.AccountConfig.DeletionGracePeriod != 720*time.Hour || e.AccountConfig.PurgeInterval != time.Hour {
+		t.Errorf("expected DeletionGracePeriod/PurgeInterval to be '720h0m0s/1h0m0s', got '%s/%s'",
+			e.AccountConfig.DeletionGracePeriod.String(), e.AccountConfig.PurgeInterval.String())
+	}
+	if e.AccountConfig.RecentLoginMaxAge != 5*time.Minute {
+		t.Errorf("expected RecentLoginMaxAge to be '5m0s', got '%s'", e.AccountConfig.RecentLoginMaxAge.String())
+	}
+}
+
 func TestCheckEmptyEnvVar(t *testing.T) {
 	// Create a buffer to capture stdout
 	var buf bytes.Buffer
@@ -346,3 +521,228 @@ func TestLoadEnvVariableDuration(t *testing.T) {
 
 	log.Logger = log.Output(os.Stdout)
 }
+
+func TestLoadRateLimitConfig(t *testing.T) {
+	e := &EnvConfig{}
+	os.Setenv("RATE_LIMIT_STORE", "redis")
+	os.Setenv("RATE_LIMIT_AUTH_REQUESTS", "20")
+	os.Setenv("RATE_LIMIT_AUTH_WINDOW", "1m")
+	os.Setenv("RATE_LIMIT_USER_REQUESTS", "300")
+	os.Setenv("RATE_LIMIT_USER_WINDOW", "1h")
+	defer os.Unsetenv("RATE_LIMIT_STORE")
+	defer os.Unsetenv("RATE_LIMIT_AUTH_REQUESTS")
+	defer os.Unsetenv("RATE_LIMIT_AUTH_WINDOW")
+	defer os.Unsetenv("RATE_LIMIT_USER_REQUESTS")
+	defer os.Unsetenv("RATE_LIMIT_USER_WINDOW")
+	e.LoadRateLimitConfig()
+
+	if e.RateLimitConfig == nil {
+		t.Fatalf("RateLimitConfig is nil")
+	}
+
+	if e.RateLimitConfig.Store != "redis" {
+		t.Errorf("expected Store to be 'redis', got '%s'", e.RateLimitConfig.Store)
+	}
+	if e.RateLimitConfig.Auth.Limit != 20 || e.RateLimitConfig.Auth.Window != time.Minute {
+		t.Errorf("expected Auth to be '20/1m0s', got '%d/%s'",
+			e.RateLimitConfig.Auth.Limit, e.RateLimitConfig.Auth.Window.String())
+	}
+	if e.RateLimitConfig.User.Limit != 300 || e.RateLimitConfig.User.Window != time.Hour {
+		t.Errorf("expected User to be '300/1h0m0s', got '%d/%s'",
+			e.RateLimitConfig.User.Limit, e.RateLimitConfig.User.Window.String())
+	}
+}
+
+func TestLoadWebAuthnConfig(t *testing.T) {
+	e := &EnvConfig{}
+	os.Setenv("WEBAUTHN_RP_ID", "localhost")
+	os.Setenv("WEBAUTHN_RP_DISPLAY_NAME", "starter-go-postgresql")
+	os.Setenv("WEBAUTHN_RP_ORIGINS", "http://localhost:3030, https://localhost:3031")
+	os.Setenv("WEBAUTHN_TIMEOUT", "5m")
+	defer os.Unsetenv("WEBAUTHN_RP_ID")
+	defer os.Unsetenv("WEBAUTHN_RP_DISPLAY_NAME")
+	defer os.Unsetenv("WEBAUTHN_RP_ORIGINS")
+	defer os.Unsetenv("WEBAUTHN_TIMEOUT")
+	e.LoadWebAuthnConfig()
+
+	if e.WebAuthnConfig == nil {
+		t.Fatalf("WebAuthnConfig is nil")
+	}
+
+	if e.WebAuthnConfig.RPID != "localhost" {
+		t.Errorf("expected RPID to be 'localhost', got '%s'", e.WebAuthnConfig.RPID)
+	}
+	if e.WebAuthnConfig.RPDisplayName != "starter-go-postgresql" {
+		t.Errorf("expected RPDisplayName to be 'starter-go-postgresql', got '%s'", e.WebAuthnConfig.RPDisplayName)
+	}
+	if origins := e.WebAuthnConfig.RPOrigins; len(origins) != 2 ||
+		origins[0] != "http://localhost:3030" || origins[1] != "https://localhost:3031" {
+		t.Errorf("expected RPOrigins to be '[http://localhost:3030 https://localhost:3031]', got '%v'", origins)
+	}
+	if e.WebAuthnConfig.Timeout != 5*time.Minute {
+		t.Errorf("expected Timeout to be '5m0s', got '%s'", e.WebAuthnConfig.Timeout.String())
+	}
+}
+
+func TestLoadOAuth2Config(t *testing.T) {
+	e := &EnvConfig{}
+	e.Port = "4040"
+	os.Setenv("PROTOCOL", "http://")
+	os.Setenv("DOMAIN", "localhost")
+	os.Setenv("GOOGLE_CLIENT_ID", "google-client-id")
+	os.Setenv("GOOGLE_CLIENT_SECRET", "google-client-secret")
+	os.Setenv("GITHUB_CLIENT_ID", "github-client-id")
+	os.Setenv("GITHUB_CLIENT_SECRET", "github-client-secret")
+	os.Setenv("OAUTH2_POST_LOGIN_REDIRECT_URL", "http://localhost:3030/")
+	os.Setenv("OAUTH2_STATE_EXPIRED_IN", "10m")
+	os.Setenv("OIDC_PROVIDERS", "Okta, ")
+	os.Setenv("OIDC_OKTA_ISSUER_URL", "https://example.okta.com")
+	os.Setenv("OIDC_OKTA_CLIENT_ID", "okta-client-id")
+	os.Setenv("OIDC_OKTA_CLIENT_SECRET", "okta-client-secret")
+	defer os.Unsetenv("PROTOCOL")
+	defer os.Unsetenv("DOMAIN")
+	defer os.Unsetenv("GOOGLE_CLIENT_ID")
+	defer os.Unsetenv("GOOGLE_CLIENT_SECRET")
+	defer os.Unsetenv("GITHUB_CLIENT_ID")
+	defer os.Unsetenv("GITHUB_CLIENT_SECRET")
+	defer os.Unsetenv("OAUTH2_POST_LOGIN_REDIRECT_URL")
+	defer os.Unsetenv("OAUTH2_STATE_EXPIRED_IN")
+	defer os.Unsetenv("OIDC_PROVIDERS")
+	defer os.Unsetenv("OIDC_OKTA_ISSUER_URL")
+	defer os.Unsetenv("OIDC_OKTA_CLIENT_ID")
+	defer os.Unsetenv("OIDC_OKTA_CLIENT_SECRET")
+	e.LoadOAuth2Config()
+
+	if e.OAuth2Config == nil {
+		t.Fatalf("OAuth2Config is nil")
+	}
+
+	if e.OAuth2Config.GoogleRedirectURL != "http://localhost:4040/auth/google_callback" {
+		t.Errorf("expected GoogleRedirectURL to be 'http://localhost:4040/auth/google_callback', got '%s'",
+			e.OAuth2Config.GoogleRedirectURL)
+	}
+	if scopes := e.OAuth2Config.Scopes; len(scopes) != 3 || scopes[0] != "openid" {
+		t.Errorf("expected Scopes to start with 'openid', got '%v'", scopes)
+	}
+	if e.OAuth2Config.GitHubRedirectURL != "http://localhost:4040/auth/github_callback" {
+		t.Errorf("expected GitHubRedirectURL to be 'http://localhost:4040/auth/github_callback', got '%s'",
+			e.OAuth2Config.GitHubRedirectURL)
+	}
+	if e.OAuth2Config.GitHubClientID != "github-client-id" || e.OAuth2Config.GitHubClientSecret != "github-client-secret" {
+		t.Errorf("expected the GitHub client to be loaded, got '%s'", e.OAuth2Config.GitHubClientID)
+	}
+	if e.OAuth2Config.StateExpiredIn != 10*time.Minute {
+		t.Errorf("expected StateExpiredIn to be '10m0s', got '%s'", e.OAuth2Config.StateExpiredIn.String())
+	}
+
+	providers := e.OAuth2Config.OIDCProviders
+	if len(providers) != 1 {
+		t.Fatalf("expected 1 OIDC provider, got %d", len(providers))
+	}
+	if providers[0].Name != "okta" || providers[0].IssuerURL != "https://example.okta.com" ||
+		providers[0].ClientID != "okta-client-id" || providers[0].ClientSecret != "okta-client-secret" {
+		t.Errorf("expected the okta provider to be loaded, got '%+v'", providers[0])
+	}
+	if providers[0].RedirectURL != "http://localhost:4040/auth/okta/callback" {
+		t.Errorf("expected RedirectURL to be 'http://localhost:4040/auth/okta/callback', got '%s'",
+			providers[0].RedirectURL)
+	}
+	if scopes := providers[0].Scopes; len(scopes) != 3 ||
+		scopes[0] != "openid" || scopes[1] != "email" || scopes[2] != "profile" {
+		t.Errorf("expected Scopes to be '[openid email profile]', got '%v'", scopes)
+	}
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	providersFile := t.TempDir() + "/oidc_providers.json"
+	err := os.WriteFile(providersFile, []byte(`[
+		{"name": "Auth0", "issuer_url": "https://example.auth0.com/", "client_id": "auth0-client-id",
+			"client_secret": "auth0-client-secret", "scopes": ["openid", "email"]},
+		{"name": "okta", "issuer_url": "https://other.okta.com", "client_id": "id", "client_secret": "secret"},
+		{"name": "keycloak", "issuer_url": "https://keycloak.example.com/realms/main", "client_id": "id"}
+	]`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write the providers file: %v", err)
+	}
+
+	e := &EnvConfig{}
+	e.Port = "4040"
+	os.Setenv("PROTOCOL", "http://")
+	os.Setenv("DOMAIN", "localhost")
+	os.Setenv("OIDC_PROVIDERS", "okta,Google,OKTA,my-idp")
+	os.Setenv("OIDC_OKTA_ISSUER_URL", "https://example.okta.com")
+	os.Setenv("OIDC_OKTA_CLIENT_ID", "okta-client-id")
+	os.Setenv("OIDC_OKTA_CLIENT_SECRET", "okta-client-secret")
+	os.Setenv("OIDC_PROVIDERS_FILE", providersFile)
+	defer os.Unsetenv("PROTOCOL")
+	defer os.Unsetenv("DOMAIN")
+	defer os.Unsetenv("OIDC_PROVIDERS")
+	defer os.Unsetenv("OIDC_OKTA_ISSUER_URL")
+	defer os.Unsetenv("OIDC_OKTA_CLIENT_ID")
+	defer os.Unsetenv("OIDC_OKTA_CLIENT_SECRET")
+	defer os.Unsetenv("OIDC_PROVIDERS_FILE")
+	e.LoadOAuth2Config()
+
+	// The built-in, duplicate, malformed and incomplete providers are ignored
+	providers := e.OAuth2Config.OIDCProviders
+	if len(providers) != 2 {
+		t.Fatalf("expected 2 OIDC providers, got %d", len(providers))
+	}
+	if providers[0].Name != "okta" || providers[0].IssuerURL != "https://example.okta.com" {
+		t.Errorf("expected the okta provider of OIDC_PROVIDERS to be loaded, got '%+v'", providers[0])
+	}
+	if providers[1].Name != "auth0" || providers[1].IssuerURL != "https://example.auth0.com/" ||
+		providers[1].ClientID != "auth0-client-id" || providers[1].ClientSecret != "auth0-client-secret" {
+		t.Errorf("expected the auth0 provider of the file to be loaded, got '%+v'", providers[1])
+	}
+	if providers[1].RedirectURL != "http://localhost:4040/auth/auth0/callback" {
+		t.Errorf("expected RedirectURL to be 'http://localhost:4040/auth/auth0/callback', got '%s'",
+			providers[1].RedirectURL)
+	}
+	if scopes := providers[1].Scopes; len(scopes) != 2 || scopes[0] != "openid" || scopes[1] != "email" {
+		t.Errorf("expected Scopes to be '[openid email]', got '%v'", scopes)
+	}
+}
+
+func TestLoadOAuthServerConfig(t *testing.T) {
+	e := &EnvConfig{}
+	os.Setenv("OAUTH_SERVER_LOGIN_URL", "http://localhost:3030/login")
+	os.Setenv("OAUTH_SERVER_CONSENT_URL", "http://localhost:3030/consent")
+	os.Setenv("OAUTH_SERVER_REQUEST_EXPIRED_IN", "10m")
+	os.Setenv("OAUTH_SERVER_CODE_EXPIRED_IN", "1m")
+	os.Setenv("OAUTH_SERVER_ID_TOKEN_EXPIRED_IN", "1h")
+	os.Setenv("OAUTH_SERVER_CLIENT_TOKEN_EXPIRED_IN", "5m")
+	defer os.Unsetenv("OAUTH_SERVER_LOGIN_URL")
+	defer os.Unsetenv("OAUTH_SERVER_CONSENT_URL")
+	defer os.Unsetenv("OAUTH_SERVER_REQUEST_EXPIRED_IN")
+	defer os.Unsetenv("OAUTH_SERVER_CODE_EXPIRED_IN")
+	defer os.Unsetenv("OAUTH_SERVER_ID_TOKEN_EXPIRED_IN")
+	defer os.Unsetenv("OAUTH_SERVER_CLIENT_TOKEN_EXPIRED_IN")
+	e.LoadOAuthServerConfig()
+
+	if e.OAuthServerConfig == nil {
+		t.Fatalf("OAuthServerConfig is nil")
+	}
+
+	if e.OAuthServerConfig.LoginURL != "http://localhost:3030/login" {
+		t.Errorf("expected LoginURL to be 'http://localhost:3030/login', got '%s'", e.OAuthServerConfig.LoginURL)
+	}
+	if e.OAuthServerConfig.ConsentURL != "http://localhost:3030/consent" {
+		t.Errorf("expected ConsentURL to be 'http://localhost:3030/consent', got '%s'", e.OAuthServerConfig.ConsentURL)
+	}
+	if e.OAuthServerConfig.AuthorizationRequestExpiredIn != 10*time.Minute {
+		t.Errorf("expected AuthorizationRequestExpiredIn to be '10m0s', got '%s'",
+			e.OAuthServerConfig.AuthorizationRequestExpiredIn.String())
+	}
+	if e.OAuthServerConfig.AuthorizationCodeExpiredIn != time.Minute {
+		t.Errorf("expected AuthorizationCodeExpiredIn to be '1m0s', got '%s'",
+			e.OAuthServerConfig.AuthorizationCodeExpiredIn.String())
+	}
+	if e.OAuthServerConfig.IDTokenExpiredIn != time.Hour {
+		t.Errorf("expected IDTokenExpiredIn to be '1h0m0s', got '%s'", e.OAuthServerConfig.IDTokenExpiredIn.String())
+	}
+	if e.OAuthServerConfig.ClientCredentialsExpiredIn != 5*time.Minute {
+		t.Errorf("expected ClientCredentialsExpiredIn to be '5m0s', got '%s'",
+			e.OAuthServerConfig.ClientCredentialsExpiredIn.String())
+	}
+}
@@ -0,0 +1,106 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PostgresAPIClientRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewAPIClientRepository(dbpool *pgxpool.Pool) repo.PostgresAPIClientRepository {
+	return &PostgresAPIClientRepository{dbpool}
+}
+
+var (
+	queryInsertAPIClient = `INSERT INTO api_clients(client_id, client_secret_hash, name, scopes)
+		VALUES ($1, $2, $3, $4) RETURNING created_at;`
+	queryGetAPIClient = `SELECT client_id, client_secret_hash, name, scopes, created_at
+		FROM api_clients WHERE client_id=$1;`
+	queryGetAPIClients = `SELECT client_id, client_secret_hash, name, scopes, created_at
+		FROM api_clients ORDER BY created_at;`
+	queryDeleteAPIClient = `DELETE FROM api_clients WHERE client_id=$1;`
+)
+
+func (ar PostgresAPIClientRepository) SaveAPIClient(client *entity.APIClient) *restErr.RestErr {
+	err := ar.dbpool.QueryRow(context.Background(), queryInsertAPIClient,
+		client.ClientID, client.SecretHash, client.Name, client.Scopes).Scan(&client.CreatedAt)
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (ar PostgresAPIClientRepository) GetAPIClient(clientID string) (*entity.APIClient, *restErr.RestErr) {
+	client, err := scanAPIClient(ar.dbpool.QueryRow(context.Background(), queryGetAPIClient, clientID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, restErr.NewNotFoundError(restErr.ErrMsgAPIClientNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return client, nil
+}
+
+func (ar PostgresAPIClientRepository) GetAPIClients() ([]*entity.APIClient, *restErr.RestErr) {
+	rows, err := ar.dbpool.Query(context.Background(), queryGetAPIClients)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer rows.Close()
+
+	clients := []*entity.APIClient{}
+	for rows.Next() {
+		client, err := scanAPIClient(rows)
+		if err != nil {
+			log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+			return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		}
+
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return clients, nil
+}
+
+func (ar PostgresAPIClientRepository) DeleteAPIClient(clientID string) *restErr.RestErr {
+	result, err := ar.dbpool.Exec(context.Background(), queryDeleteAPIClient, clientID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if result.RowsAffected() == 0 {
+		return restErr.NewNotFoundError(restErr.ErrMsgAPIClientNotFound)
+	}
+
+	return nil
+}
+
+func scanAPIClient(row pgx.Row) (*entity.APIClient, error) {
+	client := &entity.APIClient{}
+	err := row.Scan(&client.ClientID, &client.SecretHash, &client.Name, &client.Scopes, &client.CreatedAt)
+	return client, err
+}
@@ -0,0 +1,121 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PostgresAPIKeyRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(dbpool *pgxpool.Pool) repo.PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{dbpool}
+}
+
+var (
+	queryInsertAPIKey = `INSERT INTO api_keys(user_uuid, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING key_id::text, created_at;`
+	queryGetAPIKeys = `SELECT key_id::text, user_uuid::text, name, prefix, key_hash, scopes, expires_at,
+		last_used_at, COALESCE(last_used_ip, ''), created_at
+		FROM api_keys WHERE user_uuid=$1 ORDER BY created_at;`
+	queryGetAPIKeyByHash = `SELECT key_id::text, user_uuid::text, name, prefix, key_hash, scopes, expires_at,
+		last_used_at, COALESCE(last_used_ip, ''), created_at
+		FROM api_keys WHERE key_hash=$1;`
+	queryDeleteAPIKey         = `DELETE FROM api_keys WHERE user_uuid=$1 AND key_id=$2;`
+	queryUpdateAPIKeyLastUsed = `UPDATE api_keys SET last_used_at = now() AT TIME ZONE 'UTC', last_used_ip=$2
+		WHERE key_id=$1;`
+)
+
+func (kr PostgresAPIKeyRepository) SaveAPIKey(apiKey *entity.APIKey) *restErr.RestErr {
+	err := kr.dbpool.QueryRow(context.Background(), queryInsertAPIKey,
+		apiKey.UserUUID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, apiKey.ExpiresAt).
+		Scan(&apiKey.ID, &apiKey.CreatedAt)
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (kr PostgresAPIKeyRepository) GetAPIKeys(userUUID string) ([]*entity.APIKey, *restErr.RestErr) {
+	rows, err := kr.dbpool.Query(context.Background(), queryGetAPIKeys, userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer rows.Close()
+
+	apiKeys := []*entity.APIKey{}
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+			return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return apiKeys, nil
+}
+
+func (kr PostgresAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*entity.APIKey, *restErr.RestErr) {
+	apiKey, err := scanAPIKey(kr.dbpool.QueryRow(context.Background(), queryGetAPIKeyByHash, keyHash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, restErr.NewNotFoundError(restErr.ErrMsgAPIKeyNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return apiKey, nil
+}
+
+func (kr PostgresAPIKeyRepository) DeleteAPIKey(userUUID string, keyID string) *restErr.RestErr {
+	result, err := kr.dbpool.Exec(context.Background(), queryDeleteAPIKey, userUUID, keyID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if result.RowsAffected() == 0 {
+		return restErr.NewNotFoundError(restErr.ErrMsgAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+func (kr PostgresAPIKeyRepository) UpdateAPIKeyLastUsed(keyID string, ipAddress string) *restErr.RestErr {
+	if _, err := kr.dbpool.Exec(context.Background(), queryUpdateAPIKeyLastUsed, keyID, ipAddress); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*entity.APIKey, error) {
+	apiKey := &entity.APIKey{}
+	err := row.Scan(&apiKey.ID, &apiKey.UserUUID, &apiKey.Name, &apiKey.Prefix, &apiKey.KeyHash, &apiKey.Scopes,
+		&apiKey.ExpiresAt, &apiKey.LastUsedAt, &apiKey.LastUsedIP, &apiKey.CreatedAt)
+	return apiKey, err
+}
@@ -0,0 +1,154 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PostgresIdentityRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewIdentityRepository(dbpool *pgxpool.Pool) repo.PostgresIdentityRepository {
+	return &PostgresIdentityRepository{dbpool}
+}
+
+var (
+	queryInsertIdentity = `INSERT INTO user_identities(provider, subject, user_uuid, email) VALUES ($1, $2, $3, $4)
+		RETURNING linked_at;`
+	queryGetIdentity = `SELECT provider, subject, user_uuid, email, linked_at FROM user_identities
+		WHERE provider=$1 AND subject=$2;`
+	queryGetIdentities = `SELECT provider, subject, user_uuid, email, linked_at FROM user_identities
+		WHERE user_uuid=$1 ORDER BY linked_at;`
+	queryLockUserLoginMethods = `SELECT password IS NOT NULL FROM users WHERE user_uuid=$1 FOR UPDATE;`
+	queryCountIdentities      = `SELECT COUNT(*), COUNT(*) FILTER (WHERE provider=$2 AND subject=$3)
+		FROM user_identities WHERE user_uuid=$1;`
+	queryDeleteIdentity = `DELETE FROM user_identities WHERE user_uuid=$1 AND provider=$2 AND subject=$3;`
+)
+
+func (ir PostgresIdentityRepository) SaveIdentity(identity *entity.UserIdentity) *restErr.RestErr {
+	err := ir.dbpool.QueryRow(context.Background(), queryInsertIdentity,
+		identity.Provider, identity.Subject, identity.UserUUID, identity.Email).Scan(&identity.LinkedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return restErr.NewBadRequestError(restErr.ErrMsgIdentityIsAlreadyLinked)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (ir PostgresIdentityRepository) GetIdentity(provider string, subject string) (
+	*entity.UserIdentity, *restErr.RestErr) {
+	identity := &entity.UserIdentity{}
+	err := ir.dbpool.QueryRow(context.Background(), queryGetIdentity, provider, subject).
+		Scan(&identity.Provider, &identity.Subject, &identity.UserUUID, &identity.Email, &identity.LinkedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, restErr.NewNotFoundError(restErr.ErrMsgIdentityNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return identity, nil
+}
+
+func (ir PostgresIdentityRepository) GetIdentities(userUUID string) ([]*entity.UserIdentity, *restErr.RestErr) {
+	rows, err := ir.dbpool.Query(context.Background(), queryGetIdentities, userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer rows.Close()
+
+	identities := []*entity.UserIdentity{}
+	for rows.Next() {
+		identity := &entity.UserIdentity{}
+		err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserUUID, &identity.Email, &identity.LinkedAt)
+		if err != nil {
+			log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+			return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		}
+
+		identities = append(identities, identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return identities, nil
+}
+
+/*
+DeleteIdentity locks the user row first, so that concurrent unlinks of two identities of the same user
+are serialized and cannot both see the other identity as the remaining login method.
+*/
+func (ir PostgresIdentityRepository) DeleteIdentity(identity *entity.UserIdentity, hasPasskeys bool) *restErr.RestErr {
+	ctx := context.Background()
+	tx, err := ir.dbpool.Begin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer tx.Rollback(ctx) // No-op once committed
+
+	var hasPassword bool
+	if err := tx.QueryRow(ctx, queryLockUserLoginMethods, identity.UserUUID).Scan(&hasPassword); err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewNotFoundError(restErr.ErrMsgIdentityNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	var identities, matches int
+	err = tx.QueryRow(ctx, queryCountIdentities, identity.UserUUID, identity.Provider, identity.Subject).
+		Scan(&identities, &matches)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if matches == 0 {
+		return restErr.NewNotFoundError(restErr.ErrMsgIdentityNotFound)
+	}
+
+	if !hasPassword && !hasPasskeys && identities == 1 {
+		return restErr.NewConflictError(restErr.ErrMsgLastLoginMethod)
+	}
+
+	if _, err := tx.Exec(ctx, queryDeleteIdentity, identity.UserUUID, identity.Provider, identity.Subject); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
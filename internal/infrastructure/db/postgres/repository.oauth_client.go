@@ -0,0 +1,133 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PostgresOAuthClientRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewOAuthClientRepository(dbpool *pgxpool.Pool) repo.PostgresOAuthClientRepository {
+	return &PostgresOAuthClientRepository{dbpool}
+}
+
+var (
+	queryInsertOAuthClient = `INSERT INTO oauth_clients(client_id, client_secret_hash, name, redirect_uris, scopes)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5) RETURNING created_at;`
+	queryGetOAuthClient = `SELECT client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, scopes, created_at
+		FROM oauth_clients WHERE client_id=$1;`
+	queryGetOAuthClients = `SELECT client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, scopes, created_at
+		FROM oauth_clients ORDER BY created_at;`
+	queryDeleteOAuthClient = `DELETE FROM oauth_clients WHERE client_id=$1;`
+	queryGetOAuthConsent   = `SELECT scopes FROM oauth_consents WHERE user_uuid=$1 AND client_id=$2;`
+	queryUpsertConsent     = `INSERT INTO oauth_consents(user_uuid, client_id, scopes) VALUES ($1, $2, $3)
+		ON CONFLICT (user_uuid, client_id) DO UPDATE
+		SET scopes = ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes)),
+			granted_at = now() AT TIME ZONE 'UTC';`
+)
+
+func (cr PostgresOAuthClientRepository) SaveClient(client *entity.OAuthClient) *restErr.RestErr {
+	err := cr.dbpool.QueryRow(context.Background(), queryInsertOAuthClient,
+		client.ClientID, client.SecretHash, client.Name, client.RedirectURIs, client.Scopes).Scan(&client.CreatedAt)
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (cr PostgresOAuthClientRepository) GetClient(clientID string) (*entity.OAuthClient, *restErr.RestErr) {
+	client, err := scanOAuthClient(cr.dbpool.QueryRow(context.Background(), queryGetOAuthClient, clientID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, restErr.NewNotFoundError(restErr.ErrMsgOAuthClientNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return client, nil
+}
+
+func (cr PostgresOAuthClientRepository) GetClients() ([]*entity.OAuthClient, *restErr.RestErr) {
+	rows, err := cr.dbpool.Query(context.Background(), queryGetOAuthClients)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer rows.Close()
+
+	clients := []*entity.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+			return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		}
+
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return clients, nil
+}
+
+// DeleteClient also deletes the consents given to the client, which cascade.
+func (cr PostgresOAuthClientRepository) DeleteClient(clientID string) *restErr.RestErr {
+	result, err := cr.dbpool.Exec(context.Background(), queryDeleteOAuthClient, clientID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if result.RowsAffected() == 0 {
+		return restErr.NewNotFoundError(restErr.ErrMsgOAuthClientNotFound)
+	}
+
+	return nil
+}
+
+func (cr PostgresOAuthClientRepository) GetConsent(userUUID string, clientID string) ([]string, *restErr.RestErr) {
+	var scopes []string
+	err := cr.dbpool.QueryRow(context.Background(), queryGetOAuthConsent, userUUID, clientID).Scan(&scopes)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return scopes, nil
+}
+
+func (cr PostgresOAuthClientRepository) SaveConsent(userUUID string, clientID string, scopes []string) *restErr.RestErr {
+	if _, err := cr.dbpool.Exec(context.Background(), queryUpsertConsent, userUUID, clientID, scopes); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func scanOAuthClient(row pgx.Row) (*entity.OAuthClient, error) {
+	client := &entity.OAuthClient{}
+	err := row.Scan(&client.ClientID, &client.SecretHash, &client.Name, &client.RedirectURIs, &client.Scopes,
+		&client.CreatedAt)
+	return client, err
+}
@@ -0,0 +1,120 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const errMsgRoleNotFound = "role not found"
+
+type PostgresRoleRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewRoleRepository(dbpool *pgxpool.Pool) repo.PostgresRoleRepository {
+	return &PostgresRoleRepository{dbpool}
+}
+
+var (
+	queryUpsertRole = `INSERT INTO roles(name, description) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description RETURNING id;`
+	queryInsertPermission     = "INSERT INTO permissions(name) VALUES ($1) ON CONFLICT (name) DO NOTHING;"
+	queryInsertRolePermission = `INSERT INTO role_permissions(role_id, permission_id)
+		SELECT $1, id FROM permissions WHERE name=$2 ON CONFLICT DO NOTHING;`
+	queryGetRoleID      = "SELECT id FROM roles WHERE name=$1;"
+	queryInsertUserRole = "INSERT INTO user_roles(user_uuid, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;"
+	queryGetUserRoles   = `SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_uuid=$1 ORDER BY r.name;`
+	queryGetPermissions = `SELECT DISTINCT p.name FROM roles r
+		JOIN role_permissions rp ON rp.role_id = r.id JOIN permissions p ON p.id = rp.permission_id
+		WHERE r.name = ANY($1) ORDER BY p.name;`
+)
+
+func (rr PostgresRoleRepository) SaveRole(role *entity.Role) *restErr.RestErr {
+	ctx := context.Background()
+	tx, err := rr.dbpool.Begin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer tx.Rollback(ctx) // No-op once committed
+
+	var roleID int64
+	if err := tx.QueryRow(ctx, queryUpsertRole, role.Name, role.Description).Scan(&roleID); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	batch := &pgx.Batch{}
+	for _, permission := range role.Permissions {
+		batch.Queue(queryInsertPermission, permission)
+		batch.Queue(queryInsertRolePermission, roleID, permission)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// AssignRole is a no-op if the user already has the role.
+func (rr PostgresRoleRepository) AssignRole(userUUID string, roleName string) *restErr.RestErr {
+	var roleID int64
+	err := rr.dbpool.QueryRow(context.Background(), queryGetRoleID, roleName).Scan(&roleID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewNotFoundError(errMsgRoleNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if _, err := rr.dbpool.Exec(context.Background(), queryInsertUserRole, userUUID, roleID); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (rr PostgresRoleRepository) GetUserRoles(userUUID string) ([]string, *restErr.RestErr) {
+	return rr.queryNames(queryGetUserRoles, userUUID)
+}
+
+// GetPermissions returns the union of the permissions granted by the roles.
+func (rr PostgresRoleRepository) GetPermissions(roleNames []string) ([]string, *restErr.RestErr) {
+	return rr.queryNames(queryGetPermissions, roleNames)
+}
+
+func (rr PostgresRoleRepository) queryNames(query string, arg any) ([]string, *restErr.RestErr) {
+	rows, err := rr.dbpool.Query(context.Background(), query, arg)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return names, nil
+}
@@ -21,6 +21,7 @@ const (
 	errMsgUnableReadSchema      = "unable to read %s/sql/schema.user.sql"
 	errMsgUnableToExecuteSchema = "unable to execute schema.user.sql"
 	errMsgUnableToLoadJSONFile  = "unable to load [%s]"
+	errMsgUnableToSaveRole      = "unable to save the [%s] role"
 )
 
 type PostgresSeedRepository struct {
@@ -47,22 +48,35 @@ func NewSeedRepository(dbpool *pgxpool.Pool, env string) repo.PostgresSeedReposi
 	return &PostgresSeedRepository{dbpool, env, envBasePath}
 }
 
-func (sr PostgresSeedRepository) Seed(ur repo.PostgresUserRepository) {
+func (sr PostgresSeedRepository) Seed(ur repo.PostgresUserRepository, rr repo.PostgresRoleRepository) {
+	saveDefaultRoles(rr)
+
 	currentEnv := sr.env
 	switch currentEnv {
 	case "dev":
-		saveMultipleUsers(currentEnv, sr.envBasePath, ur)
+		saveMultipleUsers(currentEnv, sr.envBasePath, ur, rr)
 	case "test":
-		saveMultipleUsers(currentEnv, sr.envBasePath, ur)
+		saveMultipleUsers(currentEnv, sr.envBasePath, ur, rr)
 	default:
 		log.Info().Msgf("[%s] env will NOT be seeded with data", currentEnv)
 	}
 }
 
+// saveDefaultRoles runs in every env so that new permissions of the default roles are added on upgrade.
+func saveDefaultRoles(rr repo.PostgresRoleRepository) {
+	for _, role := range entity.DefaultRoles {
+		if err := rr.SaveRole(role); err != nil {
+			log.Error().Err(err).Msgf(errMsgUnableToSaveRole, role.Name)
+		}
+	}
+}
+
+// saveMultipleUsers seeds the users of the env; the first user is the admin.
 func saveMultipleUsers(
 	currentEnv string,
 	envBasePath string,
 	ur repo.PostgresUserRepository,
+	rr repo.PostgresRoleRepository,
 ) *restErr.RestErr {
 	userJsonFilePath := "/seed.user." + currentEnv + ".json"
 	uu, err := loadUsersFromJsonFile(envBasePath + "/json" + userJsonFilePath)
@@ -83,6 +97,10 @@ func saveMultipleUsers(
 		ur.SaveUser(uu[i])
 	}
 
+	if uu[0].UUID != nil {
+		rr.AssignRole(uu[0].UUID.String(), entity.RoleAdmin)
+	}
+
 	log.Info().Msgf("successfully seeded data in [%s] env", currentEnv)
 	return nil
 }
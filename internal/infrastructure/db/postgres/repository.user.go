@@ -5,6 +5,9 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
@@ -27,9 +30,40 @@ func NewUserRepository(dbpool *pgxpool.Pool) repo.PostgresUserRepository {
 }
 
 var (
-	queryInsertUser  = "INSERT INTO users(first_name, last_name, email, password) VALUES ($1, $2, $3, $4) RETURNING user_uuid;"
-	queryGetUser     = "SELECT user_uuid, first_name, last_name, email, password FROM users WHERE email=$1;"
-	queryGetUserByID = "SELECT user_uuid, first_name, last_name, email FROM users WHERE user_uuid=$1;"
+	queryInsertUser = "INSERT INTO users(first_name, last_name, email, password) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING user_uuid;"
+	queryGetUser    = `SELECT user_uuid, first_name, last_name, email, COALESCE(password, ''), email_verified_at,
+		COALESCE(totp_secret, ''), totp_enabled_at, disabled_at FROM users WHERE email=$1 AND deleted_at IS NULL;`
+	queryGetUserByID = `SELECT user_uuid, first_name, last_name, email, email_verified_at,
+		COALESCE(totp_secret, ''), totp_enabled_at, disabled_at, created_at, updated_at FROM users
+		WHERE user_uuid=$1 AND deleted_at IS NULL;`
+	queryVerifyEmail = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, now() AT TIME ZONE 'UTC')
+		WHERE user_uuid=$1 AND deleted_at IS NULL RETURNING email_verified_at;`
+	queryUpdatePassword = "UPDATE users SET password = $2 WHERE user_uuid=$1 RETURNING updated_at;"
+	querySetTOTPSecret  = `UPDATE users SET totp_secret = $2
+		WHERE user_uuid=$1 AND totp_enabled_at IS NULL RETURNING updated_at;`
+	queryEnableTOTP = `UPDATE users SET totp_enabled_at = now() AT TIME ZONE 'UTC'
+		WHERE user_uuid=$1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL RETURNING totp_enabled_at;`
+	queryDeleteRecoveryCodes = "DELETE FROM mfa_recovery_codes WHERE user_uuid=$1;"
+	queryInsertRecoveryCode  = "INSERT INTO mfa_recovery_codes(user_uuid, code_hash) VALUES ($1, $2);"
+	queryUseRecoveryCode     = `UPDATE mfa_recovery_codes SET used_at = now() AT TIME ZONE 'UTC'
+		WHERE user_uuid=$1 AND code_hash=$2 AND used_at IS NULL;`
+	queryListUsers = `SELECT id, user_uuid, first_name, last_name, email, email_verified_at, totp_enabled_at,
+		disabled_at, created_at, updated_at FROM users`
+	querySetDisabled = `UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now() AT TIME ZONE 'UTC') END
+		WHERE user_uuid=$1 AND deleted_at IS NULL RETURNING disabled_at, updated_at;`
+	queryDeleteUser     = "DELETE FROM users WHERE user_uuid=$1;"
+	querySoftDeleteUser = `UPDATE users SET deleted_at = now() AT TIME ZONE 'UTC'
+		WHERE user_uuid=$1 AND deleted_at IS NULL RETURNING deleted_at;`
+	queryPurgeDeletedUsers = "DELETE FROM users WHERE deleted_at < $1;"
+
+	/*
+		JSON clients such as browsers only keep milliseconds of the microseconds that Postgres stores,
+		so the `updated_at` the update is based on is compared at that precision.
+	*/
+	queryUpdateUser = `UPDATE users SET first_name = $2, last_name = $3
+		WHERE user_uuid=$1 AND deleted_at IS NULL AND date_trunc('milliseconds', updated_at) = date_trunc('milliseconds', $4::TIMESTAMP)
+		RETURNING updated_at;`
 )
 
 // Create a method of the `User` type
@@ -58,7 +92,8 @@ func (ur PostgresUserRepository) SaveUser(user *entity.User) *restErr.RestErr {
 
 func (ur PostgresUserRepository) GetUserByEmail(user *entity.User) *restErr.RestErr {
 	err := ur.dbpool.QueryRow(context.Background(), queryGetUser, user.Email).
-		Scan(&user.UUID, &user.FirstName, &user.LastName, &user.Email, &user.Password)
+		Scan(&user.UUID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.EmailVerifiedAt,
+			&user.TOTPSecret, &user.TOTPEnabledAt, &user.DisabledAt)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -74,10 +109,251 @@ func (ur PostgresUserRepository) GetUserByEmail(user *entity.User) *restErr.Rest
 
 func (ur PostgresUserRepository) GetUserByUUID(user *entity.User) *restErr.RestErr {
 	result := ur.dbpool.QueryRow(context.Background(), queryGetUserByID, user.UUID)
-	if err := result.Scan(&user.UUID, &user.FirstName, &user.LastName, &user.Email); err != nil {
+	if err := result.Scan(&user.UUID, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerifiedAt,
+		&user.TOTPSecret, &user.TOTPEnabledAt, &user.DisabledAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows { // e.g. a passkey that outlived its account
+			return restErr.NewBadRequestError(errMsgUnregisteredAcc)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// VerifyEmail keeps the original `email_verified_at` if the email address has already been verified.
+func (ur PostgresUserRepository) VerifyEmail(user *entity.User) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), queryVerifyEmail, user.UUID).Scan(&user.EmailVerifiedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewBadRequestError(errMsgUnregisteredAcc)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// UpdatePassword stores `user.Password`, which must already be hashed.
+func (ur PostgresUserRepository) UpdatePassword(user *entity.User) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), queryUpdatePassword, user.UUID, user.Password).Scan(&user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewBadRequestError(errMsgUnregisteredAcc)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+/*
+UpdateUser saves the name of the user unless the user has been updated after `lastUpdatedAt`,
+which rejects the update with a conflict so that a stale record never overwrites a newer one.
+*/
+func (ur PostgresUserRepository) UpdateUser(user *entity.User, lastUpdatedAt time.Time) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), queryUpdateUser,
+		user.UUID, user.FirstName, user.LastName, lastUpdatedAt.UTC()).Scan(&user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewConflictError(restErr.ErrMsgStaleRecord)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// SetTOTPSecret stores the encrypted `user.TOTPSecret` of an enrollment, replacing any unconfirmed one.
+func (ur PostgresUserRepository) SetTOTPSecret(user *entity.User) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), querySetTOTPSecret, user.UUID, user.TOTPSecret).Scan(&user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewBadRequestError(restErr.ErrMsgTOTPAlreadyEnabled)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// EnableTOTP enables the enrolled secret and replaces the recovery codes in a single transaction.
+func (ur PostgresUserRepository) EnableTOTP(user *entity.User, recoveryCodeHashes []string) *restErr.RestErr {
+	ctx := context.Background()
+	tx, err := ur.dbpool.Begin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer tx.Rollback(ctx) // No-op once committed
+
+	if err := tx.QueryRow(ctx, queryEnableTOTP, user.UUID).Scan(&user.TOTPEnabledAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewBadRequestError(restErr.ErrMsgTOTPNotEnrolled)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(queryDeleteRecoveryCodes, user.UUID)
+	for _, codeHash := range recoveryCodeHashes {
+		batch.Queue(queryInsertRecoveryCode, user.UUID, codeHash)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the code as used; each code can only be used once.
+func (ur PostgresUserRepository) UseRecoveryCode(user *entity.User, codeHash string) *restErr.RestErr {
+	result, err := ur.dbpool.Exec(context.Background(), queryUseRecoveryCode, user.UUID, codeHash)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if result.RowsAffected() == 0 {
+		return restErr.NewUnauthorizedError(restErr.ErrMsgInvalidMFACode)
+	}
+
+	return nil
+}
+
+// ListUsers returns up to `filter.Limit` users matching every filter that is set, from the newest.
+func (ur PostgresUserRepository) ListUsers(filter *entity.UserFilter) ([]*entity.User, *restErr.RestErr) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []any{}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Email != "" {
+		addCondition("email ILIKE $%d", "%"+escapeLike(filter.Email)+"%")
+	}
+	if filter.Name != "" {
+		addCondition("(first_name || ' ' || last_name) ILIKE $%d", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at < $%d", *filter.CreatedBefore)
+	}
+	if filter.BeforeID > 0 {
+		addCondition("id < $%d", filter.BeforeID)
+	}
+
+	query := queryListUsers + " WHERE " + strings.Join(conditions, " AND ")
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d;", len(args))
+
+	rows, err := ur.dbpool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*entity.User, error) {
+		user := &entity.User{}
+		err := row.Scan(&user.ID, &user.UUID, &user.FirstName, &user.LastName, &user.Email, &user.EmailVerifiedAt,
+			&user.TOTPEnabledAt, &user.DisabledAt, &user.CreatedAt, &user.UpdatedAt)
+		return user, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return users, nil
+}
+
+// SetDisabled keeps the original `disabled_at` if the user has already been disabled.
+func (ur PostgresUserRepository) SetDisabled(user *entity.User, disabled bool) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), querySetDisabled, user.UUID, disabled).
+		Scan(&user.DisabledAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewNotFoundError(restErr.ErrMsgUserNotFound)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// DeleteUser also deletes the recovery codes, passkeys and roles of the user.
+func (ur PostgresUserRepository) DeleteUser(user *entity.User) *restErr.RestErr {
+	result, err := ur.dbpool.Exec(context.Background(), queryDeleteUser, user.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if result.RowsAffected() == 0 {
+		return restErr.NewNotFoundError(restErr.ErrMsgUserNotFound)
+	}
+
+	return nil
+}
+
+// SoftDeleteUser hides the user from every lookup until `PurgeDeletedUsers` deletes it.
+func (ur PostgresUserRepository) SoftDeleteUser(user *entity.User) *restErr.RestErr {
+	err := ur.dbpool.QueryRow(context.Background(), querySoftDeleteUser, user.UUID).Scan(&user.DeletedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewNotFoundError(restErr.ErrMsgUserNotFound)
+		}
+
 		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
 		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
 	}
 
 	return nil
 }
+
+// PurgeDeletedUsers deletes the users soft-deleted before `deletedBefore` and returns how many were deleted.
+func (ur PostgresUserRepository) PurgeDeletedUsers(deletedBefore time.Time) (int64, *restErr.RestErr) {
+	result, err := ur.dbpool.Exec(context.Background(), queryPurgeDeletedUsers, deletedBefore.UTC())
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return 0, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// escapeLike matches the wildcards of a `LIKE` pattern literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
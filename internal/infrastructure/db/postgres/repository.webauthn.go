@@ -0,0 +1,99 @@
+// coverage:ignore file
+// Testing with integration test
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const errMsgPasskeyIsAlreadyRegistered = "the passkey has already been registered"
+
+type PostgresWebAuthnRepository struct {
+	dbpool *pgxpool.Pool
+}
+
+func NewWebAuthnRepository(dbpool *pgxpool.Pool) repo.PostgresWebAuthnRepository {
+	return &PostgresWebAuthnRepository{dbpool}
+}
+
+var (
+	queryInsertCredential = `INSERT INTO webauthn_credentials(credential_id, user_uuid, public_key, attestation_type,
+		aaguid, sign_count, transports, backup_eligible, backup_state) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at;`
+	queryGetCredentials = `SELECT credential_id, user_uuid, public_key, attestation_type, aaguid, sign_count,
+		transports, backup_eligible, backup_state, created_at, last_used_at
+		FROM webauthn_credentials WHERE user_uuid=$1 ORDER BY created_at;`
+	queryUpdateCredential = `UPDATE webauthn_credentials
+		SET sign_count = $2, backup_state = $3, last_used_at = now() AT TIME ZONE 'UTC'
+		WHERE credential_id=$1 RETURNING last_used_at;`
+)
+
+func (wr PostgresWebAuthnRepository) SaveCredential(credential *entity.WebAuthnCredential) *restErr.RestErr {
+	err := wr.dbpool.QueryRow(context.Background(), queryInsertCredential,
+		credential.ID, credential.UserUUID, credential.PublicKey, credential.AttestationType, credential.AAGUID,
+		int64(credential.SignCount), credential.Transports, credential.BackupEligible, credential.BackupState,
+	).Scan(&credential.CreatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return restErr.NewBadRequestError(errMsgPasskeyIsAlreadyRegistered)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (wr PostgresWebAuthnRepository) GetCredentials(userUUID string) ([]*entity.WebAuthnCredential, *restErr.RestErr) {
+	rows, err := wr.dbpool.Query(context.Background(), queryGetCredentials, userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	credentials, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*entity.WebAuthnCredential, error) {
+		credential := &entity.WebAuthnCredential{}
+		var signCount int64
+		err := row.Scan(&credential.ID, &credential.UserUUID, &credential.PublicKey, &credential.AttestationType,
+			&credential.AAGUID, &signCount, &credential.Transports, &credential.BackupEligible,
+			&credential.BackupState, &credential.CreatedAt, &credential.LastUsedAt)
+		credential.SignCount = uint32(signCount)
+		return credential, err
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return credentials, nil
+}
+
+// UpdateCredential records the use of the passkey with the sign count and backup state of the latest assertion.
+func (wr PostgresWebAuthnRepository) UpdateCredential(credential *entity.WebAuthnCredential) *restErr.RestErr {
+	err := wr.dbpool.QueryRow(context.Background(), queryUpdateCredential,
+		credential.ID, int64(credential.SignCount), credential.BackupState).Scan(&credential.LastUsedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return restErr.NewUnauthorizedError(restErr.ErrMsgInvalidPasskey)
+		}
+
+		log.Error().Err(err).Msg(restErr.ErrMsgPostgresError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
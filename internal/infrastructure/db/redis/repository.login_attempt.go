@@ -0,0 +1,87 @@
+// coverage:ignore file
+// Testing with integration test
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const loginAttemptsKeyPrefix = "login_attempts:" // hash of `count` and `last_failed_at` in unix milliseconds
+
+type RedisLoginAttemptRepository struct {
+	RedisDB *RedisDB
+}
+
+func NewLoginAttemptRepository(redisDB *RedisDB) r.RedisLoginAttemptRepository {
+	return &RedisLoginAttemptRepository{redisDB}
+}
+
+func (r RedisLoginAttemptRepository) GetLoginAttempts(key string) (*entity.LoginAttempts, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.HGetAll(ctx, loginAttemptsKeyPrefix+key).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return parseLoginAttempts(result), nil
+}
+
+func (r RedisLoginAttemptRepository) AddFailedLoginAttempt(key string, ttl time.Duration) (
+	*entity.LoginAttempts, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var count *redis.IntCmd
+	_, err := r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HIncrBy(ctx, loginAttemptsKeyPrefix+key, "count", 1)
+		pipe.HSet(ctx, loginAttemptsKeyPrefix+key, "last_failed_at", now.UnixMilli())
+		pipe.Expire(ctx, loginAttemptsKeyPrefix+key, ttl)
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return &entity.LoginAttempts{Count: count.Val(), LastFailedAt: now}, nil
+}
+
+func (r RedisLoginAttemptRepository) DelLoginAttempts(keys ...string) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	prefixedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		prefixedKeys[i] = loginAttemptsKeyPrefix + key
+	}
+
+	if err := r.RedisDB.RedisClient.Del(ctx, prefixedKeys...).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func parseLoginAttempts(result map[string]string) *entity.LoginAttempts {
+	loginAttempts := &entity.LoginAttempts{}
+	loginAttempts.Count, _ = strconv.ParseInt(result["count"], 10, 64)
+	if lastFailedAt, err := strconv.ParseInt(result["last_failed_at"], 10, 64); err == nil {
+		loginAttempts.LastFailedAt = time.UnixMilli(lastFailedAt)
+	}
+
+	return loginAttempts
+}
@@ -0,0 +1,173 @@
+// coverage:ignore file
+// Testing with integration test
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	consentRequestKeyPrefix    = "oauth_consent_request:" // requestHash -> JSON encoded grant waiting for the consent
+	authorizationCodeKeyPrefix = "oauth_code:"            // codeHash -> JSON encoded grant
+	oauthRefreshTokenKeyPrefix = "oauth_refresh_token:"   // tokenHash -> JSON encoded grant
+	userOAuthRefreshKeyPrefix  = "oauth_refresh_tokens:"  // set of tokenHashes of the refresh tokens of the user
+)
+
+type RedisOAuthRepository struct {
+	RedisDB *RedisDB
+}
+
+func NewOAuthRepository(redisDB *RedisDB) r.RedisOAuthRepository {
+	return &RedisOAuthRepository{redisDB}
+}
+
+func (r RedisOAuthRepository) SetConsentRequest(requestHash string, grant *entity.AuthorizationGrant,
+	expiresIn int64) *restErr.RestErr {
+	return r.setGrant(consentRequestKeyPrefix+requestHash, grant, expiresIn)
+}
+
+func (r RedisOAuthRepository) GetConsentRequest(requestHash string) (*entity.AuthorizationGrant, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.Get(ctx, consentRequestKeyPrefix+requestHash).Result()
+	return parseGrant(result, err, restErr.ErrMsgAuthorizationExpired)
+}
+
+func (r RedisOAuthRepository) ConsumeConsentRequest(requestHash string) (
+	*entity.AuthorizationGrant, *restErr.RestErr) {
+	return r.consumeGrant(consentRequestKeyPrefix+requestHash, restErr.ErrMsgAuthorizationExpired)
+}
+
+func (r RedisOAuthRepository) SetAuthorizationCode(codeHash string, grant *entity.AuthorizationGrant,
+	expiresIn int64) *restErr.RestErr {
+	return r.setGrant(authorizationCodeKeyPrefix+codeHash, grant, expiresIn)
+}
+
+func (r RedisOAuthRepository) ConsumeAuthorizationCode(codeHash string) (
+	*entity.AuthorizationGrant, *restErr.RestErr) {
+	return r.consumeGrant(authorizationCodeKeyPrefix+codeHash, restErr.ErrMsgInvalidGrant)
+}
+
+// SetOAuthRefreshToken also indexes the token by its user so that `DelUserSessions` can revoke it.
+func (r RedisOAuthRepository) SetOAuthRefreshToken(tokenHash string, grant *entity.AuthorizationGrant,
+	expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	value, err := json.Marshal(grant)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	userTokensKey := userOAuthRefreshKeyPrefix + grant.UserUUID
+	expiresAt := time.Unix(expiresIn, 0)
+	_, err = r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, oauthRefreshTokenKeyPrefix+tokenHash, value, time.Until(expiresAt))
+		pipe.SAdd(ctx, userTokensKey, tokenHash)
+		pipe.ExpireNX(ctx, userTokensKey, time.Until(expiresAt))
+		pipe.ExpireGT(ctx, userTokensKey, time.Until(expiresAt))
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisOAuthRepository) ConsumeOAuthRefreshToken(tokenHash string) (
+	*entity.AuthorizationGrant, *restErr.RestErr) {
+	grant, err := r.consumeGrant(oauthRefreshTokenKeyPrefix+tokenHash, restErr.ErrMsgInvalidGrant)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is used up either way, so a failure here only leaves its hash in the set until it expires
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	if err := r.RedisDB.RedisClient.SRem(ctx, userOAuthRefreshKeyPrefix+grant.UserUUID, tokenHash).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+	}
+
+	return grant, nil
+}
+
+// delUserOAuthRefreshTokens revokes every refresh token that the user gave to OAuth clients.
+func delUserOAuthRefreshTokens(ctx context.Context, redisDB *RedisDB, userUUID string) *restErr.RestErr {
+	userTokensKey := userOAuthRefreshKeyPrefix + userUUID
+	tokenHashes, err := redisDB.RedisClient.SMembers(ctx, userTokensKey).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	keys := []string{userTokensKey}
+	for _, tokenHash := range tokenHashes {
+		keys = append(keys, oauthRefreshTokenKeyPrefix+tokenHash)
+	}
+
+	if err := redisDB.RedisClient.Del(ctx, keys...).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisOAuthRepository) setGrant(key string, grant *entity.AuthorizationGrant, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	value, err := json.Marshal(grant)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := r.RedisDB.RedisClient.Set(ctx, key, value, time.Until(time.Unix(expiresIn, 0))).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// consumeGrant atomically reads and deletes the grant so that its token can only be used once.
+func (r RedisOAuthRepository) consumeGrant(key string, errMsgNotFound string) (
+	*entity.AuthorizationGrant, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.GetDel(ctx, key).Result()
+	return parseGrant(result, err, errMsgNotFound)
+}
+
+func parseGrant(result string, err error, errMsgNotFound string) (*entity.AuthorizationGrant, *restErr.RestErr) {
+	if err == redis.Nil {
+		return nil, restErr.NewBadRequestError(errMsgNotFound)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	grant := &entity.AuthorizationGrant{}
+	if err := json.Unmarshal([]byte(result), grant); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return grant, nil
+}
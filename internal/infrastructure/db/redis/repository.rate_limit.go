@@ -0,0 +1,75 @@
+// coverage:ignore file
+// Testing with integration test
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const rateLimitKeyPrefix = "rate_limit:" // sorted set of request IDs scored by their time in milliseconds
+
+/*
+`slidingWindow` drops the requests that left the window and records the new one when there is room.
+The clock of the Redis server is used so that every instance shares the same window.
+Returns whether the request is allowed, the remaining requests and the milliseconds until a slot frees up.
+*/
+var slidingWindow = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+local resetAfter = window
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	resetAfter = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, limit - count, resetAfter}
+`)
+
+type RedisRateLimitRepository struct {
+	RedisDB *RedisDB
+}
+
+func NewRateLimitRepository(redisDB *RedisDB) r.RateLimitRepository {
+	return &RedisRateLimitRepository{redisDB}
+}
+
+func (r RedisRateLimitRepository) Allow(key string, rule *entity.RateLimitRule) (
+	*entity.RateLimit, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := slidingWindow.Run(ctx, r.RedisDB.RedisClient, []string{rateLimitKeyPrefix + key},
+		rule.Window.Milliseconds(), rule.Limit, uuid.NewString()).Int64Slice()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return &entity.RateLimit{
+		Allowed:    result[0] == 1,
+		Limit:      rule.Limit,
+		Remaining:  int(result[1]),
+		ResetAfter: time.Duration(result[2]) * time.Millisecond,
+	}, nil
+}
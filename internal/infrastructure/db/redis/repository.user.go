@@ -4,14 +4,52 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	tokenFamilyKeyPrefix       = "token_family:"       // set of token UUIDs issued from the same login
+	familyOfTokenKeyPrefix     = "family_of:"          // tokenUUID -> familyID
+	sessionKeyPrefix           = "session:"            // familyID -> JSON encoded session
+	userSessionsKeyPrefix      = "user_sessions:"      // set of familyIDs of the user
+	emailVerificationKeyPrefix = "email_verification:" // userUUID -> tokenUUID of the latest verification email
+	passwordResetKeyPrefix     = "password_reset:"     // tokenHash -> userUUID
+	passwordResetOfKeyPrefix   = "password_reset_of:"  // userUUID -> tokenHash of the latest reset token
+	mfaChallengeKeyPrefix      = "mfa_challenge:"      // tokenHash -> hash of `user_uuid` and `failed_attempts`
+	totpUsedKeyPrefix          = "totp_used:"          // userUUID:step of a TOTP code that has been used
+	webAuthnSessionKeyPrefix   = "webauthn_session:"   // registration:userUUID or login:tokenHash -> session data
+	oauth2StateKeyPrefix       = "oauth2_state:"       // stateHash -> JSON encoded OAuth2 state
+
+	// A TOTP code is accepted for at most 3 steps of 30 seconds
+	totpUsedExpiresIn = 2 * time.Minute
+)
+
+// Deletes the key only if it still holds the expected value
+var compareAndDelete = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Increments the field only if the key has not expired, so that no key is left without a TTL
+var incrementIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+end
+return 0
+`)
+
 type RedisUserRepository struct {
 	RedisDB *RedisDB
 }
@@ -62,3 +100,488 @@ func (r RedisUserRepository) DelUserUUID(tokenUUID string, accessTokenUUID strin
 
 	return result, nil
 }
+
+func (r RedisUserRepository) SetTokenFamily(familyID string, tokenUUID string, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 5*time.Second)
+	defer cancel()
+
+	familyKey := tokenFamilyKeyPrefix + familyID
+	expiresAt := time.Unix(expiresIn, 0)
+
+	// The family outlives its newest token so that a rotated refresh token can still be traced back to it
+	_, err := r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, familyOfTokenKeyPrefix+tokenUUID, familyID, time.Until(expiresAt))
+		pipe.SAdd(ctx, familyKey, tokenUUID)
+		pipe.ExpireNX(ctx, familyKey, time.Until(expiresAt))
+		pipe.ExpireGT(ctx, familyKey, time.Until(expiresAt))
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) GetTokenFamily(tokenUUID string) (string, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.Get(ctx, familyOfTokenKeyPrefix+tokenUUID).Result()
+
+	if err == redis.Nil {
+		err := restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+		return "", err
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return "", restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return result, nil
+}
+
+// ConsumeRefreshToken atomically reads and deletes the refresh token so it can only be exchanged once.
+func (r RedisUserRepository) ConsumeRefreshToken(tokenUUID string) (string, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.GetDel(ctx, tokenUUID).Result()
+
+	if err == redis.Nil {
+		err := restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+		return "", err
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return "", restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return result, nil
+}
+
+func (r RedisUserRepository) DelTokenFamily(familyID string) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	familyKey := tokenFamilyKeyPrefix + familyID
+	tokenUUIDs, err := r.RedisDB.RedisClient.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	// The session of the family is removed together with its tokens
+	session, errGetSession := r.GetSession(familyID)
+	if errGetSession != nil && errGetSession.Status != http.StatusNotFound {
+		return errGetSession
+	}
+
+	// The reverse lookups of the tokens go too, or they would outlive the family they point to
+	keys := append(tokenUUIDs, familyKey, sessionKeyPrefix+familyID)
+	for _, tokenUUID := range tokenUUIDs {
+		keys = append(keys, familyOfTokenKeyPrefix+tokenUUID)
+	}
+
+	_, err = r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		if session != nil {
+			pipe.SRem(ctx, userSessionsKeyPrefix+session.UserUUID, familyID)
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) SetSession(session *entity.Session, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 5*time.Second)
+	defer cancel()
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	userSessionsKey := userSessionsKeyPrefix + session.UserUUID
+	expiresAt := time.Unix(expiresIn, 0)
+
+	_, err = r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKeyPrefix+session.ID, value, time.Until(expiresAt))
+		pipe.SAdd(ctx, userSessionsKey, session.ID)
+		pipe.ExpireNX(ctx, userSessionsKey, time.Until(expiresAt))
+		pipe.ExpireGT(ctx, userSessionsKey, time.Until(expiresAt))
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) GetSession(familyID string) (*entity.Session, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.Get(ctx, sessionKeyPrefix+familyID).Result()
+
+	if err == redis.Nil {
+		return nil, restErr.NewNotFoundError(restErr.ErrMsgSessionNotFound)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	session := &entity.Session{}
+	if err := json.Unmarshal([]byte(result), session); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return session, nil
+}
+
+// GetSessions returns the active sessions of the user and prunes the index of sessions that have expired.
+func (r RedisUserRepository) GetSessions(userUUID string) ([]*entity.Session, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	userSessionsKey := userSessionsKeyPrefix + userUUID
+	familyIDs, err := r.RedisDB.RedisClient.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	sessions := []*entity.Session{}
+	if len(familyIDs) == 0 {
+		return sessions, nil
+	}
+
+	keys := make([]string, len(familyIDs))
+	for i, familyID := range familyIDs {
+		keys[i] = sessionKeyPrefix + familyID
+	}
+
+	values, err := r.RedisDB.RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	expired := []interface{}{}
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			expired = append(expired, familyIDs[i])
+			continue
+		}
+
+		session := &entity.Session{}
+		if err := json.Unmarshal([]byte(str), session); err != nil {
+			log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if len(expired) > 0 {
+		if err := r.RedisDB.RedisClient.SRem(ctx, userSessionsKey, expired...).Err(); err != nil {
+			log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		}
+	}
+
+	return sessions, nil
+}
+
+/*
+DelUserSessions revokes every token family of the user other than `exceptFamilyIDs`,
+and every refresh token that the user gave to OAuth clients, which belong to no session.
+Refresh tokens issued before token families are not listed with the user, so they are only revoked by expiring.
+*/
+func (r RedisUserRepository) DelUserSessions(userUUID string, exceptFamilyIDs ...string) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	if err := delUserOAuthRefreshTokens(ctx, r.RedisDB, userUUID); err != nil {
+		return err
+	}
+
+	userSessionsKey := userSessionsKeyPrefix + userUUID
+	familyIDs, err := r.RedisDB.RedisClient.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	for _, familyID := range familyIDs {
+		if slices.Contains(exceptFamilyIDs, familyID) {
+			continue
+		}
+
+		if err := r.DelTokenFamily(familyID); err != nil {
+			return err
+		}
+	}
+
+	if len(exceptFamilyIDs) > 0 {
+		return nil
+	}
+
+	if err := r.RedisDB.RedisClient.Del(ctx, userSessionsKey).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) SetEmailVerification(userUUID string, tokenUUID string, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	// Overwriting the previous token UUID invalidates any verification email sent before
+	err := r.RedisDB.RedisClient.Set(
+		ctx, emailVerificationKeyPrefix+userUUID, tokenUUID, time.Until(time.Unix(expiresIn, 0))).Err()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) ConsumeEmailVerification(userUUID string, tokenUUID string) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	deleted, err := compareAndDelete.Run(
+		ctx, r.RedisDB.RedisClient, []string{emailVerificationKeyPrefix + userUUID}, tokenUUID).Int()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if deleted == 0 {
+		return restErr.NewBadRequestError(restErr.ErrMsgInvalidToken)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) SetPasswordReset(userUUID string, tokenHash string, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	previousTokenHash, err := r.RedisDB.RedisClient.Get(ctx, passwordResetOfKeyPrefix+userUUID).Result()
+	if err != nil && err != redis.Nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	ttl := time.Until(time.Unix(expiresIn, 0))
+	_, err = r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousTokenHash != "" {
+			pipe.Del(ctx, passwordResetKeyPrefix+previousTokenHash)
+		}
+		pipe.Set(ctx, passwordResetKeyPrefix+tokenHash, userUUID, ttl)
+		pipe.Set(ctx, passwordResetOfKeyPrefix+userUUID, tokenHash, ttl)
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// ConsumePasswordReset atomically reads and deletes the reset token so it can only be used once.
+func (r RedisUserRepository) ConsumePasswordReset(tokenHash string) (string, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	userUUID, err := r.RedisDB.RedisClient.GetDel(ctx, passwordResetKeyPrefix+tokenHash).Result()
+
+	if err == redis.Nil {
+		return "", restErr.NewBadRequestError(restErr.ErrMsgInvalidToken)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return "", restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := compareAndDelete.Run(
+		ctx, r.RedisDB.RedisClient, []string{passwordResetOfKeyPrefix + userUUID}, tokenHash).Err(); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+	}
+
+	return userUUID, nil
+}
+
+func (r RedisUserRepository) SetMFAChallenge(tokenHash string, userUUID string, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	_, err := r.RedisDB.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, mfaChallengeKeyPrefix+tokenHash, "user_uuid", userUUID, "failed_attempts", 0)
+		pipe.ExpireAt(ctx, mfaChallengeKeyPrefix+tokenHash, time.Unix(expiresIn, 0))
+		return nil
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) GetMFAChallenge(tokenHash string) (string, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	userUUID, err := r.RedisDB.RedisClient.HGet(ctx, mfaChallengeKeyPrefix+tokenHash, "user_uuid").Result()
+
+	if err == redis.Nil {
+		return "", restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return "", restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return userUUID, nil
+}
+
+func (r RedisUserRepository) AddFailedMFAAttempt(tokenHash string) (int64, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	failedAttempts, err := incrementIfExists.Run(
+		ctx, r.RedisDB.RedisClient, []string{mfaChallengeKeyPrefix + tokenHash}, "failed_attempts").Int64()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return 0, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return failedAttempts, nil
+}
+
+// ConsumeMFAChallenge deletes the challenge and fails if it was already gone, so that it completes a single login.
+func (r RedisUserRepository) ConsumeMFAChallenge(tokenHash string) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	deleted, err := r.RedisDB.RedisClient.Del(ctx, mfaChallengeKeyPrefix+tokenHash).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if deleted == 0 {
+		return restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of a valid TOTP code and rejects a code of the same step being replayed.
+func (r RedisUserRepository) UseTOTPStep(userUUID string, step int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	key := totpUsedKeyPrefix + userUUID + ":" + strconv.FormatInt(step, 10)
+	set, err := r.RedisDB.RedisClient.SetNX(ctx, key, 1, totpUsedExpiresIn).Result()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if !set {
+		return restErr.NewUnauthorizedError(restErr.ErrMsgInvalidMFACode)
+	}
+
+	return nil
+}
+
+func (r RedisUserRepository) SetWebAuthnSession(key string, sessionData []byte, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	err := r.RedisDB.RedisClient.Set(
+		ctx, webAuthnSessionKeyPrefix+key, sessionData, time.Until(time.Unix(expiresIn, 0))).Err()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// ConsumeWebAuthnSession atomically reads and deletes the session data so that a challenge is only answered once.
+func (r RedisUserRepository) ConsumeWebAuthnSession(key string) ([]byte, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	sessionData, err := r.RedisDB.RedisClient.GetDel(ctx, webAuthnSessionKeyPrefix+key).Bytes()
+
+	if err == redis.Nil {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return sessionData, nil
+}
+
+func (r RedisUserRepository) SetOAuth2State(stateHash string, state *entity.OAuth2State, expiresIn int64) *restErr.RestErr {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	err = r.RedisDB.RedisClient.Set(ctx, oauth2StateKeyPrefix+stateHash, value, time.Until(time.Unix(expiresIn, 0))).Err()
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
+
+// ConsumeOAuth2State atomically reads and deletes the state so that a callback cannot be replayed.
+func (r RedisUserRepository) ConsumeOAuth2State(stateHash string) (*entity.OAuth2State, *restErr.RestErr) {
+	ctx, cancel := context.WithTimeout(r.RedisDB.RedisCtx, 3*time.Second)
+	defer cancel()
+
+	result, err := r.RedisDB.RedisClient.GetDel(ctx, oauth2StateKeyPrefix+stateHash).Result()
+	if err == redis.Nil {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgOAuth2StateExpired)
+	} else if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgRedisError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	state := &entity.OAuth2State{}
+	if err := json.Unmarshal([]byte(result), state); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const errMsgUnsupportedKeyType = "unsupported key type: %T"
+
+/*
+`newJWK` converts a public key into its JSON Web Key representation (RFC 7517).
+The `kid` is the RFC 7638 thumbprint of the key, so it is stable across restarts and
+identical on every instance that is configured with the same key pair.
+*/
+func newJWK(publicKey crypto.PublicKey) (*entity.JWK, error) {
+	method, err := signingMethodForKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := &entity.JWK{Use: "sig", Alg: method.Alg()}
+
+	// Required members in lexicographic order as specified by RFC 7638
+	var thumbprintInput interface{}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		thumbprintInput = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+
+	case *ecdsa.PublicKey:
+		// Coordinates are left-padded to the curve size (RFC 7518 section 6.2.1.2)
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+		thumbprintInput = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		thumbprintInput = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	input, err := json.Marshal(thumbprintInput)
+	if err != nil { // coverage:ignore
+		return nil, err
+	}
+
+	jwk.Kid = thumbprint(input)
+	return jwk, nil
+}
+
+/*
+`signingMethodForKey` is the only place where a key is paired with an algorithm.
+A key can verify exactly one algorithm, which rules out alg confusion such as
+an RSA public key being used as an HMAC secret.
+*/
+func signingMethodForKey(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return signingMethods["RS256"], nil
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P256() {
+			return signingMethods["ES256"], nil
+		}
+	case ed25519.PublicKey:
+		return signingMethods["EdDSA"], nil
+	}
+
+	return nil, fmt.Errorf(errMsgUnsupportedKeyType, publicKey)
+}
+
+func thumbprint(input []byte) string {
+	sum := sha256.Sum256(input)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -7,6 +7,9 @@ import (
 
 const testTTL = 360 * time.Minute
 
+var testScope = []string{"openid", "profile"}
+var testRoles = []string{"admin", "support"}
+
 var tokenTests = []struct {
 	name            string
 	validPrivateKey bool
@@ -56,3 +59,32 @@ var tokenTests = []struct {
 		expectedErrMsg: "invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key",
 		privateKey:     "", publicKey: ""},
 }
+
+var signingAlgorithmTests = []struct {
+	alg         string
+	expectedKty string
+	expectedCrv string
+}{
+	{alg: "ES256", expectedKty: "EC", expectedCrv: "P-256"},
+	{alg: "EdDSA", expectedKty: "OKP", expectedCrv: "Ed25519"},
+}
+
+var registeredClaimsTests = []struct {
+	name          string
+	ttl           time.Duration
+	issuer        string
+	audience      string
+	leeway        time.Duration
+	expectedValid bool
+}{
+	{name: "Expected issuer and audience", ttl: testTTL, issuer: "http://localhost:8080/auth",
+		audience: "http://localhost:8080/auth/api", expectedValid: true},
+	{name: "Issuer mismatch", ttl: testTTL, issuer: "http://misconfigured:8080/auth",
+		audience: "http://localhost:8080/auth/api", expectedValid: false},
+	{name: "Audience mismatch", ttl: testTTL, issuer: "http://localhost:8080/auth",
+		audience: "http://localhost:8080/auth/api/v1/users/refresh", expectedValid: false},
+	{name: "Expired within leeway", ttl: -2 * time.Second, issuer: "http://localhost:8080/auth",
+		audience: "http://localhost:8080/auth/api", leeway: 30 * time.Second, expectedValid: true},
+	{name: "Expired beyond leeway", ttl: -time.Minute, issuer: "http://localhost:8080/auth",
+		audience: "http://localhost:8080/auth/api", leeway: 30 * time.Second, expectedValid: false},
+}
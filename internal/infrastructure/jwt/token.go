@@ -1,8 +1,13 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
@@ -14,12 +19,28 @@ import (
 )
 
 const (
-	errMsgDecodeStringError = "DecodeString error"
-	errMsgParseRSAKeyError  = "ParseRSA error"
-	errMsgSignKeyError      = "sign key error"
-	errMsgUnexpectedMethod  = "unexpected signing method: %s"
+	defaultAlgorithm = "RS256"
+
+	errMsgDecodeStringError    = "DecodeString error"
+	errMsgParseKeyError        = "parse key error"
+	errMsgSignKeyError         = "sign key error"
+	errMsgUnexpectedMethod     = "unexpected signing method: %s"
+	errMsgUnsupportedMethod    = "unsupported signing algorithm: %s"
+	errMsgKeyAlgorithmMismatch = "private key does not match the %s signing algorithm"
+	errMsgUnknownKeyID         = "unknown kid: %v"
+	errMsgEmptyKeyRing         = "key ring has no public key"
 )
 
+/*
+`signingMethods` is the allowlist of signing algorithms.
+Anything else, including `none` and the HMAC family, is rejected by `ValidateToken`.
+*/
+var signingMethods = map[string]jwt.SigningMethod{
+	"RS256": jwt.SigningMethodRS256,
+	"ES256": jwt.SigningMethodES256,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
 /*
 The `TokenService` should be a stateless service that performs operations related to tokens.
 It does not need to manage the lifecycle of the `Token` entity itself but rather uses it.
@@ -30,74 +51,181 @@ func NewTokenService() service.TokenService {
 	return &TokenService{}
 }
 
-func (ts *TokenService) CreateToken(userUUID string, ttl time.Duration, privateKey string) (
+func (ts *TokenService) CreateToken(userUUID string, scope []string, roles []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	return createToken(userUUID, "", scope, roles, ttl, keyRing)
+}
+
+/*
+CreateClientToken issues an access token to an OAuth client, either on behalf of the user in `subject`
+or, for the client's own access, with the client ID as the `subject`.
+The `client_id` claim tells these tokens apart from the tokens of the sessions of the users.
+*/
+func (ts *TokenService) CreateClientToken(subject string, clientID string, scope []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	return createToken(subject, clientID, scope, nil, ttl, keyRing)
+}
+
+// CreateIDToken issues an OpenID Connect ID token, whose audience is the client that the user logged in to.
+func (ts *TokenService) CreateIDToken(idToken *entity.IDToken, ttl time.Duration, keyRing *entity.JWTKeyRing) (
 	*entity.Token, *restErr.RestErr) {
 	now := time.Now().UTC()
 	t := &entity.Token{
+		UserUUID:  idToken.Subject,
 		ExpiresIn: new(int64),
-		Token:     new(string),
+		Issuer:    keyRing.Issuer,
+		Audience:  []string{idToken.ClientID},
 	}
+	*t.ExpiresIn = now.Add(ttl).Unix()
 
-	id, err := uuid.NewV7()
-	if err != nil { // coverage:ignore
-		log.Error().Err(err).Msg(restErr.ErrUUIDError)
-		return nil, restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+	claims := jwt.MapClaims{}
+	for name, value := range idToken.Claims {
+		claims[name] = value
 	}
 
-	t.TokenUUID = id.String()
-	t.UserUUID = userUUID
-	*t.ExpiresIn = now.Add(ttl).Unix()
+	// The claims of the user cannot override the registered claims
+	claims["iss"] = keyRing.Issuer
+	claims["sub"] = idToken.Subject
+	claims["aud"] = idToken.ClientID
+	claims["exp"] = *t.ExpiresIn
+	claims["iat"] = now.Unix()
+	if idToken.Nonce != "" {
+		claims["nonce"] = idToken.Nonce
+	}
 
-	decodedPrivateKey, err := base64.StdEncoding.DecodeString(privateKey)
+	signedToken, err := signClaims(claims, keyRing)
 	if err != nil {
-		log.Error().Err(err).Msg(errMsgDecodeStringError)
-		return nil, restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+		return nil, err
 	}
 
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(decodedPrivateKey)
+	t.Token = &signedToken
+	return t, nil
+}
 
-	if err != nil {
-		log.Error().Err(err).Msg(errMsgParseRSAKeyError)
+func createToken(subject string, clientID string, scope []string, roles []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	now := time.Now().UTC()
+	t := &entity.Token{
+		ExpiresIn: new(int64),
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Msg(restErr.ErrUUIDError)
 		return nil, restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
 	}
 
+	t.TokenUUID = id.String()
+	t.UserUUID = subject
+	*t.ExpiresIn = now.Add(ttl).Unix()
+	t.Issuer = keyRing.Issuer
+	t.Scope = scope
+	t.Roles = roles
+	t.ClientID = clientID
+
 	atClaims := jwt.MapClaims{
-		"sub":        userUUID,
+		"sub":        subject,
 		"token_uuid": t.TokenUUID,
 		"exp":        *t.ExpiresIn,
 		"iat":        now.Unix(), // Issued at
 		"nbf":        now.Unix(), // Not before
 	}
 
-	*t.Token, err = jwt.NewWithClaims(jwt.SigningMethodRS256, atClaims).SignedString(key)
+	if keyRing.Issuer != "" {
+		atClaims["iss"] = keyRing.Issuer
+	}
+
+	if keyRing.Audience != "" {
+		atClaims["aud"] = keyRing.Audience
+		t.Audience = []string{keyRing.Audience}
+	}
+
+	if len(scope) > 0 {
+		atClaims["scope"] = strings.Join(scope, " ") // space-delimited as in RFC 8693
+	}
+
+	if len(roles) > 0 {
+		atClaims["roles"] = roles
+	}
+
+	if clientID != "" {
+		atClaims["client_id"] = clientID // As in RFC 9068
+	}
+
+	signedToken, errSign := signClaims(atClaims, keyRing)
+	if errSign != nil {
+		return nil, errSign
+	}
+
+	t.Token = &signedToken
+	return t, nil
+}
+
+// signClaims signs the claims with the active key of the key ring and sets the `kid` header.
+func signClaims(claims jwt.MapClaims, keyRing *entity.JWTKeyRing) (string, *restErr.RestErr) {
+	method, key, err := parsePrivateKey(keyRing)
 	if err != nil {
+		log.Error().Err(err).Msg(errMsgParseKeyError)
+		return "", restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	jwk, err := newJWK(key.Public())
+	if err != nil { // coverage:ignore
 		log.Error().Err(err).Msg(errMsgSignKeyError)
-		return nil, restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+		return "", restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
 	}
 
-	return t, nil
+	jwtToken := jwt.NewWithClaims(method, claims)
+	jwtToken.Header["kid"] = jwk.Kid // Lets verifiers pick the matching key from the JWKS
+	signedToken, err := jwtToken.SignedString(key)
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgSignKeyError)
+		return "", restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return signedToken, nil
 }
 
-func (ts *TokenService) ValidateToken(tokenStr string, publicKey string) (
+func (ts *TokenService) ValidateToken(tokenStr string, keyRing *entity.JWTKeyRing) (
 	*entity.Token, *restErr.RestErr) {
-	decodedPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
+	verificationKeys, err := parseVerificationKeys(keyRing)
 	if err != nil {
-		log.Error().Err(err).Msg(errMsgDecodeStringError)
 		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
 	}
 
-	key, err := jwt.ParseRSAPublicKeyFromPEM(decodedPublicKey)
-	if err != nil {
-		log.Error().Err(err).Msg(errMsgParseRSAKeyError)
-		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	validMethods := make([]string, 0, len(signingMethods))
+	for alg := range signingMethods {
+		validMethods = append(validMethods, alg)
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(keyRing.Leeway),
+	}
+
+	if keyRing.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(keyRing.Issuer))
+	}
+
+	if keyRing.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(keyRing.Audience))
 	}
 
 	parsedToken, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+		verificationKey, err := selectVerificationKey(t, verificationKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm is dictated by the key, never by the token header
+		if t.Method.Alg() != verificationKey.method.Alg() {
 			return nil, fmt.Errorf(errMsgUnexpectedMethod, t.Header["alg"])
 		}
-		return key, nil
-	})
+
+		return verificationKey.key, nil
+	}, parserOptions...)
 
 	if err != nil {
 		log.Error().Err(err).Msg(restErr.ErrMsgInvalidToken)
@@ -111,8 +239,179 @@ func (ts *TokenService) ValidateToken(tokenStr string, publicKey string) (
 		return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
 	}
 
-	return &entity.Token{
+	t := &entity.Token{
 		TokenUUID: fmt.Sprint(claims["token_uuid"]),
 		UserUUID:  fmt.Sprint(claims["sub"]),
-	}, nil
+		ExpiresIn: new(int64),
+	}
+
+	// The registered claims have already been validated by the parser
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		*t.ExpiresIn = exp.Unix()
+	}
+	t.Issuer, _ = claims.GetIssuer()
+	t.Audience, _ = claims.GetAudience()
+	if scope, ok := claims["scope"].(string); ok {
+		t.Scope = strings.Fields(scope)
+	}
+	if clientID, ok := claims["client_id"].(string); ok {
+		t.ClientID = clientID
+	}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				t.Roles = append(t.Roles, role)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// GetJWKS builds the JWK Set that verifiers use to validate tokens signed with the key ring.
+func (ts *TokenService) GetJWKS(keyRing *entity.JWTKeyRing) (*entity.JWKSet, *restErr.RestErr) {
+	verificationKeys, err := parseVerificationKeys(keyRing)
+	if err != nil {
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	jwks := &entity.JWKSet{Keys: []entity.JWK{}}
+	for _, verificationKey := range verificationKeys {
+		jwks.Keys = append(jwks.Keys, *verificationKey.jwk)
+	}
+
+	return jwks, nil
+}
+
+// parsePrivateKey parses the active signing key of the key ring for its configured algorithm.
+func parsePrivateKey(keyRing *entity.JWTKeyRing) (jwt.SigningMethod, crypto.Signer, error) {
+	alg := keyRing.Algorithm
+	if alg == "" {
+		alg = defaultAlgorithm
+	}
+
+	method, ok := signingMethods[alg]
+	if !ok {
+		return nil, nil, fmt.Errorf(errMsgUnsupportedMethod, alg)
+	}
+
+	decodedPrivateKey, err := base64.StdEncoding.DecodeString(keyRing.PrivateKey)
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgDecodeStringError)
+		return nil, nil, err
+	}
+
+	var key crypto.Signer
+	switch method {
+	case jwt.SigningMethodRS256:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(decodedPrivateKey)
+	case jwt.SigningMethodES256:
+		var ecdsaKey *ecdsa.PrivateKey
+		ecdsaKey, err = jwt.ParseECPrivateKeyFromPEM(decodedPrivateKey)
+		key = ecdsaKey
+	case jwt.SigningMethodEdDSA:
+		var privateKey crypto.PrivateKey
+		privateKey, err = jwt.ParseEdPrivateKeyFromPEM(decodedPrivateKey)
+		if edKey, ok := privateKey.(ed25519.PrivateKey); ok {
+			key = edKey
+		}
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key == nil { // coverage:ignore
+		return nil, nil, fmt.Errorf(errMsgKeyAlgorithmMismatch, alg)
+	}
+
+	// e.g. an ECDSA key on a curve other than P-256 configured for ES256
+	if keyMethod, err := signingMethodForKey(key.Public()); err != nil || keyMethod != method {
+		return nil, nil, fmt.Errorf(errMsgKeyAlgorithmMismatch, alg)
+	}
+
+	return method, key, nil
+}
+
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+	jwk    *entity.JWK
+}
+
+// parseVerificationKeys returns the active public key followed by the retired public keys of the key ring.
+func parseVerificationKeys(keyRing *entity.JWTKeyRing) ([]verificationKey, error) {
+	publicKeys := append([]string{keyRing.PublicKey}, keyRing.RetiredPublicKeys...)
+	verificationKeys := make([]verificationKey, 0, len(publicKeys))
+
+	for _, publicKey := range publicKeys {
+		if publicKey == "" {
+			continue
+		}
+
+		key, err := parsePublicKey(publicKey)
+		if err != nil {
+			log.Error().Err(err).Msg(errMsgParseKeyError)
+			return nil, err
+		}
+
+		method, err := signingMethodForKey(key)
+		if err != nil {
+			log.Error().Err(err).Msg(errMsgParseKeyError)
+			return nil, err
+		}
+
+		jwk, err := newJWK(key)
+		if err != nil { // coverage:ignore
+			log.Error().Err(err).Msg(errMsgParseKeyError)
+			return nil, err
+		}
+
+		verificationKeys = append(verificationKeys,
+			verificationKey{kid: jwk.Kid, method: method, key: key, jwk: jwk})
+	}
+
+	if len(verificationKeys) == 0 {
+		err := errors.New(errMsgEmptyKeyRing)
+		log.Error().Err(err).Msg(errMsgParseKeyError)
+		return nil, err
+	}
+
+	return verificationKeys, nil
+}
+
+// parsePublicKey accepts a base64 encoded PEM public key of any supported type.
+func parsePublicKey(publicKey string) (crypto.PublicKey, error) {
+	decodedPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgDecodeStringError)
+		return nil, err
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(decodedPublicKey); err == nil {
+		return key, nil
+	}
+
+	if key, err := jwt.ParseECPublicKeyFromPEM(decodedPublicKey); err == nil {
+		return key, nil
+	}
+
+	return jwt.ParseEdPublicKeyFromPEM(decodedPublicKey)
+}
+
+func selectVerificationKey(t *jwt.Token, verificationKeys []verificationKey) (*verificationKey, error) {
+	// Tokens without a `kid` predate key rotation and can only have been signed by the active key
+	kid, ok := t.Header["kid"].(string)
+	if !ok {
+		return &verificationKeys[0], nil
+	}
+
+	for i := range verificationKeys {
+		if verificationKeys[i].kid == kid {
+			return &verificationKeys[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf(errMsgUnknownKeyID, t.Header["kid"])
 }
@@ -2,10 +2,21 @@ package jwt
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/golang-jwt/jwt/v5"
 	uuid "github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -15,24 +26,29 @@ func TestNewTokenService(t *testing.T) {
 	for _, test := range tokenTests {
 		t.Run(test.name, func(t *testing.T) {
 			tokenService := NewTokenService()
+			keyRing := &entity.JWTKeyRing{PrivateKey: test.privateKey, PublicKey: test.publicKey}
 			userUUID, err := uuid.NewV7()
 			if err != nil {
 				t.Errorf("Failed to create userUUID: %v", err)
 			}
 
 			if test.validPrivateKey && test.validPublicKey {
-				testToken, err := tokenService.CreateToken(userUUID.String(), testTTL, test.privateKey)
+				testToken, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing)
 				if err != nil {
 					t.Errorf("Failed to CreateToken: %v", err)
 				}
 
-				validatedToken, err := tokenService.ValidateToken(*testToken.Token, test.publicKey)
+				validatedToken, err := tokenService.ValidateToken(*testToken.Token, keyRing)
 				if err != nil {
 					t.Errorf("Failed to ValidateToken: %v", err)
 				}
 
 				if validatedToken == nil {
-					t.Errorf("Token validation failed")
+					t.Fatalf("Token validation failed")
+				}
+
+				if strings.Join(validatedToken.Roles, " ") != strings.Join(testRoles, " ") {
+					t.Errorf("Expected roles '%v', got '%v'", testRoles, validatedToken.Roles)
 				}
 			}
 
@@ -41,7 +57,7 @@ func TestNewTokenService(t *testing.T) {
 			zerolog.SetGlobalLevel(zerolog.ErrorLevel)
 
 			if !test.validPrivateKey {
-				_, err := tokenService.CreateToken(userUUID.String(), testTTL, test.privateKey)
+				_, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing)
 				if err == nil {
 					logOutput := buf.String()
 					if !strings.Contains(logOutput, test.expectedErrMsg) {
@@ -51,12 +67,12 @@ func TestNewTokenService(t *testing.T) {
 			}
 
 			if test.validPrivateKey && !test.validPublicKey {
-				testToken, err := tokenService.CreateToken(userUUID.String(), testTTL, test.privateKey)
+				testToken, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing)
 				if err != nil {
 					t.Errorf("Failed to CreateToken: %v", err)
 				}
 
-				_, err = tokenService.ValidateToken(*testToken.Token, test.publicKey)
+				_, err = tokenService.ValidateToken(*testToken.Token, keyRing)
 				if err == nil {
 					logOutput := buf.String()
 					if !strings.Contains(logOutput, test.expectedErrMsg) {
@@ -69,3 +85,432 @@ func TestNewTokenService(t *testing.T) {
 		})
 	}
 }
+
+func TestGetJWKS(t *testing.T) {
+	tokenService := NewTokenService()
+	userUUID, err := uuid.NewV7()
+	if err != nil {
+		t.Errorf("Failed to create userUUID: %v", err)
+	}
+
+	for _, test := range tokenTests {
+		if !test.validPrivateKey || !test.validPublicKey {
+			continue
+		}
+
+		t.Run(test.name, func(t *testing.T) {
+			keyRing := &entity.JWTKeyRing{PrivateKey: test.privateKey, PublicKey: test.publicKey}
+			jwks, err := tokenService.GetJWKS(keyRing)
+			if err != nil {
+				t.Fatalf("Failed to GetJWKS: %v", err)
+			}
+
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("Expected 1 key in JWKS, got %d", len(jwks.Keys))
+			}
+
+			jwk := jwks.Keys[0]
+			if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.Use != "sig" {
+				t.Errorf("Unexpected JWK members: %+v", jwk)
+			}
+
+			// The `kid` must be stable across tokens and match the published key
+			for i := 0; i < 2; i++ {
+				testToken, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing)
+				if err != nil {
+					t.Fatalf("Failed to CreateToken: %v", err)
+				}
+
+				parsedToken, _, parseErr := jwt.NewParser().ParseUnverified(*testToken.Token, jwt.MapClaims{})
+				if parseErr != nil {
+					t.Fatalf("Failed to parse token: %v", parseErr)
+				}
+
+				if parsedToken.Header["kid"] != jwk.Kid {
+					t.Errorf("Expected kid '%s', got '%v'", jwk.Kid, parsedToken.Header["kid"])
+				}
+			}
+		})
+	}
+
+	t.Run("Invalid public key", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.Logger = log.Output(&buf)
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+
+		_, err := tokenService.GetJWKS(&entity.JWTKeyRing{PublicKey: "invalid"})
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		log.Logger = log.Output(os.Stdout)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	tokenService := NewTokenService()
+	userUUID, err := uuid.NewV7()
+	if err != nil {
+		t.Errorf("Failed to create userUUID: %v", err)
+	}
+
+	oldKeyRing := &entity.JWTKeyRing{PrivateKey: tokenTests[0].privateKey, PublicKey: tokenTests[0].publicKey}
+	rotatedKeyRing := &entity.JWTKeyRing{
+		PrivateKey:        tokenTests[1].privateKey,
+		PublicKey:         tokenTests[1].publicKey,
+		RetiredPublicKeys: []string{tokenTests[0].publicKey},
+	}
+	droppedKeyRing := &entity.JWTKeyRing{PrivateKey: tokenTests[1].privateKey, PublicKey: tokenTests[1].publicKey}
+
+	oldToken, createErr := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, oldKeyRing)
+	if createErr != nil {
+		t.Fatalf("Failed to CreateToken: %v", createErr)
+	}
+
+	newToken, createErr := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, rotatedKeyRing)
+	if createErr != nil {
+		t.Fatalf("Failed to CreateToken: %v", createErr)
+	}
+
+	t.Run("Token signed before rotation is still valid", func(t *testing.T) {
+		if _, err := tokenService.ValidateToken(*oldToken.Token, rotatedKeyRing); err != nil {
+			t.Errorf("Failed to ValidateToken: %v", err)
+		}
+	})
+
+	t.Run("Token signed after rotation is valid", func(t *testing.T) {
+		if _, err := tokenService.ValidateToken(*newToken.Token, rotatedKeyRing); err != nil {
+			t.Errorf("Failed to ValidateToken: %v", err)
+		}
+	})
+
+	t.Run("Token signed by a dropped key is rejected", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.Logger = log.Output(&buf)
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+
+		if _, err := tokenService.ValidateToken(*oldToken.Token, droppedKeyRing); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		if !strings.Contains(buf.String(), "unknown kid") {
+			t.Errorf("Expected error message 'unknown kid' not found in log output: '%s'", buf.String())
+		}
+
+		log.Logger = log.Output(os.Stdout)
+	})
+
+	t.Run("JWKS publishes the active key first", func(t *testing.T) {
+		jwks, err := tokenService.GetJWKS(rotatedKeyRing)
+		if err != nil {
+			t.Fatalf("Failed to GetJWKS: %v", err)
+		}
+
+		if len(jwks.Keys) != 2 {
+			t.Fatalf("Expected 2 keys in JWKS, got %d", len(jwks.Keys))
+		}
+
+		parsedToken, _, parseErr := jwt.NewParser().ParseUnverified(*newToken.Token, jwt.MapClaims{})
+		if parseErr != nil {
+			t.Fatalf("Failed to parse token: %v", parseErr)
+		}
+
+		if parsedToken.Header["kid"] != jwks.Keys[0].Kid {
+			t.Errorf("Expected kid '%s', got '%v'", jwks.Keys[0].Kid, parsedToken.Header["kid"])
+		}
+	})
+}
+
+func TestSigningAlgorithms(t *testing.T) {
+	tokenService := NewTokenService()
+	userUUID, err := uuid.NewV7()
+	if err != nil {
+		t.Errorf("Failed to create userUUID: %v", err)
+	}
+
+	for _, test := range signingAlgorithmTests {
+		t.Run(test.alg, func(t *testing.T) {
+			keyRing := generateKeyRing(t, test.alg)
+			testToken, createErr := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing)
+			if createErr != nil {
+				t.Fatalf("Failed to CreateToken: %v", createErr)
+			}
+
+			parsedToken, _, parseErr := jwt.NewParser().ParseUnverified(*testToken.Token, jwt.MapClaims{})
+			if parseErr != nil {
+				t.Fatalf("Failed to parse token: %v", parseErr)
+			}
+
+			if parsedToken.Header["alg"] != test.alg {
+				t.Errorf("Expected alg '%s', got '%v'", test.alg, parsedToken.Header["alg"])
+			}
+
+			validatedToken, validateErr := tokenService.ValidateToken(*testToken.Token, keyRing)
+			if validateErr != nil {
+				t.Fatalf("Failed to ValidateToken: %v", validateErr)
+			}
+
+			if validatedToken.UserUUID != userUUID.String() {
+				t.Errorf("Expected sub '%s', got '%s'", userUUID.String(), validatedToken.UserUUID)
+			}
+
+			jwks, jwksErr := tokenService.GetJWKS(keyRing)
+			if jwksErr != nil {
+				t.Fatalf("Failed to GetJWKS: %v", jwksErr)
+			}
+
+			jwk := jwks.Keys[0]
+			if jwk.Kty != test.expectedKty || jwk.Crv != test.expectedCrv || jwk.Alg != test.alg {
+				t.Errorf("Unexpected JWK members: %+v", jwk)
+			}
+
+			if parsedToken.Header["kid"] != jwk.Kid {
+				t.Errorf("Expected kid '%s', got '%v'", jwk.Kid, parsedToken.Header["kid"])
+			}
+		})
+	}
+
+	t.Run("Private key does not match the algorithm", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.Logger = log.Output(&buf)
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+
+		keyRing := generateKeyRing(t, "EdDSA")
+		keyRing.Algorithm = "ES256"
+		if _, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		keyRing.Algorithm = "HS256"
+		if _, err := tokenService.CreateToken(userUUID.String(), testScope, testRoles, testTTL, keyRing); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		log.Logger = log.Output(os.Stdout)
+	})
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	tokenService := NewTokenService()
+	keyRing := &entity.JWTKeyRing{PrivateKey: tokenTests[0].privateKey, PublicKey: tokenTests[0].publicKey}
+	decodedPublicKey, _ := base64.StdEncoding.DecodeString(keyRing.PublicKey)
+	jwks, _ := tokenService.GetJWKS(keyRing)
+	claims := jwt.MapClaims{
+		"sub":        "attacker",
+		"token_uuid": "attacker",
+		"exp":        time.Now().Add(testTTL).Unix(),
+	}
+
+	// HS256 signed with the RSA public key as the HMAC secret
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmacToken.Header["kid"] = jwks.Keys[0].Kid
+	hmacTokenStr, _ := hmacToken.SignedString(decodedPublicKey)
+
+	// Unsigned token
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	noneToken.Header["kid"] = jwks.Keys[0].Kid
+	noneTokenStr, _ := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	// ES256 token claiming the kid of the RSA key
+	ecdsaKeyRing := generateKeyRing(t, "ES256")
+	_, ecdsaKey, _ := parsePrivateKey(ecdsaKeyRing)
+	ecdsaToken := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	ecdsaToken.Header["kid"] = jwks.Keys[0].Kid
+	ecdsaTokenStr, _ := ecdsaToken.SignedString(ecdsaKey)
+
+	var buf bytes.Buffer
+	log.Logger = log.Output(&buf)
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+
+	for name, tokenStr := range map[string]string{"HS256": hmacTokenStr, "none": noneTokenStr, "ES256": ecdsaTokenStr} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tokenService.ValidateToken(tokenStr, keyRing); err == nil {
+				t.Errorf("Expected %s token to be rejected", name)
+			}
+		})
+	}
+
+	log.Logger = log.Output(os.Stdout)
+}
+
+func TestRegisteredClaims(t *testing.T) {
+	tokenService := NewTokenService()
+	userUUID, err := uuid.NewV7()
+	if err != nil {
+		t.Errorf("Failed to create userUUID: %v", err)
+	}
+
+	for _, test := range registeredClaimsTests {
+		t.Run(test.name, func(t *testing.T) {
+			issuingKeyRing := generateKeyRing(t, "ES256")
+			issuingKeyRing.Issuer = test.issuer
+			issuingKeyRing.Audience = test.audience
+
+			// The validating side always expects the issuer and access token audience of the deployment
+			validatingKeyRing := *issuingKeyRing
+			validatingKeyRing.Issuer = registeredClaimsTests[0].issuer
+			validatingKeyRing.Audience = registeredClaimsTests[0].audience
+			validatingKeyRing.Leeway = test.leeway
+
+			testToken, createErr := tokenService.CreateToken(userUUID.String(), testScope, testRoles, test.ttl, issuingKeyRing)
+			if createErr != nil {
+				t.Fatalf("Failed to CreateToken: %v", createErr)
+			}
+
+			var buf bytes.Buffer
+			log.Logger = log.Output(&buf)
+			zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+			defer func() { log.Logger = log.Output(os.Stdout) }()
+
+			validatedToken, validateErr := tokenService.ValidateToken(*testToken.Token, &validatingKeyRing)
+			if !test.expectedValid {
+				if validateErr == nil {
+					t.Errorf("Expected an error, got nil")
+				}
+				return
+			}
+
+			if validateErr != nil {
+				t.Fatalf("Failed to ValidateToken: %v", validateErr)
+			}
+
+			if validatedToken.Issuer != test.issuer {
+				t.Errorf("Expected iss '%s', got '%s'", test.issuer, validatedToken.Issuer)
+			}
+
+			if len(validatedToken.Audience) != 1 || validatedToken.Audience[0] != test.audience {
+				t.Errorf("Expected aud '[%s]', got '%v'", test.audience, validatedToken.Audience)
+			}
+
+			if strings.Join(validatedToken.Scope, " ") != strings.Join(testScope, " ") {
+				t.Errorf("Expected scope '%v', got '%v'", testScope, validatedToken.Scope)
+			}
+
+			if *validatedToken.ExpiresIn != *testToken.ExpiresIn {
+				t.Errorf("Expected exp '%d', got '%d'", *testToken.ExpiresIn, *validatedToken.ExpiresIn)
+			}
+		})
+	}
+}
+
+func TestCreateClientToken(t *testing.T) {
+	tokenService := NewTokenService()
+	keyRing := generateKeyRing(t, "ES256")
+	keyRing.Issuer = registeredClaimsTests[0].issuer
+	keyRing.Audience = registeredClaimsTests[0].audience
+
+	testToken, err := tokenService.CreateClientToken("test-user-uuid", "test-client-id", testScope, testTTL, keyRing)
+	if err != nil {
+		t.Fatalf("Failed to CreateClientToken: %v", err)
+	}
+
+	validatedToken, err := tokenService.ValidateToken(*testToken.Token, keyRing)
+	if err != nil {
+		t.Fatalf("Failed to ValidateToken: %v", err)
+	}
+
+	if validatedToken.ClientID != "test-client-id" || validatedToken.UserUUID != "test-user-uuid" {
+		t.Errorf("Expected client_id 'test-client-id' and sub 'test-user-uuid', got '%s' and '%s'",
+			validatedToken.ClientID, validatedToken.UserUUID)
+	}
+
+	if len(validatedToken.Roles) != 0 {
+		t.Errorf("Expected no roles, got '%v'", validatedToken.Roles)
+	}
+
+	// The tokens of the sessions of the users have no client
+	userToken, err := tokenService.CreateToken("test-user-uuid", testScope, testRoles, testTTL, keyRing)
+	if err != nil {
+		t.Fatalf("Failed to CreateToken: %v", err)
+	}
+
+	if validatedToken, err := tokenService.ValidateToken(*userToken.Token, keyRing); err != nil ||
+		validatedToken.ClientID != "" {
+		t.Errorf("Expected a token without client_id, got '%v' and '%v'", validatedToken, err)
+	}
+}
+
+func TestCreateIDToken(t *testing.T) {
+	tokenService := NewTokenService()
+	accessKeyRing := generateKeyRing(t, "ES256")
+	accessKeyRing.Issuer = registeredClaimsTests[0].issuer
+	accessKeyRing.Audience = registeredClaimsTests[0].audience
+
+	idToken := &entity.IDToken{
+		Subject:  "test-user-uuid",
+		ClientID: "test-client-id",
+		Nonce:    "test-nonce",
+		Claims:   map[string]interface{}{"email": "jiewei@example.com", "sub": "another-user-uuid"},
+	}
+
+	testToken, err := tokenService.CreateIDToken(idToken, testTTL, accessKeyRing)
+	if err != nil {
+		t.Fatalf("Failed to CreateIDToken: %v", err)
+	}
+
+	// The audience of an ID token is the client, so it is not accepted as an access token
+	var buf bytes.Buffer
+	log.Logger = log.Output(&buf)
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	defer func() { log.Logger = log.Output(os.Stdout) }()
+
+	if _, err := tokenService.ValidateToken(*testToken.Token, accessKeyRing); err == nil {
+		t.Errorf("Expected the ID token to be rejected as an access token")
+	}
+
+	clientKeyRing := *accessKeyRing
+	clientKeyRing.Audience = idToken.ClientID
+	if _, err := tokenService.ValidateToken(*testToken.Token, &clientKeyRing); err != nil {
+		t.Fatalf("Failed to ValidateToken for the client: %v", err)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(*testToken.Token, claims); err != nil {
+		t.Fatalf("Failed to parse the ID token: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"iss": accessKeyRing.Issuer, "sub": "test-user-uuid", "aud": "test-client-id",
+		"nonce": "test-nonce", "email": "jiewei@example.com",
+	}
+	for name, value := range expected {
+		if claims[name] != value {
+			t.Errorf("Expected %s '%v', got '%v'", name, value, claims[name])
+		}
+	}
+}
+
+// generateKeyRing creates a fresh key pair for `alg`, encoded like the env variables
+func generateKeyRing(t *testing.T, alg string) *entity.JWTKeyRing {
+	var privateKey crypto.Signer
+	var err error
+
+	switch alg {
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("Unsupported alg in test: %s", alg)
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+
+	return &entity.JWTKeyRing{
+		Algorithm:  alg,
+		PrivateKey: base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// FileMailer writes every mail as an `.eml` file into `dir` instead of delivering it, for dev environments.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from string, dir string) *FileMailer {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgMailerError)
+	}
+
+	return &FileMailer{from, dir}
+}
+
+func (m *FileMailer) Send(mail *entity.Mail) *restErr.RestErr {
+	id, err := uuid.NewV7()
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Msg(restErr.ErrUUIDError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	// UUIDv7 file names sort by the time the mail was sent
+	path := filepath.Join(m.dir, id.String()+".eml")
+	if err := os.WriteFile(path, buildMessage(m.from, mail), 0644); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgMailerError)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	log.Debug().Str("to", mail.To).Str("path", path).Msg("mail written to file")
+	return nil
+}
+
+// buildMessage formats a plain text RFC 5322 message.
+func buildMessage(from string, mail *entity.Mail) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mail.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(mail.Body)
+	return []byte(b.String())
+}
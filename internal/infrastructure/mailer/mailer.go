@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgUnknownMailer = "unknown mailer [%s]; defaulting to the in-memory mailer"
+
+	smtpMailer   = "smtp"
+	fileMailer   = "file"
+	memoryMailer = "memory"
+)
+
+// NewMailer returns the `Mailer` adapter selected by `MAILER`.
+func NewMailer(mailerConfig *entity.MailerConfig) service.Mailer {
+	switch mailerConfig.Mailer {
+	case smtpMailer:
+		return NewSMTPMailer(mailerConfig)
+	case fileMailer:
+		return NewFileMailer(mailerConfig.From, mailerConfig.FileDir)
+	case memoryMailer:
+		return NewInMemoryMailer()
+	default:
+		log.Error().Msgf(errMsgUnknownMailer, mailerConfig.Mailer)
+		return NewInMemoryMailer()
+	}
+}
@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+)
+
+func TestNewMailer(t *testing.T) {
+	for _, test := range newMailerTests {
+		t.Run(test.name, func(t *testing.T) {
+			mailerConfig := &entity.MailerConfig{Mailer: test.mailer, From: testFrom, FileDir: t.TempDir()}
+			m := NewMailer(mailerConfig)
+
+			var ok bool
+			switch test.expectedType {
+			case smtpMailer:
+				_, ok = m.(*SMTPMailer)
+			case fileMailer:
+				_, ok = m.(*FileMailer)
+			case memoryMailer:
+				_, ok = m.(*InMemoryMailer)
+			}
+
+			if !ok {
+				t.Errorf("Expected a %s mailer, got %T", test.expectedType, m)
+			}
+		})
+	}
+}
+
+func TestInMemoryMailer(t *testing.T) {
+	m := NewInMemoryMailer()
+	for _, mail := range testMails {
+		if err := m.Send(&mail); err != nil {
+			t.Fatalf("Failed to Send: %v", err)
+		}
+	}
+
+	if len(m.Mails()) != len(testMails) {
+		t.Errorf("Expected %d mails, got %d", len(testMails), len(m.Mails()))
+	}
+
+	mail, ok := m.LastMailTo(testMails[0].To)
+	if !ok {
+		t.Fatalf("Expected a mail to '%s'", testMails[0].To)
+	}
+
+	if mail.Subject != testMails[2].Subject {
+		t.Errorf("Expected the latest mail '%s', got '%s'", testMails[2].Subject, mail.Subject)
+	}
+
+	if _, ok := m.LastMailTo("nobody@e.com"); ok {
+		t.Errorf("Expected no mail to 'nobody@e.com'")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(testFrom, dir)
+	if err := m.Send(&testMails[0]); err != nil {
+		t.Fatalf("Failed to Send: %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 mail file in '%s', got %d (%v)", dir, len(files), err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read mail file: %v", err)
+	}
+
+	for _, expected := range []string{
+		"From: " + testFrom, "To: " + testMails[0].To, "Subject: " + testMails[0].Subject, testMails[0].Body,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected '%s' in mail file: '%s'", expected, content)
+		}
+	}
+
+	t.Run("Unwritable directory", func(t *testing.T) {
+		m := &FileMailer{from: testFrom, dir: filepath.Join(dir, "missing")}
+		if err := m.Send(&testMails[0]); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
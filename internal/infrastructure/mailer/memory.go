@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"sync"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/rs/zerolog/log"
+)
+
+// InMemoryMailer keeps every mail in memory so that tests can read them back.
+type InMemoryMailer struct {
+	mu    sync.Mutex
+	mails []entity.Mail
+}
+
+func NewInMemoryMailer() *InMemoryMailer {
+	return &InMemoryMailer{}
+}
+
+func (m *InMemoryMailer) Send(mail *entity.Mail) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mails = append(m.mails, *mail)
+	log.Debug().Str("to", mail.To).Str("subject", mail.Subject).Msg("mail kept in memory")
+	return nil
+}
+
+// Mails returns a copy of the mails sent so far.
+func (m *InMemoryMailer) Mails() []entity.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]entity.Mail{}, m.mails...)
+}
+
+// LastMailTo returns the latest mail sent to `to`.
+func (m *InMemoryMailer) LastMailTo(to string) (entity.Mail, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.mails) - 1; i >= 0; i-- {
+		if m.mails[i].To == to {
+			return m.mails[i], true
+		}
+	}
+
+	return entity.Mail{}, false
+}
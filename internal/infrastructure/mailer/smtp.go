@@ -0,0 +1,40 @@
+// coverage:ignore file
+// Testing with integration test
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/rs/zerolog/log"
+)
+
+type SMTPMailer struct {
+	from string
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(mailerConfig *entity.MailerConfig) *SMTPMailer {
+	var auth smtp.Auth
+	if mailerConfig.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", mailerConfig.SMTPUsername, mailerConfig.SMTPPassword, mailerConfig.SMTPHost)
+	}
+
+	return &SMTPMailer{
+		from: mailerConfig.From,
+		addr: net.JoinHostPort(mailerConfig.SMTPHost, mailerConfig.SMTPPort),
+		auth: auth,
+	}
+}
+
+func (m *SMTPMailer) Send(mail *entity.Mail) *restErr.RestErr {
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{mail.To}, buildMessage(m.from, mail)); err != nil {
+		log.Error().Err(err).Str("to", mail.To).Msg(restErr.ErrMsgMailerError)
+		return restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
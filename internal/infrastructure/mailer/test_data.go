@@ -0,0 +1,24 @@
+// coverage:ignore file
+// Test file
+package mailer
+
+import "github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+
+const testFrom = "no-reply@localhost"
+
+var newMailerTests = []struct {
+	name         string
+	mailer       string
+	expectedType string
+}{
+	{name: "SMTP", mailer: smtpMailer, expectedType: smtpMailer},
+	{name: "File", mailer: fileMailer, expectedType: fileMailer},
+	{name: "Memory", mailer: memoryMailer, expectedType: memoryMailer},
+	{name: "Unknown defaults to memory", mailer: "carrier-pigeon", expectedType: memoryMailer},
+}
+
+var testMails = []entity.Mail{
+	{To: "jiewei@gmail.com", Subject: "Verify your email address", Body: "https://localhost/verify-email?token=1"},
+	{To: "mu@e.com", Subject: "Verify your email address", Body: "https://localhost/verify-email?token=2"},
+	{To: "jiewei@gmail.com", Subject: "Verify your email address again", Body: "https://localhost/verify-email?token=3"},
+}
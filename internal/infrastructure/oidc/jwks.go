@@ -0,0 +1,94 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+)
+
+const (
+	errMsgUnsupportedJWK = "unsupported JWK: kty %s, crv %s"
+	errMsgInvalidJWK     = "invalid JWK"
+)
+
+// parseJWKS returns the signing keys of a JWK Set by `kid`; keys of unsupported types are skipped.
+func parseJWKS(body []byte) (map[string]crypto.PublicKey, error) {
+	jwks := &entity.JWKSet{}
+	if err := json.Unmarshal(body, jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := publicKeyFromJWK(&jwk)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// publicKeyFromJWK is the inverse of `newJWK` of the jwt package, for the keys published by other issuers.
+func publicKeyFromJWK(jwk *entity.JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(jwk.N)
+		e, errE := decodeBigInt(jwk.E)
+		if errN != nil || errE != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New(errMsgInvalidJWK)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		curve, ok := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521(),
+		}[jwk.Crv]
+		if !ok {
+			break
+		}
+
+		x, errX := decodeBigInt(jwk.X)
+		y, errY := decodeBigInt(jwk.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New(errMsgInvalidJWK)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			break
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New(errMsgInvalidJWK)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf(errMsgUnsupportedJWK, jwk.Kty, jwk.Crv)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New(errMsgInvalidJWK)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
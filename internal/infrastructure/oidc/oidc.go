@@ -0,0 +1,362 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// The keys are fetched again when an ID token is signed with an unknown `kid`, at most once per interval
+	keyRefreshInterval = time.Minute
+	requestTimeout     = 10 * time.Second
+)
+
+const (
+	errMsgUnexpectedStatus = "unexpected status %d from %s"
+	errMsgIssuerMismatch   = "issuer of the discovery document does not match the issuer URL"
+	errMsgMissingEndpoint  = "discovery document is missing a required endpoint"
+	errMsgMissingIDToken   = "token response is missing the ID token"
+	errMsgInvalidIDToken   = "invalid ID token"
+	errMsgInvalidNonce     = "nonce of the ID token does not match the login"
+	errMsgUnknownKey       = "ID token is signed with an unknown key"
+	errMsgSubjectMismatch  = "subject of the userinfo response does not match the ID token"
+)
+
+// The asymmetric algorithms accepted for ID tokens; `none` and HMAC would let anyone forge them.
+var supportedSigningAlgs = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+}
+
+// discoveryDocument is the part of the OpenID Provider Metadata that the authorization code flow needs.
+type discoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	UserInfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// idTokenClaims are the claims of the ID token and of the userinfo response that identify the user.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	GivenName     string       `json:"given_name"`
+	FamilyName    string       `json:"family_name"`
+}
+
+// flexibleBool accepts both `true` and `"true"`, as some providers send `email_verified` as a string.
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	*b = flexibleBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+/*
+OIDCProvider resolves its endpoints from the discovery document of the issuer on first use,
+so that a provider that is down at startup does not prevent the server from starting.
+*/
+type OIDCProvider struct {
+	config *entity.OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *discoveryDocument // Only set once the JWKS has been fetched and never changed afterwards
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewOIDCProviders(config *entity.OAuth2Config) []service.OIDCProvider {
+	providers := []service.OIDCProvider{}
+	for _, providerConfig := range config.OIDCProviders {
+		providers = append(providers, NewOIDCProvider(providerConfig))
+	}
+
+	return providers
+}
+
+func NewOIDCProvider(config *entity.OIDCProviderConfig) service.OIDCProvider {
+	return &OIDCProvider{config: config, client: &http.Client{Timeout: requestTimeout}}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *OIDCProvider) AuthCodeURL(state, codeVerifier, nonce string) (string, *restErr.RestErr) {
+	oauth2Config, _, err := p.oauth2Config()
+	if err != nil {
+		return "", err
+	}
+
+	return oauth2Config.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(codeVerifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+func (p *OIDCProvider) Exchange(code, codeVerifier, nonce string) (*entity.ExternalUser, *restErr.RestErr) {
+	oauth2Config, metadata, rErr := p.oauth2Config()
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), oauth2.HTTPClient, p.client),
+		requestTimeout)
+	defer cancel()
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		log.Error().Err(err).Str("provider", p.config.Name).Msg(restErr.ErrMsgOIDCError)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		log.Error().Str("provider", p.config.Name).Msg(errMsgMissingIDToken)
+		return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	claims, err := p.verifyIDToken(metadata, rawIDToken, nonce)
+	if err != nil {
+		log.Warn().Err(err).Str("provider", p.config.Name).Msg(errMsgInvalidIDToken)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	// The email is optional in the ID token, e.g. when the provider only returns it from the userinfo endpoint
+	if claims.Email == "" && metadata.UserInfoEndpoint != "" {
+		if claims, err = getUserInfo(ctx, oauth2Config, metadata.UserInfoEndpoint, token, claims.Subject); err != nil {
+			log.Error().Err(err).Str("provider", p.config.Name).Msg(restErr.ErrMsgOIDCError)
+			return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+		}
+	}
+
+	return &entity.ExternalUser{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		FirstName:     strings.ToLower(strings.TrimSpace(claims.GivenName)),
+		LastName:      strings.ToLower(strings.TrimSpace(claims.FamilyName)),
+	}, nil
+}
+
+/*
+verifyIDToken checks the signature of the ID token against the keys of the provider,
+that it was issued by the provider for this client and has not expired, and that it carries the nonce of the login.
+*/
+func (p *OIDCProvider) verifyIDToken(metadata *discoveryDocument, rawIDToken, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, p.keyFunc,
+		jwt.WithValidMethods(metadata.signingAlgs()),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New(errMsgInvalidIDToken)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New(errMsgInvalidNonce)
+	}
+
+	return claims, nil
+}
+
+// keyFunc picks the key of the JWKS that matches the `kid` of the token, fetching the JWKS again after a key rotation.
+func (p *OIDCProvider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.lookupKey(kid)
+	if !ok && time.Since(p.keysFetchedAt) > keyRefreshInterval {
+		if err := p.fetchKeys(p.metadata.JWKSURI); err != nil {
+			return nil, err
+		}
+		key, ok = p.lookupKey(kid)
+	}
+
+	if !ok {
+		return nil, errors.New(errMsgUnknownKey)
+	}
+
+	return key, nil
+}
+
+// lookupKey falls back to the only key of the JWKS for tokens without a `kid`.
+func (p *OIDCProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// signingAlgs defaults to RS256, which every provider must support.
+func (d *discoveryDocument) signingAlgs() []string {
+	algs := []string{}
+	for _, alg := range d.IDTokenSigningAlgValuesSupported {
+		if slices.Contains(supportedSigningAlgs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+
+	if len(algs) == 0 {
+		return []string{"RS256"}
+	}
+
+	return algs
+}
+
+// oauth2Config also returns the metadata it was built from, so that a request uses the same metadata throughout.
+func (p *OIDCProvider) oauth2Config() (*oauth2.Config, *discoveryDocument, *restErr.RestErr) {
+	metadata, err := p.discover()
+	if err != nil {
+		log.Error().Err(err).Str("provider", p.config.Name).Msg(restErr.ErrMsgOIDCError)
+		return nil, nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+	}, metadata, nil
+}
+
+/*
+discover fetches the discovery document and the JWKS of the provider unless it already has them.
+The metadata is only read under the lock, and the returned document is never changed afterwards.
+*/
+func (p *OIDCProvider) discover() (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	issuerURL := strings.TrimSuffix(p.config.IssuerURL, "/")
+	body, err := p.get(issuerURL + discoveryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &discoveryDocument{}
+	if err := json.Unmarshal(body, metadata); err != nil {
+		return nil, err
+	}
+
+	// Prevents a provider from issuing tokens in the name of another issuer (OpenID Connect Discovery section 4.3)
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuerURL {
+		return nil, errors.New(errMsgIssuerMismatch)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New(errMsgMissingEndpoint)
+	}
+
+	if err := p.fetchKeys(metadata.JWKSURI); err != nil {
+		return nil, err
+	}
+
+	p.metadata = metadata
+	return metadata, nil
+}
+
+// fetchKeys must be called with the lock held.
+func (p *OIDCProvider) fetchKeys(jwksURI string) error {
+	body, err := p.get(jwksURI)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(body)
+	if err != nil {
+		return err
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	return nil
+}
+
+// getUserInfo reads the claims of the userinfo endpoint, which must be about the subject of the ID token.
+func getUserInfo(ctx context.Context, oauth2Config *oauth2.Config, userInfoEndpoint string, token *oauth2.Token,
+	subject string) (*idTokenClaims, error) {
+	resp, err := oauth2Config.Client(ctx, token).Get(userInfoEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(errMsgUnexpectedStatus, resp.StatusCode, userInfoEndpoint)
+	}
+
+	claims := &idTokenClaims{}
+	if err := json.NewDecoder(resp.Body).Decode(claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Subject != subject {
+		return nil, errors.New(errMsgSubjectMismatch)
+	}
+
+	return claims, nil
+}
+
+func (p *OIDCProvider) get(url string) ([]byte, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(errMsgUnexpectedStatus, resp.StatusCode, url)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
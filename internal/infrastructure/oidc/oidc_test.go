@@ -0,0 +1,252 @@
+package oidc
+
+import (
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const testVerifier = "test-code-verifier-that-is-at-least-43-characters-long"
+
+func newTestProvider(s *stubServer) *OIDCProvider {
+	s.verifier = testVerifier
+	return NewOIDCProvider(s.config()).(*OIDCProvider)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	s := newStubServer()
+	defer s.Close()
+	s.addKey("rsa", &newRSAKey().PublicKey)
+	p := newTestProvider(s)
+
+	authCodeURL, rErr := p.AuthCodeURL("test-state", testVerifier, testNonce)
+	if rErr != nil {
+		t.Fatalf("AuthCodeURL failed: %v", rErr)
+	}
+
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatalf("failed to parse '%s': %v", authCodeURL, err)
+	}
+
+	if u.Scheme+"://"+u.Host+u.Path != s.URL+"/authorize" {
+		t.Errorf("expected the authorization endpoint of the discovery document, got '%s'", authCodeURL)
+	}
+
+	query := u.Query()
+	expected := map[string]string{
+		"client_id":             testClientID,
+		"redirect_uri":          "http://localhost:4040/auth/stub/callback",
+		"response_type":         "code",
+		"scope":                 "openid email profile",
+		"state":                 "test-state",
+		"nonce":                 testNonce,
+		"code_challenge":        oauth2.S256ChallengeFromVerifier(testVerifier),
+		"code_challenge_method": "S256",
+	}
+	for param, value := range expected {
+		if query.Get(param) != value {
+			t.Errorf("expected '%s' to be '%s', got '%s'", param, value, query.Get(param))
+		}
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	s := newStubServer()
+	defer s.Close()
+	s.issuer = "https://evil.example.com"
+	p := newTestProvider(s)
+
+	if _, rErr := p.AuthCodeURL("test-state", testVerifier, testNonce); rErr == nil || rErr.Status != 502 {
+		t.Errorf("expected a 502 for an issuer that does not match, got '%v'", rErr)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	rsaKey := newRSAKey()
+	ecKey := newECKey()
+	otherKey := newRSAKey()
+
+	tests := []struct {
+		name           string
+		idToken        func(s *stubServer) string
+		code           string
+		verifier       string
+		expectedStatus int
+	}{
+		{name: "Valid RS256 ID token", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, s.validClaims())
+		}},
+		{name: "Valid ES256 ID token", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodES256, "ec", ecKey, s.validClaims())
+		}},
+		{name: "Wrong code verifier", verifier: "another-code-verifier-that-is-at-least-43-characters",
+			idToken: func(s *stubServer) string {
+				return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, s.validClaims())
+			}, expectedStatus: 400},
+		{name: "Wrong code", code: "another-code", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, s.validClaims())
+		}, expectedStatus: 400},
+		{name: "Wrong nonce", idToken: func(s *stubServer) string {
+			claims := s.validClaims()
+			claims["nonce"] = "another-nonce"
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, expectedStatus: 400},
+		{name: "Wrong audience", idToken: func(s *stubServer) string {
+			claims := s.validClaims()
+			claims["aud"] = "another-client-id"
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, expectedStatus: 400},
+		{name: "Wrong issuer", idToken: func(s *stubServer) string {
+			claims := s.validClaims()
+			claims["iss"] = "https://evil.example.com"
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, expectedStatus: 400},
+		{name: "Expired", idToken: func(s *stubServer) string {
+			claims := s.validClaims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return signToken(jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, expectedStatus: 400},
+		{name: "Signed with an unknown key", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodRS256, "rsa", otherKey, s.validClaims())
+		}, expectedStatus: 400},
+		{name: "Signed with HS256", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodHS256, "rsa", []byte(testClientSecret), s.validClaims())
+		}, expectedStatus: 400},
+		{name: "Unsigned", idToken: func(s *stubServer) string {
+			return signToken(jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, s.validClaims())
+		}, expectedStatus: 400},
+		{name: "Missing ID token", idToken: func(s *stubServer) string {
+			return ""
+		}, expectedStatus: 502},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStubServer()
+			defer s.Close()
+			s.addKey("rsa", &rsaKey.PublicKey)
+			s.addKey("ec", &ecKey.PublicKey)
+			p := newTestProvider(s)
+			s.setIDToken(test.idToken(s))
+
+			code, verifier := testCode, testVerifier
+			if test.code != "" {
+				code = test.code
+			}
+			if test.verifier != "" {
+				verifier = test.verifier
+			}
+
+			user, rErr := p.Exchange(code, verifier, testNonce)
+			if test.expectedStatus != 0 {
+				if rErr == nil || rErr.Status != test.expectedStatus {
+					t.Errorf("expected status '%d', got '%v'", test.expectedStatus, rErr)
+				}
+				return
+			}
+
+			if rErr != nil {
+				t.Fatalf("Exchange failed: %v", rErr)
+			}
+
+			if user.Provider != "stub" || user.Subject != testSubject || user.Email != "jiewei@example.com" ||
+				!user.EmailVerified || user.FirstName != "jie" || user.LastName != "wei" {
+				t.Errorf("expected the user of the ID token, got '%+v'", user)
+			}
+		})
+	}
+}
+
+func TestExchangeKeyRotation(t *testing.T) {
+	s := newStubServer()
+	defer s.Close()
+	s.addKey("old", &newRSAKey().PublicKey)
+	p := newTestProvider(s)
+
+	if _, rErr := p.AuthCodeURL("test-state", testVerifier, testNonce); rErr != nil {
+		t.Fatalf("AuthCodeURL failed: %v", rErr)
+	}
+
+	// The provider rotates to a key that was not in the JWKS when it was fetched
+	newKey := newRSAKey()
+	s.addKey("new", &newKey.PublicKey)
+	s.setIDToken(signToken(jwt.SigningMethodRS256, "new", newKey, s.validClaims()))
+
+	if _, rErr := p.Exchange(testCode, testVerifier, testNonce); rErr == nil {
+		t.Errorf("expected the JWKS not to be fetched again within %s", keyRefreshInterval)
+	}
+
+	p.keysFetchedAt = time.Now().Add(-keyRefreshInterval - time.Second)
+	if _, rErr := p.Exchange(testCode, testVerifier, testNonce); rErr != nil {
+		t.Errorf("expected the rotated key to be fetched, got '%v'", rErr)
+	}
+}
+
+// Run with -race: the logins share the metadata of the provider while the discovery fails and is retried.
+func TestExchangeConcurrentDiscovery(t *testing.T) {
+	s := newStubServer()
+	defer s.Close()
+	key := newRSAKey()
+	s.addKey("rsa", &key.PublicKey)
+	s.setIDToken(signToken(jwt.SigningMethodRS256, "rsa", key, s.validClaims()))
+	s.setJWKSDown(true)
+	p := newTestProvider(s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		if i == 10 {
+			s.setJWKSDown(false)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, rErr := p.Exchange(testCode, testVerifier, testNonce); rErr != nil && rErr.Status != 502 {
+				t.Errorf("expected the login to succeed or the discovery to fail, got '%v'", rErr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, rErr := p.Exchange(testCode, testVerifier, testNonce); rErr != nil {
+		t.Errorf("expected the discovery to be retried, got '%v'", rErr)
+	}
+}
+
+func TestExchangeUserInfo(t *testing.T) {
+	s := newStubServer()
+	defer s.Close()
+	key := newRSAKey()
+	s.addKey("rsa", &key.PublicKey)
+	p := newTestProvider(s)
+
+	claims := s.validClaims()
+	delete(claims, "email")
+	delete(claims, "email_verified")
+	s.setIDToken(signToken(jwt.SigningMethodRS256, "rsa", key, claims))
+
+	// Some providers send `email_verified` as a string
+	s.userInfo = map[string]interface{}{
+		"sub": testSubject, "email": "jiewei@example.com", "email_verified": "true", "given_name": "Jie",
+	}
+
+	user, rErr := p.Exchange(testCode, testVerifier, testNonce)
+	if rErr != nil {
+		t.Fatalf("Exchange failed: %v", rErr)
+	}
+
+	if user.Email != "jiewei@example.com" || !user.EmailVerified || user.FirstName != "jie" {
+		t.Errorf("expected the email of the userinfo response, got '%+v'", user)
+	}
+
+	// The userinfo response must be about the user of the ID token
+	s.userInfo["sub"] = "another-subject"
+	if _, rErr := p.Exchange(testCode, testVerifier, testNonce); rErr == nil || rErr.Status != 502 {
+		t.Errorf("expected a 502 for the userinfo of another subject, got '%v'", rErr)
+	}
+}
@@ -0,0 +1,206 @@
+// coverage:ignore file
+// Test file
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "test-client-id"
+	testClientSecret = "test-client-secret"
+	testCode         = "test-code"
+	testNonce        = "test-nonce"
+	testSubject      = "248289761001"
+)
+
+/*
+stubServer is an OpenID Connect provider serving the discovery document, the JWKS,
+the token endpoint and the userinfo endpoint. The token endpoint only redeems `testCode`
+with the code verifier in `verifier` and returns `idToken` as the ID token.
+*/
+type stubServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	issuer   string // Issuer of the discovery document, which defaults to the URL of the server
+	keys     map[string]crypto.PublicKey
+	jwksDown bool // Fails the JWKS requests, which fails the discovery
+	verifier string
+	idToken  string
+	userInfo map[string]interface{}
+}
+
+func newStubServer() *stubServer {
+	s := &stubServer{keys: map[string]crypto.PublicKey{}}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		issuer := s.issuer
+		if issuer == "" {
+			issuer = s.URL
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                issuer,
+			"authorization_endpoint":                s.URL + "/authorize",
+			"token_endpoint":                        s.URL + "/token",
+			"userinfo_endpoint":                     s.URL + "/userinfo",
+			"jwks_uri":                              s.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256", "ES256", "HS256", "none"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.jwksDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		keys := []map[string]string{}
+		for kid, key := range s.keys {
+			keys = append(keys, toJWK(kid, key))
+		}
+		writeJSON(w, map[string]interface{}{"keys": keys})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if r.FormValue("code") != testCode || r.FormValue("code_verifier") != s.verifier {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"access_token": "test-access-token", "token_type": "Bearer", "expires_in": 3600, "id_token": s.idToken,
+		})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, s.userInfo)
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *stubServer) addKey(kid string, key crypto.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+}
+
+func (s *stubServer) setJWKSDown(jwksDown bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwksDown = jwksDown
+}
+
+func (s *stubServer) setIDToken(idToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idToken = idToken
+}
+
+func (s *stubServer) config() *entity.OIDCProviderConfig {
+	return &entity.OIDCProviderConfig{
+		Name:         "stub",
+		IssuerURL:    s.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "http://localhost:4040/auth/stub/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// validClaims are the claims of an ID token that `verifyIDToken` accepts.
+func (s *stubServer) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            testSubject,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          testNonce,
+		"email":          "JieWei@Example.com",
+		"email_verified": true,
+		"given_name":     "Jie",
+		"family_name":    "Wei",
+	}
+}
+
+func signToken(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func newRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func newECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func toJWK(kid string, key crypto.PublicKey) map[string]string {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "use": "sig", "kid": kid, "n": encode(k.N), "e": encode(big.NewInt(int64(k.E)))}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "use": "sig", "kid": kid, "crv": "P-256", "x": encode(k.X), "y": encode(k.Y)}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
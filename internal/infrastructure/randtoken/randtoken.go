@@ -0,0 +1,33 @@
+package randtoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/rs/zerolog/log"
+)
+
+const errMsgRandomToken = "failed to generate a random token"
+
+// Generate returns a random 256-bit token, hex encoded so that it is safe in a URL and a cookie
+// and that `PreProcessInputs` leaves it intact.
+func Generate() (string, *restErr.RestErr) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Error().Err(err).Msg(errMsgRandomToken)
+		return "", restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+/*
+Hash is how opaque tokens, e.g. codes, API keys and client secrets, are stored,
+so that a leaked Redis snapshot or database dump does not leak usable tokens.
+*/
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
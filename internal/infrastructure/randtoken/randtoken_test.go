@@ -0,0 +1,27 @@
+package randtoken
+
+import "testing"
+
+func TestGenerate(t *testing.T) {
+	token, err := Generate()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	if len(token) != 64 {
+		t.Errorf("expected a 64 characters long token, got '%d'", len(token))
+	}
+
+	other, _ := Generate()
+	if token == other {
+		t.Errorf("expected two tokens to differ, got '%s' twice", token)
+	}
+}
+
+func TestHash(t *testing.T) {
+	// SHA-256 of "abc"
+	expected := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if hash := Hash("abc"); hash != expected {
+		t.Errorf("expected '%s', got '%s'", expected, hash)
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+/*
+`InMemoryRateLimitRepository` keeps the sliding windows in the process memory.
+The limits only hold per instance, so it is meant for tests and single instance deployments.
+*/
+type InMemoryRateLimitRepository struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	requests map[string][]time.Time
+}
+
+func NewInMemoryRateLimitRepository() r.RateLimitRepository {
+	return newInMemoryRateLimitRepository(time.Now)
+}
+
+func newInMemoryRateLimitRepository(now func() time.Time) *InMemoryRateLimitRepository {
+	return &InMemoryRateLimitRepository{now: now, requests: map[string][]time.Time{}}
+}
+
+func (m *InMemoryRateLimitRepository) Allow(key string, rule *entity.RateLimitRule) (
+	*entity.RateLimit, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	requests := m.requests[key]
+
+	// Requests are appended in order, so the expired ones are at the front
+	expired := 0
+	for expired < len(requests) && !requests[expired].After(now.Add(-rule.Window)) {
+		expired++
+	}
+	requests = requests[expired:]
+
+	allowed := len(requests) < rule.Limit
+	if allowed {
+		requests = append(requests, now)
+	}
+
+	if len(requests) == 0 {
+		delete(m.requests, key)
+	} else {
+		m.requests[key] = requests
+	}
+
+	resetAfter := rule.Window
+	if len(requests) > 0 {
+		resetAfter = requests[0].Add(rule.Window).Sub(now)
+	}
+
+	return &entity.RateLimit{
+		Allowed:    allowed,
+		Limit:      rule.Limit,
+		Remaining:  rule.Limit - len(requests),
+		ResetAfter: resetAfter,
+	}, nil
+}
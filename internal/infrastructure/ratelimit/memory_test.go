@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+)
+
+func TestInMemoryRateLimitRepository(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newInMemoryRateLimitRepository(func() time.Time { return now })
+	rule := &entity.RateLimitRule{Limit: 2, Window: time.Minute}
+
+	for _, test := range slidingWindowTests {
+		t.Run(test.name, func(t *testing.T) {
+			now = now.Add(test.elapsed)
+
+			rateLimit, err := store.Allow(test.key, rule)
+			if err != nil {
+				t.Fatalf("Allow failed: %v", err)
+			}
+
+			if rateLimit.Allowed != test.expectedAllowed {
+				t.Errorf("expected Allowed to be '%t', got '%t'", test.expectedAllowed, rateLimit.Allowed)
+			}
+			if rateLimit.Remaining != test.expectedRemaining {
+				t.Errorf("expected Remaining to be '%d', got '%d'", test.expectedRemaining, rateLimit.Remaining)
+			}
+			if rateLimit.ResetAfter != test.expectedResetAfter {
+				t.Errorf("expected ResetAfter to be '%s', got '%s'", test.expectedResetAfter, rateLimit.ResetAfter)
+			}
+		})
+	}
+}
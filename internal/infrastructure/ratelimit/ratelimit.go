@@ -0,0 +1,30 @@
+// coverage:ignore file
+// Testing with integration test
+package ratelimit
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/db/redis"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgUnknownStore = "unknown rate limit store [%s]; defaulting to the redis store"
+
+	redisStore  = "redis"
+	memoryStore = "memory"
+)
+
+// NewRateLimitRepository returns the store selected by `RATE_LIMIT_STORE`.
+func NewRateLimitRepository(rateLimitConfig *entity.RateLimitConfig, redisDB *redis.RedisDB) r.RateLimitRepository {
+	switch rateLimitConfig.Store {
+	case redisStore:
+		return redis.NewRateLimitRepository(redisDB)
+	case memoryStore:
+		return NewInMemoryRateLimitRepository()
+	default:
+		log.Error().Msgf(errMsgUnknownStore, rateLimitConfig.Store)
+		return redis.NewRateLimitRepository(redisDB)
+	}
+}
@@ -0,0 +1,30 @@
+// coverage:ignore file
+// Test file
+package ratelimit
+
+import "time"
+
+// Each case runs after the previous ones against the same store and a limit of 2 per minute
+var slidingWindowTests = []struct {
+	name               string
+	key                string
+	elapsed            time.Duration
+	expectedAllowed    bool
+	expectedRemaining  int
+	expectedResetAfter time.Duration
+}{
+	{name: "First request", key: "a", expectedAllowed: true, expectedRemaining: 1, expectedResetAfter: time.Minute},
+	{
+		name: "Second request", key: "a", elapsed: 20 * time.Second,
+		expectedAllowed: true, expectedRemaining: 0, expectedResetAfter: 40 * time.Second,
+	},
+	{
+		name: "Over the limit", key: "a", elapsed: 20 * time.Second,
+		expectedAllowed: false, expectedRemaining: 0, expectedResetAfter: 20 * time.Second,
+	},
+	{name: "Other key", key: "b", expectedAllowed: true, expectedRemaining: 1, expectedResetAfter: time.Minute},
+	{
+		name: "First request left the window", key: "a", elapsed: 20 * time.Second,
+		expectedAllowed: true, expectedRemaining: 0, expectedResetAfter: 20 * time.Second,
+	},
+}
@@ -0,0 +1,35 @@
+// coverage:ignore file
+// Test file
+package totp
+
+// The secret and SHA1 vectors of RFC 6238 Appendix B, truncated to 6 digits
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // base32 of "12345678901234567890"
+
+var rfcVectors = []struct {
+	unixTime int64
+	code     string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+var validateTests = []struct {
+	name          string
+	secret        string
+	code          string
+	offset        int64 // Seconds between the code being generated and validated
+	expectedValid bool
+}{
+	{name: "Current step", secret: rfcSecret, code: "287082", offset: 0, expectedValid: true},
+	{name: "Previous step", secret: rfcSecret, code: "287082", offset: 30, expectedValid: true},
+	{name: "Next step", secret: rfcSecret, code: "287082", offset: -30, expectedValid: true},
+	{name: "Outside the skew", secret: rfcSecret, code: "287082", offset: 60, expectedValid: false},
+	{name: "Wrong code", secret: rfcSecret, code: "123456", offset: 0, expectedValid: false},
+	{name: "Wrong length", secret: rfcSecret, code: "28708", offset: 0, expectedValid: false},
+	{name: "Invalid secret", secret: "not base32!", code: "287082", offset: 0, expectedValid: false},
+	{name: "Lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "287082", offset: 0, expectedValid: true},
+}
@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGenerateCode(t *testing.T) {
+	key, _ := encoding.DecodeString(rfcSecret)
+
+	for _, vector := range rfcVectors {
+		if code := generateCode(key, vector.unixTime/period); code != vector.code {
+			t.Errorf("expected the code at '%d' to be '%s', got '%s'", vector.unixTime, vector.code, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range validateTests {
+		t.Run(test.name, func(t *testing.T) {
+			step, valid := Validate(test.secret, test.code, time.Unix(59+test.offset, 0))
+			if valid != test.expectedValid {
+				t.Fatalf("expected valid to be '%t', got '%t'", test.expectedValid, valid)
+			}
+
+			if valid && step != 1 {
+				t.Errorf("expected the matching step to be '1', got '%d'", step)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Errorf("expected a base32 secret of %d bytes, got '%s'", secretSize, secret)
+	}
+
+	if code := generateCode(key, time.Now().Unix()/period); !validAt(secret, code, time.Now()) {
+		t.Errorf("expected the generated secret to validate its own code")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri, err := url.Parse(KeyURI("Starter", "jiewei@gmail.com", rfcSecret))
+	if err != nil {
+		t.Fatalf("KeyURI is not a valid URI: %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Starter:jiewei@gmail.com" {
+		t.Errorf("expected 'otpauth://totp/Starter:jiewei@gmail.com', got '%s'", uri.String())
+	}
+
+	query := uri.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Starter" || query.Get("digits") != "6" {
+		t.Errorf("unexpected query '%s'", uri.RawQuery)
+	}
+}
+
+func validAt(secret string, code string, t time.Time) bool {
+	_, valid := Validate(secret, code, t)
+	return valid
+}
@@ -0,0 +1,50 @@
+// coverage:ignore file
+// Test file
+package webauthn
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/descope/virtualwebauthn"
+	"github.com/google/uuid"
+)
+
+var testConfig = &entity.WebAuthnConfig{
+	RPID:          "localhost",
+	RPDisplayName: "starter-go-postgresql",
+	RPOrigins:     []string{"http://localhost:3030"},
+	Timeout:       5 * time.Minute,
+}
+
+var testRelyingParty = virtualwebauthn.RelyingParty{
+	Name:   "starter-go-postgresql",
+	ID:     "localhost",
+	Origin: "http://localhost:3030",
+}
+
+func newTestUser() *entity.User {
+	userUUID := uuid.New()
+	return &entity.User{UUID: &userUUID, FirstName: "jie", LastName: "wei", Email: "jiewei@gmail.com"}
+}
+
+var loginTests = []struct {
+	name           string
+	relyingParty   virtualwebauthn.RelyingParty
+	userVerified   bool
+	counter        uint32 // Counter reported by the authenticator after registering with a count of 1
+	otherUser      bool   // The user handle does not own the passkey
+	expectedStatus int
+}{
+	{name: "Valid passkey", relyingParty: testRelyingParty, userVerified: true, counter: 2, expectedStatus: 0},
+	{name: "Counter unsupported", relyingParty: testRelyingParty, userVerified: true, counter: 0, expectedStatus: 401},
+	{name: "Cloned authenticator", relyingParty: testRelyingParty, userVerified: true, counter: 1, expectedStatus: 401},
+	{name: "User not verified", relyingParty: testRelyingParty, userVerified: false, counter: 2, expectedStatus: 401},
+	{name: "Other user", relyingParty: testRelyingParty, userVerified: true, counter: 2, otherUser: true, expectedStatus: 401},
+	{name: "Wrong origin", relyingParty: virtualwebauthn.RelyingParty{
+		Name: "evil", ID: "localhost", Origin: "http://evil.com"},
+		userVerified: true, counter: 2, expectedStatus: 401},
+	{name: "Wrong relying party", relyingParty: virtualwebauthn.RelyingParty{
+		Name: "evil", ID: "evil.com", Origin: "http://localhost:3030"},
+		userVerified: true, counter: 2, expectedStatus: 401},
+}
@@ -0,0 +1,97 @@
+package webauthn
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// user adapts `entity.User` to the `webauthn.User` interface; the user handle is the 16 bytes of the user UUID.
+type user struct {
+	user        *entity.User
+	stored      []*entity.WebAuthnCredential
+	credentials []webauthn.Credential
+}
+
+func newUser(u *entity.User, stored []*entity.WebAuthnCredential) *user {
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, credential := range stored {
+		credentials = append(credentials, toCredential(credential))
+	}
+
+	return &user{user: u, stored: stored, credentials: credentials}
+}
+
+func (u *user) WebAuthnID() []byte {
+	return u.user.UUID[:]
+}
+
+func (u *user) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *user) WebAuthnDisplayName() string {
+	return strings.TrimSpace(u.user.FirstName + " " + u.user.LastName)
+}
+
+func (u *user) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func (u *user) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *user) findCredential(id []byte) *entity.WebAuthnCredential {
+	for _, credential := range u.stored {
+		if bytes.Equal(credential.ID, id) {
+			return credential
+		}
+	}
+
+	return nil
+}
+
+func toCredential(credential *entity.WebAuthnCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+	for _, transport := range credential.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+
+	return webauthn.Credential{
+		ID:              credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    credential.AAGUID,
+			SignCount: credential.SignCount,
+		},
+	}
+}
+
+func fromCredential(userUUID string, credential *webauthn.Credential) *entity.WebAuthnCredential {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	return &entity.WebAuthnCredential{
+		ID:              credential.ID,
+		UserUUID:        userUUID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
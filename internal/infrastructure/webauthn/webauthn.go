@@ -0,0 +1,208 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgWebAuthnError  = "webauthn error"
+	errMsgConfigError    = "webauthn config error"
+	errMsgCloneWarning   = "the sign count of the passkey went backwards; the authenticator may have been cloned"
+	errMsgInvalidUserID  = "invalid user handle"
+	errMsgNotConfigured  = "webauthn is not configured"
+	errMsgUnknownPasskey = "unknown passkey"
+)
+
+/*
+Passkeys replace the password, so both ceremonies require user verification
+and registration requires a discoverable credential that can log in without an email address.
+*/
+var authenticatorSelection = protocol.AuthenticatorSelection{
+	ResidentKey:        protocol.ResidentKeyRequirementRequired,
+	RequireResidentKey: protocol.ResidentKeyRequired(),
+	UserVerification:   protocol.VerificationRequired,
+}
+
+type WebAuthnService struct {
+	wa *webauthn.WebAuthn
+}
+
+func NewWebAuthnService(config *entity.WebAuthnConfig) service.WebAuthnService {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: config.Timeout, TimeoutUVD: config.Timeout}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:                   config.RPID,
+		RPDisplayName:          config.RPDisplayName,
+		RPOrigins:              config.RPOrigins,
+		AuthenticatorSelection: authenticatorSelection,
+		Timeouts:               webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgConfigError)
+		return &WebAuthnService{}
+	}
+
+	return &WebAuthnService{wa}
+}
+
+func (s *WebAuthnService) BeginRegistration(user *entity.User, credentials []*entity.WebAuthnCredential) (
+	*entity.WebAuthnCeremony, *restErr.RestErr) {
+	if s.wa == nil {
+		log.Error().Msg(errMsgNotConfigured)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	waUser := newUser(user, credentials)
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range waUser.credentials {
+		exclusions = append(exclusions, credential.Descriptor()) // Prevents registering the same authenticator twice
+	}
+
+	options, sessionData, err := s.wa.BeginRegistration(waUser, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return newCeremony(options, sessionData)
+}
+
+func (s *WebAuthnService) FinishRegistration(user *entity.User, credentials []*entity.WebAuthnCredential,
+	sessionData []byte, response []byte) (*entity.WebAuthnCredential, *restErr.RestErr) {
+	if s.wa == nil {
+		log.Error().Msg(errMsgNotConfigured)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	session, rErr := parseSessionData(sessionData)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	credential, err := s.wa.CreateCredential(newUser(user, credentials), *session, parsedResponse)
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	return fromCredential(user.UUID.String(), credential), nil
+}
+
+func (s *WebAuthnService) BeginLogin() (*entity.WebAuthnCeremony, *restErr.RestErr) {
+	if s.wa == nil {
+		log.Error().Msg(errMsgNotConfigured)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	options, sessionData, err := s.wa.BeginDiscoverableLogin()
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return newCeremony(options, sessionData)
+}
+
+func (s *WebAuthnService) FinishLogin(sessionData []byte, response []byte, findUser service.WebAuthnUserFinder) (
+	*entity.User, *entity.WebAuthnCredential, *restErr.RestErr) {
+	if s.wa == nil {
+		log.Error().Msg(errMsgNotConfigured)
+		return nil, nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	session, rErr := parseSessionData(sessionData)
+	if rErr != nil {
+		return nil, nil, rErr
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, nil, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	// Kept aside so that a database error of `findUser` is not reported as an invalid passkey
+	var findUserErr *restErr.RestErr
+	var waUser *user
+	credential, err := s.wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		userUUID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, errors.New(errMsgInvalidUserID)
+		}
+
+		u, credentials, rErr := findUser(userUUID.String())
+		if rErr != nil {
+			findUserErr = rErr
+			return nil, errors.New(rErr.Message)
+		}
+
+		waUser = newUser(u, credentials)
+		return waUser, nil
+	}, *session, parsedResponse)
+
+	if findUserErr != nil && findUserErr.Status >= 500 {
+		return nil, nil, findUserErr
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg(errMsgWebAuthnError)
+		return nil, nil, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Error().Str("user_uuid", waUser.user.UUID.String()).Msg(errMsgCloneWarning)
+		return nil, nil, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	stored := waUser.findCredential(credential.ID)
+	if stored == nil { // coverage:ignore
+		log.Error().Msg(errMsgUnknownPasskey)
+		return nil, nil, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidPasskey)
+	}
+
+	stored.SignCount = credential.Authenticator.SignCount
+	stored.BackupState = credential.Flags.BackupState
+	return waUser.user, stored, nil
+}
+
+func newCeremony(options interface{}, sessionData *webauthn.SessionData) (*entity.WebAuthnCeremony, *restErr.RestErr) {
+	encodedOptions, err := json.Marshal(options)
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	encodedSessionData, err := json.Marshal(sessionData)
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return &entity.WebAuthnCeremony{Options: encodedOptions, SessionData: encodedSessionData}, nil
+}
+
+func parseSessionData(sessionData []byte) (*webauthn.SessionData, *restErr.RestErr) {
+	session := &webauthn.SessionData{}
+	if err := json.Unmarshal(sessionData, session); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrJSONParseError)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+	}
+
+	return session, nil
+}
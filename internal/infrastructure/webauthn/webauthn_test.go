@@ -0,0 +1,177 @@
+package webauthn
+
+import (
+	"testing"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/descope/virtualwebauthn"
+)
+
+// register runs a registration ceremony with a software authenticator holding a new EC2 credential.
+func register(t *testing.T, s *WebAuthnService, user *entity.User) (
+	virtualwebauthn.Authenticator, virtualwebauthn.Credential, *entity.WebAuthnCredential) {
+	t.Helper()
+
+	ceremony, rErr := s.BeginRegistration(user, nil)
+	if rErr != nil {
+		t.Fatalf("BeginRegistration failed: %v", rErr)
+	}
+
+	options, err := virtualwebauthn.ParseAttestationOptions(string(ceremony.Options))
+	if err != nil {
+		t.Fatalf("ParseAttestationOptions failed: %v", err)
+	}
+
+	if options.RelyingPartyID != testConfig.RPID || options.UserName != user.Email {
+		t.Errorf("expected the options for '%s' at '%s', got '%s' at '%s'",
+			user.Email, testConfig.RPID, options.UserName, options.RelyingPartyID)
+	}
+
+	authenticator := virtualwebauthn.NewAuthenticatorWithOptions(
+		virtualwebauthn.AuthenticatorOptions{UserHandle: user.UUID[:]})
+	credential := virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2)
+	credential.Counter = 1
+	response := virtualwebauthn.CreateAttestationResponse(testRelyingParty, authenticator, credential, *options)
+
+	stored, rErr := s.FinishRegistration(user, nil, ceremony.SessionData, []byte(response))
+	if rErr != nil {
+		t.Fatalf("FinishRegistration failed: %v", rErr)
+	}
+
+	authenticator.AddCredential(credential)
+	return authenticator, credential, stored
+}
+
+func TestRegistration(t *testing.T) {
+	s := NewWebAuthnService(testConfig).(*WebAuthnService)
+	user := newTestUser()
+	_, credential, stored := register(t, s, user)
+
+	if string(stored.ID) != string(credential.ID) || stored.UserUUID != user.UUID.String() {
+		t.Errorf("expected the credential '%x' of '%s', got '%x' of '%s'",
+			credential.ID, user.UUID.String(), stored.ID, stored.UserUUID)
+	}
+
+	if stored.SignCount != 1 || len(stored.PublicKey) == 0 {
+		t.Errorf("expected a public key with a sign count of '1', got '%d'", stored.SignCount)
+	}
+
+	// A registered passkey is excluded so that the same authenticator is not registered twice
+	ceremony, _ := s.BeginRegistration(user, []*entity.WebAuthnCredential{stored})
+	options, _ := virtualwebauthn.ParseAttestationOptions(string(ceremony.Options))
+	if !credential.IsExcludedForAttestation(*options) {
+		t.Errorf("expected the registered passkey to be excluded")
+	}
+}
+
+func TestFinishRegistrationOfAnotherUser(t *testing.T) {
+	s := NewWebAuthnService(testConfig).(*WebAuthnService)
+	user := newTestUser()
+	ceremony, _ := s.BeginRegistration(user, nil)
+	options, _ := virtualwebauthn.ParseAttestationOptions(string(ceremony.Options))
+
+	authenticator := virtualwebauthn.NewAuthenticator()
+	credential := virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2)
+	response := virtualwebauthn.CreateAttestationResponse(testRelyingParty, authenticator, credential, *options)
+
+	_, rErr := s.FinishRegistration(newTestUser(), nil, ceremony.SessionData, []byte(response))
+	if rErr == nil || rErr.Status != 400 {
+		t.Errorf("expected a registration started by another user to fail with '400', got '%v'", rErr)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	for _, test := range loginTests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewWebAuthnService(testConfig).(*WebAuthnService)
+			user := newTestUser()
+			otherUser := newTestUser()
+			authenticator, credential, stored := register(t, s, user)
+
+			ceremony, rErr := s.BeginLogin()
+			if rErr != nil {
+				t.Fatalf("BeginLogin failed: %v", rErr)
+			}
+
+			options, err := virtualwebauthn.ParseAssertionOptions(string(ceremony.Options))
+			if err != nil {
+				t.Fatalf("ParseAssertionOptions failed: %v", err)
+			}
+
+			authenticator.Options.UserNotVerified = !test.userVerified
+			if test.otherUser {
+				authenticator.Options.UserHandle = otherUser.UUID[:]
+			}
+
+			credential.Counter = test.counter
+			response := virtualwebauthn.CreateAssertionResponse(test.relyingParty, authenticator, credential, *options)
+
+			loggedInUser, loggedInCredential, rErr := s.FinishLogin(ceremony.SessionData, []byte(response),
+				func(userUUID string) (*entity.User, []*entity.WebAuthnCredential, *restErr.RestErr) {
+					if userUUID == otherUser.UUID.String() {
+						return otherUser, nil, nil
+					}
+					return user, []*entity.WebAuthnCredential{stored}, nil
+				})
+
+			if test.expectedStatus != 0 {
+				if rErr == nil || rErr.Status != test.expectedStatus {
+					t.Fatalf("expected status '%d', got '%v'", test.expectedStatus, rErr)
+				}
+				return
+			}
+
+			if rErr != nil {
+				t.Fatalf("FinishLogin failed: %v", rErr)
+			}
+
+			if loggedInUser.UUID.String() != user.UUID.String() {
+				t.Errorf("expected the user '%s', got '%s'", user.UUID.String(), loggedInUser.UUID.String())
+			}
+
+			if loggedInCredential.SignCount != test.counter {
+				t.Errorf("expected the sign count to be '%d', got '%d'", test.counter, loggedInCredential.SignCount)
+			}
+		})
+	}
+}
+
+func TestFinishLoginUserLookupError(t *testing.T) {
+	s := NewWebAuthnService(testConfig).(*WebAuthnService)
+	authenticator, credential, _ := register(t, s, newTestUser())
+
+	ceremony, _ := s.BeginLogin()
+	options, _ := virtualwebauthn.ParseAssertionOptions(string(ceremony.Options))
+	credential.Counter = 2
+	response := virtualwebauthn.CreateAssertionResponse(testRelyingParty, authenticator, credential, *options)
+
+	_, _, rErr := s.FinishLogin(ceremony.SessionData, []byte(response),
+		func(userUUID string) (*entity.User, []*entity.WebAuthnCredential, *restErr.RestErr) {
+			return nil, nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		})
+
+	if rErr == nil || rErr.Status != 500 {
+		t.Errorf("expected the lookup error to be returned with '500', got '%v'", rErr)
+	}
+}
+
+func TestInvalidSessionData(t *testing.T) {
+	s := NewWebAuthnService(testConfig).(*WebAuthnService)
+
+	if _, rErr := s.FinishRegistration(newTestUser(), nil, []byte("invalid"), []byte("{}")); rErr == nil || rErr.Status != 400 {
+		t.Errorf("expected FinishRegistration to fail with '400', got '%v'", rErr)
+	}
+
+	if _, _, rErr := s.FinishLogin([]byte("invalid"), []byte("{}"), nil); rErr == nil || rErr.Status != 400 {
+		t.Errorf("expected FinishLogin to fail with '400', got '%v'", rErr)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	s := NewWebAuthnService(&entity.WebAuthnConfig{}) // RPID and origins are required
+
+	if _, rErr := s.BeginLogin(); rErr == nil || rErr.Status != 500 {
+		t.Errorf("expected an unconfigured service to fail with '500', got '%v'", rErr)
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"slices"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const errMsgPermissionDenied = "permission denied"
+
+/*
+`RequireRole` lets the request through if the user has any of the `roles`.
+It must run after `Deserializer`, which sets the roles of the access token on the `UserRecord`.
+*/
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+		if !ok {
+			return rejectWithoutUser(c)
+		}
+
+		for _, role := range roles {
+			if slices.Contains(userRecord.Roles, role) {
+				return c.Next()
+			}
+		}
+
+		log.Warn().Str("user_uuid", userRecord.UUID.String()).Strs("required_roles", roles).Msg(errMsgPermissionDenied)
+		err := restErr.NewForbiddenError(restErr.ErrMsgPermissionDenied)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+}
+
+/*
+`RequirePermission` lets the request through if the roles of the user grant every one of the `permissions`.
+Roles come from the access token while their permissions are looked up on each request,
+so that changing the permissions of a role applies immediately.
+Requests made with an API key also need every one of the `permissions` among the scopes of the key.
+*/
+func RequirePermission(rr rp.PostgresRoleRepository, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+		if !ok {
+			return rejectWithoutUser(c)
+		}
+
+		granted := []string{}
+		if len(userRecord.Roles) > 0 {
+			var err *restErr.RestErr
+			if granted, err = rr.GetPermissions(userRecord.Roles); err != nil {
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+		}
+
+		apiKey, withAPIKey := c.Locals("apiKey").(*dto.APIKeyPrincipal)
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) || (withAPIKey && !slices.Contains(apiKey.Scopes, permission)) {
+				log.Warn().Str("user_uuid", userRecord.UUID.String()).
+					Str("required_permission", permission).Msg(errMsgPermissionDenied)
+				err := restErr.NewForbiddenError(restErr.ErrMsgPermissionDenied)
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+/*
+rejectWithoutUser replies 403 to API clients, which have no roles and may only pass `RequireScope`,
+and 401 to anyone else, who has not logged in.
+*/
+func rejectWithoutUser(c *fiber.Ctx) error {
+	if clientPrincipal, ok := c.Locals("clientPrincipal").(*dto.ClientPrincipal); ok {
+		log.Warn().Str("client_id", clientPrincipal.ClientID).Msg(errMsgPermissionDenied)
+		err := restErr.NewForbiddenError(restErr.ErrMsgPermissionDenied)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	err := restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+}
+
+/*
+`RequireScope` lets an API client through if its access token was granted every one of the `scopes`,
+and checks anyone else like `RequirePermission`, so that a route can serve both API clients and users.
+*/
+func RequireScope(rr rp.PostgresRoleRepository, scopes ...string) fiber.Handler {
+	requirePermission := RequirePermission(rr, scopes...)
+	return func(c *fiber.Ctx) error {
+		clientPrincipal, ok := c.Locals("clientPrincipal").(*dto.ClientPrincipal)
+		if !ok {
+			return requirePermission(c)
+		}
+
+		for _, scope := range scopes {
+			if !slices.Contains(clientPrincipal.Scopes, scope) {
+				log.Warn().Str("client_id", clientPrincipal.ClientID).Str("required_scope", scope).
+					Msg(errMsgPermissionDenied)
+				err := restErr.NewForbiddenError(restErr.ErrMsgPermissionDenied)
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+/*
+newTestApp stands in for `Deserializer` by setting a `UserRecord` with the roles of the access token,
+the principal of an API key when `apiKeyScopes` is not nil,
+and the principal of an API client instead of a `UserRecord` when `clientScopes` is not nil.
+*/
+func newTestApp(authenticated bool, roles []string, apiKeyScopes []string, clientScopes []string,
+	handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if authenticated {
+			userUUID := uuid.New()
+			c.Locals("userRecord", &dto.UserRecord{UUID: &userUUID, Roles: roles})
+		}
+		if apiKeyScopes != nil {
+			c.Locals("apiKey", &dto.APIKeyPrincipal{ID: uuid.NewString(), Scopes: apiKeyScopes})
+		}
+		if clientScopes != nil {
+			c.Locals("clientPrincipal", &dto.ClientPrincipal{ClientID: "mock-api-client", Scopes: clientScopes})
+		}
+		return c.Next()
+	})
+
+	app.Get("/", handler, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	return app
+}
+
+func TestRequireRole(t *testing.T) {
+	for _, test := range requireRoleTests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApp(test.authenticated, test.userRoles, nil, test.clientScopes, RequireRole(test.requiredRoles...))
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatalf("RequireRole middleware test failed: %v", err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	for _, test := range requirePermissionTests {
+		t.Run(test.name, func(t *testing.T) {
+			roleRepo := &mockRoleRepository{fail: test.failingRepository}
+			app := newTestApp(test.authenticated, test.userRoles, test.apiKeyScopes, test.clientScopes,
+				RequirePermission(roleRepo, test.requiredPermissions...))
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatalf("RequirePermission middleware test failed: %v", err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+
+			if roleRepo.lookups != test.expectedLookups {
+				t.Errorf("Expected '%d' permission lookups but got '%d'", test.expectedLookups, roleRepo.lookups)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	for _, test := range requireScopeTests {
+		t.Run(test.name, func(t *testing.T) {
+			roleRepo := &mockRoleRepository{}
+			app := newTestApp(test.clientScopes == nil, test.userRoles, nil, test.clientScopes,
+				RequireScope(roleRepo, test.requiredScopes...))
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatalf("RequireScope middleware test failed: %v", err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+
+			if roleRepo.lookups != test.expectedLookups {
+				t.Errorf("Expected '%d' permission lookups but got '%d'", test.expectedLookups, roleRepo.lookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import "github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+
+var mockRolePermissions = map[string][]string{
+	entity.RoleAdmin: {entity.PermissionUsersRead, entity.PermissionUsersWrite, entity.PermissionUsersDelete},
+	"support":        {entity.PermissionUsersRead},
+}
+
+var requireRoleTests = []struct {
+	name           string
+	userRoles      []string
+	requiredRoles  []string
+	clientScopes   []string
+	authenticated  bool
+	expectedStatus int
+}{
+	{name: "Has the role", userRoles: []string{"admin"}, requiredRoles: []string{"admin"},
+		authenticated: true, expectedStatus: 200},
+	{name: "Has one of the roles", userRoles: []string{"support"}, requiredRoles: []string{"admin", "support"},
+		authenticated: true, expectedStatus: 200},
+	{name: "Missing the role", userRoles: []string{"support"}, requiredRoles: []string{"admin"},
+		authenticated: true, expectedStatus: 403},
+	{name: "No roles", userRoles: nil, requiredRoles: []string{"admin"},
+		authenticated: true, expectedStatus: 403},
+	{name: "Not authenticated", userRoles: nil, requiredRoles: []string{"admin"},
+		authenticated: false, expectedStatus: 401},
+	{name: "API client", userRoles: nil, requiredRoles: []string{"admin"},
+		clientScopes: []string{entity.PermissionUsersRead}, authenticated: false, expectedStatus: 403},
+}
+
+var requirePermissionTests = []struct {
+	name                string
+	userRoles           []string
+	requiredPermissions []string
+	apiKeyScopes        []string
+	clientScopes        []string
+	authenticated       bool
+	failingRepository   bool
+	expectedStatus      int
+	expectedLookups     int
+}{
+	{name: "Granted by the role", userRoles: []string{"admin"},
+		requiredPermissions: []string{entity.PermissionUsersDelete},
+		authenticated:       true, expectedStatus: 200, expectedLookups: 1},
+	{name: "Granted by all the roles together", userRoles: []string{"support", "admin"},
+		requiredPermissions: []string{entity.PermissionUsersRead, entity.PermissionUsersWrite},
+		authenticated:       true, expectedStatus: 200, expectedLookups: 1},
+	{name: "Missing one of the permissions", userRoles: []string{"support"},
+		requiredPermissions: []string{entity.PermissionUsersRead, entity.PermissionUsersWrite},
+		authenticated:       true, expectedStatus: 403, expectedLookups: 1},
+	{name: "No roles", userRoles: nil,
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		authenticated:       true, expectedStatus: 403, expectedLookups: 0},
+	{name: "Not authenticated", userRoles: nil,
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		authenticated:       false, expectedStatus: 401, expectedLookups: 0},
+	{name: "Repository error", userRoles: []string{"admin"},
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		authenticated:       true, failingRepository: true, expectedStatus: 500, expectedLookups: 1},
+	{name: "API key with the scope", userRoles: []string{"admin"},
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		apiKeyScopes:        []string{entity.PermissionUsersRead},
+		authenticated:       true, expectedStatus: 200, expectedLookups: 1},
+	{name: "API key without the scope", userRoles: []string{"admin"},
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		apiKeyScopes:        []string{},
+		authenticated:       true, expectedStatus: 403, expectedLookups: 1},
+	{name: "API key scope without the role", userRoles: []string{"support"},
+		requiredPermissions: []string{entity.PermissionUsersWrite},
+		apiKeyScopes:        []string{entity.PermissionUsersWrite},
+		authenticated:       true, expectedStatus: 403, expectedLookups: 1},
+	{name: "API client with the scope", userRoles: nil,
+		requiredPermissions: []string{entity.PermissionUsersRead},
+		clientScopes:        []string{entity.PermissionUsersRead},
+		authenticated:       false, expectedStatus: 403, expectedLookups: 0},
+}
+
+var requireScopeTests = []struct {
+	name            string
+	clientScopes    []string
+	userRoles       []string
+	requiredScopes  []string
+	expectedStatus  int
+	expectedLookups int
+}{
+	{name: "API client with the scope", clientScopes: []string{entity.PermissionUsersRead},
+		requiredScopes: []string{entity.PermissionUsersRead}, expectedStatus: 200, expectedLookups: 0},
+	{name: "API client without the scope", clientScopes: []string{"reports:read"},
+		requiredScopes: []string{entity.PermissionUsersRead}, expectedStatus: 403, expectedLookups: 0},
+	{name: "API client missing one of the scopes", clientScopes: []string{entity.PermissionUsersRead},
+		requiredScopes: []string{entity.PermissionUsersRead, entity.PermissionUsersWrite},
+		expectedStatus: 403, expectedLookups: 0},
+	{name: "User with the permission", userRoles: []string{"support"},
+		requiredScopes: []string{entity.PermissionUsersRead}, expectedStatus: 200, expectedLookups: 1},
+	{name: "User without the permission", userRoles: []string{"support"},
+		requiredScopes: []string{entity.PermissionUsersWrite}, expectedStatus: 403, expectedLookups: 1},
+}
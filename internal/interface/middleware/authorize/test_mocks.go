@@ -0,0 +1,40 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+// mockRoleRepository grants the permissions of `mockRolePermissions` and counts the lookups.
+type mockRoleRepository struct {
+	lookups int
+	fail    bool
+}
+
+func (m *mockRoleRepository) SaveRole(role *entity.Role) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRoleRepository) AssignRole(userUUID string, roleName string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRoleRepository) GetUserRoles(userUUID string) ([]string, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) GetPermissions(roleNames []string) ([]string, *restErr.RestErr) {
+	m.lookups++
+	if m.fail {
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	permissions := []string{}
+	for _, roleName := range roleNames {
+		permissions = append(permissions, mockRolePermissions[roleName]...)
+	}
+
+	return permissions, nil
+}
@@ -1,22 +1,40 @@
 package middleware
 
 import (
+	"slices"
 	"strings"
+	"time"
 
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
 	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
 	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
 	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
 	"github.com/gofiber/fiber/v2"
 )
 
+/*
+Deserializer sets the `userRecord` of the user of the access token, or the `clientPrincipal` of an API client
+whose token from the client credentials grant has the client itself as its subject.
+The client is looked up on each request so that deleting it revokes its tokens at once.
+The tokens that users delegate to OAuth clients are only accepted by `/oauth/userinfo`.
+A personal API key in the `X-API-Key` header or in `Authorization: ApiKey ...` is accepted instead of a JWT.
+*/
 func Deserializer(
 	r r.RedisUserRepository,
 	ts domainSvc.TokenService,
 	us appSvc.UserService,
+	kr rp.PostgresAPIKeyRepository,
+	ar rp.PostgresAPIClientRepository,
 ) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if apiKey := apiKeyFromRequest(c); apiKey != "" {
+			return deserializeAPIKey(c, apiKey, us, kr)
+		}
+
 		var access_token string
 		authorization := c.Get("Authorization")
 
@@ -31,18 +49,28 @@ func Deserializer(
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "fail", "error": err})
 		}
 
-		tokenClaims, err := ts.ValidateToken(access_token, us.GetJWTConfig().AccessTokenPublicKey)
+		tokenClaims, err := ts.ValidateToken(access_token, us.GetJWTConfig().AccessTokenKeyRing)
 		if err != nil {
 			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 		}
 
-		userUuid, errGetTokenUUID := r.GetUserUUID(tokenClaims.TokenUUID)
-		if errGetTokenUUID != nil {
-			return c.Status(fiber.StatusUnauthorized).
-				JSON(fiber.Map{"status": "fail", "message": errGetTokenUUID})
+		if tokenClaims.ClientID != "" {
+			if tokenClaims.UserUUID != tokenClaims.ClientID {
+				err := restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+
+			clientPrincipal, err := apiClientPrincipal(tokenClaims, ar)
+			if err != nil {
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+
+			c.Locals("clientPrincipal", clientPrincipal)
+			c.Locals("clientID", tokenClaims.ClientID)
+			return c.Next()
 		}
 
-		u, err := us.GetUserByUUID(userUuid)
+		u, err := userOfAccessToken(tokenClaims, r, us)
 		if err != nil {
 			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 		}
@@ -54,6 +82,7 @@ func Deserializer(
 			Email:     u.Email,
 			CreatedAt: u.CreatedAt,
 			UpdatedAt: u.UpdatedAt,
+			Roles:     tokenClaims.Roles,
 		}
 
 		c.Locals("userRecord", userRecord)
@@ -62,3 +91,148 @@ func Deserializer(
 		return c.Next()
 	}
 }
+
+// apiClientPrincipal keeps only the scopes of the token that the API client is still allowed.
+func apiClientPrincipal(tokenClaims *entity.Token, ar rp.PostgresAPIClientRepository) (
+	*dto.ClientPrincipal, *restErr.RestErr) {
+	client, err := ar.GetAPIClient(tokenClaims.ClientID)
+	if err != nil {
+		if err.Status == fiber.StatusNotFound {
+			return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+		}
+
+		return nil, err
+	}
+
+	scopes := []string{}
+	for _, scope := range tokenClaims.Scope {
+		if slices.Contains(client.Scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return &dto.ClientPrincipal{ClientID: client.ClientID, Scopes: scopes}, nil
+}
+
+/*
+LoggedInUser resolves the user of the session cookie like the `Deserializer`, for the handlers that are not behind it,
+e.g. `/oauth/authorize` which sends the users who are not logged in to the login page instead.
+*/
+func LoggedInUser(c *fiber.Ctx, r r.RedisUserRepository, ts domainSvc.TokenService,
+	us appSvc.UserService) (*entity.User, *restErr.RestErr) {
+	accessToken := c.Cookies("access_token")
+	if accessToken == "" {
+		return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	tokenClaims, err := ts.ValidateToken(accessToken, us.GetJWTConfig().AccessTokenKeyRing)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cookie only ever holds a session token, never one issued to a client
+	if tokenClaims.ClientID != "" {
+		return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	return userOfAccessToken(tokenClaims, r, us)
+}
+
+// userOfAccessToken resolves the user of a session access token, which is only valid while it is kept in Redis.
+func userOfAccessToken(tokenClaims *entity.Token, r r.RedisUserRepository,
+	us appSvc.UserService) (*entity.User, *restErr.RestErr) {
+	userUuid, err := r.GetUserUUID(tokenClaims.TokenUUID)
+	if err != nil {
+		return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	u, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.DisabledAt != nil {
+		return nil, restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
+	}
+
+	return u, nil
+}
+
+// RequireUser must run after `Deserializer` on the routes that act on the account of a user, which API clients have not.
+func RequireUser(c *fiber.Ctx) error {
+	if _, ok := c.Locals("userRecord").(*dto.UserRecord); !ok {
+		err := restErr.NewForbiddenError(restErr.ErrMsgUserOnly)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Next()
+}
+
+// RequireSession must run after `Deserializer` on the routes that a leaked API key must not reach, e.g. creating keys.
+func RequireSession(c *fiber.Ctx) error {
+	if _, ok := c.Locals("apiKey").(*dto.APIKeyPrincipal); ok {
+		err := restErr.NewForbiddenError(restErr.ErrMsgSessionRequired)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Next()
+}
+
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if apiKey := c.Get("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+
+	if apiKey, ok := strings.CutPrefix(c.Get("Authorization"), "ApiKey "); ok {
+		return apiKey
+	}
+
+	return ""
+}
+
+/*
+deserializeAPIKey sets the `userRecord` of the owner of the key with their current roles, as there is no token
+that carries them, and the `apiKey` principal whose scopes `RequirePermission` checks on top of the roles.
+*/
+func deserializeAPIKey(c *fiber.Ctx, apiKey string, us appSvc.UserService, kr rp.PostgresAPIKeyRepository) error {
+	key, err := kr.GetAPIKeyByHash(randtoken.Hash(apiKey))
+	if err != nil && err.Status != fiber.StatusNotFound {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err != nil || key.IsExpired(time.Now()) {
+		err := restErr.NewUnauthorizedError(restErr.ErrMsgInvalidAPIKey)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	u, err := us.GetUserByUUID(key.UserUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if u.DisabledAt != nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	roles, err := us.GetUserRoles(key.UserUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// The request goes on without the last use being recorded, which the repository logs
+	kr.UpdateAPIKeyLastUsed(key.ID, c.IP())
+
+	c.Locals("userRecord", &dto.UserRecord{
+		UUID:      u.UUID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		Roles:     roles,
+	})
+	c.Locals("apiKey", &dto.APIKeyPrincipal{ID: key.ID, Scopes: key.Scopes})
+
+	return c.Next()
+}
@@ -22,10 +22,11 @@ func TestDeserializer(t *testing.T) {
 
 	redisUserRepo := &mockRedisUserRepository{mid: mockUUIDs}
 	tokenService := &mockTokenService{mid: mockUUIDs}
-	userService := &mockUserService{}
+	userService := &mockUserService{mid: mockUUIDs}
 
 	app := fiber.New()
-	app.Use(Deserializer(redisUserRepo, tokenService, userService))
+	app.Use(Deserializer(redisUserRepo, tokenService, userService, &mockAPIKeyRepository{mid: mockUUIDs},
+		&mockAPIClientRepository{}))
 	app.Get("/", func(c *fiber.Ctx) error {
 		userRecord := c.Locals("userRecord").(*dto.UserRecord)
 		accessTokenUUID := c.Locals("accessTokenUUID").(string)
@@ -78,15 +79,18 @@ func TestDeserializer(t *testing.T) {
 					t.Error("Expected userRecord but got nil")
 				}
 
+				if len(respBody.UserRecord.Roles) != 1 || respBody.UserRecord.Roles[0] != mockRoles[0] {
+					t.Errorf("Expected the roles of the access token '%v', got '%v'", mockRoles, respBody.UserRecord.Roles)
+				}
+
 				if respBody.AccessTokenUUID == "" {
 					t.Error("Expected accessTokenUUID but got an empty string")
 				}
 			}
 
 			if test.hasError {
-				// Ensure response status code is 401 Unauthorized
-				if resp.StatusCode != fiber.StatusUnauthorized {
-					t.Errorf("Expected status '%d' but got '%d'", fiber.StatusUnauthorized, resp.StatusCode)
+				if resp.StatusCode != test.expectedStatus {
+					t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
 				}
 
 				var respBody map[string]interface{}
@@ -103,3 +107,268 @@ func TestDeserializer(t *testing.T) {
 		})
 	}
 }
+
+func TestDeserializerClientToken(t *testing.T) {
+	mockUUIDs := mockUUIDs{}
+	mockUUIDs.initializeMockUUIDEntities()
+
+	app := fiber.New()
+	app.Use(Deserializer(&mockRedisUserRepository{mid: mockUUIDs}, &mockTokenService{mid: mockUUIDs},
+		&mockUserService{mid: mockUUIDs}, &mockAPIKeyRepository{mid: mockUUIDs},
+		&mockAPIClientRepository{}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("userRecord").(*dto.UserRecord); ok {
+			t.Error("Expected no userRecord for an API client")
+		}
+
+		return c.JSON(fiber.Map{
+			"clientPrincipal": c.Locals("clientPrincipal").(*dto.ClientPrincipal),
+			"clientID":        c.Locals("clientID").(string),
+		})
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+mockClientAccessToken)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Deserializer middleware test failed: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status '%d' but got '%d'", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var respBody struct {
+		ClientPrincipal *dto.ClientPrincipal `json:"clientPrincipal"`
+		ClientID        string               `json:"clientID"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	if respBody.ClientPrincipal == nil || respBody.ClientPrincipal.ClientID != mockClientID {
+		t.Errorf("Expected the client principal of '%s', got '%v'", mockClientID, respBody.ClientPrincipal)
+	}
+
+	if len(respBody.ClientPrincipal.Scopes) != 1 || respBody.ClientPrincipal.Scopes[0] != mockClientScopes[0] {
+		t.Errorf("Expected the scopes '%v', got '%v'", mockClientScopes, respBody.ClientPrincipal.Scopes)
+	}
+
+	if respBody.ClientID != mockClientID {
+		t.Errorf("Expected clientID '%s', got '%s'", mockClientID, respBody.ClientID)
+	}
+
+	// The tokens that users delegate to OAuth clients, and those of deleted clients, are not accepted
+	for _, token := range []string{mockDelegatedToken, mockDeletedClientAccessToken} {
+		req = httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err = app.Test(req)
+		if err != nil {
+			t.Fatalf("Deserializer middleware test failed: %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status '%d' for '%s' but got '%d'", fiber.StatusUnauthorized, token, resp.StatusCode)
+		}
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	mockUUIDs := mockUUIDs{}
+	mockUUIDs.initializeMockUUIDEntities()
+
+	app := fiber.New()
+	app.Use(Deserializer(&mockRedisUserRepository{mid: mockUUIDs}, &mockTokenService{mid: mockUUIDs},
+		&mockUserService{mid: mockUUIDs}, &mockAPIKeyRepository{mid: mockUUIDs},
+		&mockAPIClientRepository{}), RequireUser)
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+	})
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "User", token: "mockAccessToken", expectedStatus: fiber.StatusOK},
+		{name: "API client", token: mockClientAccessToken, expectedStatus: fiber.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("RequireUser middleware test failed: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDeserializerAPIKey(t *testing.T) {
+	mockUUIDs := mockUUIDs{}
+	mockUUIDs.initializeMockUUIDEntities()
+
+	for _, test := range apiKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			apiKeyRepo := &mockAPIKeyRepository{mid: mockUUIDs}
+			app := fiber.New()
+			app.Use(Deserializer(&mockRedisUserRepository{mid: mockUUIDs}, &mockTokenService{mid: mockUUIDs},
+				&mockUserService{mid: mockUUIDs}, apiKeyRepo, &mockAPIClientRepository{}))
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.JSON(fiber.Map{
+					"userRecord": c.Locals("userRecord").(*dto.UserRecord),
+					"apiKey":     c.Locals("apiKey").(*dto.APIKeyPrincipal),
+				})
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(test.header, test.value)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Deserializer middleware test failed: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+
+			if test.expectedStatus != fiber.StatusOK {
+				if apiKeyRepo.lastUsedIP != "" {
+					t.Errorf("Expected the use of a rejected API key not to be recorded")
+				}
+				return
+			}
+
+			var respBody struct {
+				UserRecord *dto.UserRecord      `json:"userRecord"`
+				APIKey     *dto.APIKeyPrincipal `json:"apiKey"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+
+			if respBody.UserRecord == nil || respBody.UserRecord.UUID.String() != mockUUIDs.mockUserUUID.String() {
+				t.Errorf("Expected the userRecord of the owner of the API key, got '%v'", respBody.UserRecord)
+			}
+
+			if len(respBody.UserRecord.Roles) != 1 || respBody.UserRecord.Roles[0] != mockRoles[0] {
+				t.Errorf("Expected the current roles of the user '%v', got '%v'", mockRoles, respBody.UserRecord.Roles)
+			}
+
+			if respBody.APIKey == nil || len(respBody.APIKey.Scopes) != 1 ||
+				respBody.APIKey.Scopes[0] != mockAPIKeyScopes[0] {
+				t.Errorf("Expected the API key with the scopes '%v', got '%v'", mockAPIKeyScopes, respBody.APIKey)
+			}
+
+			if apiKeyRepo.lastUsedIP != mockIP {
+				t.Errorf("Expected the last use from '%s' to be recorded, got '%s'", mockIP, apiKeyRepo.lastUsedIP)
+			}
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	mockUUIDs := mockUUIDs{}
+	mockUUIDs.initializeMockUUIDEntities()
+
+	app := fiber.New()
+	app.Use(Deserializer(&mockRedisUserRepository{mid: mockUUIDs}, &mockTokenService{mid: mockUUIDs},
+		&mockUserService{mid: mockUUIDs}, &mockAPIKeyRepository{mid: mockUUIDs},
+		&mockAPIClientRepository{}), RequireSession)
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+	})
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{name: "Access token", header: "Authorization", value: "Bearer mockAccessToken", expectedStatus: 200},
+		{name: "API key", header: "X-API-Key", value: mockAPIKey, expectedStatus: 403},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(test.header, test.value)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("RequireSession middleware test failed: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestLoggedInUser(t *testing.T) {
+	mockUUIDs := mockUUIDs{}
+	mockUUIDs.initializeMockUUIDEntities()
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		user, err := LoggedInUser(c, &mockRedisUserRepository{mid: mockUUIDs}, &mockTokenService{mid: mockUUIDs},
+			&mockUserService{mid: mockUUIDs})
+		if err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		return c.JSON(fiber.Map{"uuid": user.UUID.String()})
+	})
+
+	tests := []struct {
+		name           string
+		header         string
+		cookieValue    string
+		expectedStatus int
+	}{
+		{name: "Session cookie", cookieValue: "mockAccessToken", expectedStatus: 200},
+		{name: "No session cookie", header: "Bearer mockAccessToken", expectedStatus: 401},
+		{name: "Token of an API client", cookieValue: mockClientAccessToken, expectedStatus: 401},
+		{name: "Disabled user", cookieValue: mockDisabledAccessToken, expectedStatus: 403},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+
+			if test.cookieValue != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: test.cookieValue})
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("LoggedInUser test failed: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -2,30 +2,64 @@
 // Test file
 package middleware
 
-const mockExpiresIn = int64(3600)
+const (
+	mockExpiresIn                = int64(3600)
+	mockDisabledAccessToken      = "mockDisabledAccessToken"
+	mockClientAccessToken        = "mockClientAccessToken"
+	mockDelegatedToken           = "mockDelegatedToken"
+	mockClientID                 = "mock-api-client"
+	mockDeletedClientAccessToken = "mockDeletedClientAccessToken"
+	mockAPIKey                   = "ak_mockAPIKey"
+	mockExpiredAPIKey            = "ak_mockExpiredAPIKey"
+	mockIP                       = "0.0.0.0"
+)
+
+var (
+	mockRoles        = []string{"admin"}
+	mockClientScopes = []string{"reports:read"}
+	mockTokenScopes  = []string{"reports:read", "reports:write"} // The client has since lost `reports:write`
+	mockAPIKeyScopes = []string{"users:read"}
+)
 
 var deserializerTests = []struct {
 	name           string
 	hasError       bool
 	expectedErrMsg string
+	expectedStatus int
 	header         string
 	cookieName     string
 	cookieValue    string
 }{
 	{
 		name: "Authorization header", hasError: true, expectedErrMsg: "please login again",
-		header: "Bearer mockInvalidBearerToken",
+		expectedStatus: 401, header: "Bearer mockInvalidBearerToken",
 	},
 	{
 		name: "No authorization header and empty cookie value", hasError: true, expectedErrMsg: "please login again",
-		header: "", cookieName: "access_token", cookieValue: "",
+		expectedStatus: 401, header: "", cookieName: "access_token", cookieValue: "",
 	},
 	{
 		name: "No authorization header and no cookie", hasError: true, expectedErrMsg: "please login again",
-		header: "", cookieName: "", cookieValue: "",
+		expectedStatus: 401, header: "", cookieName: "", cookieValue: "",
 	},
 	{
 		name: "Authorization cookie", hasError: false,
 		cookieName: "access_token", cookieValue: "mockAccessToken",
 	},
+	{
+		name: "Disabled user", hasError: true, expectedErrMsg: "this account has been disabled",
+		expectedStatus: 403, cookieName: "access_token", cookieValue: mockDisabledAccessToken,
+	},
+}
+
+var apiKeyTests = []struct {
+	name           string
+	header         string
+	value          string
+	expectedStatus int
+}{
+	{name: "X-API-Key header", header: "X-API-Key", value: mockAPIKey, expectedStatus: 200},
+	{name: "ApiKey authorization", header: "Authorization", value: "ApiKey " + mockAPIKey, expectedStatus: 200},
+	{name: "Expired API key", header: "X-API-Key", value: mockExpiredAPIKey, expectedStatus: 401},
+	{name: "Unknown API key", header: "X-API-Key", value: "ak_unknown", expectedStatus: 401},
 }
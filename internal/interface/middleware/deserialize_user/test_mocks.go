@@ -9,19 +9,26 @@ import (
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	errConst "github.com/DarrelA/starter-go-postgresql/internal/error"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
 	"github.com/google/uuid"
 )
 
 type mockUUIDs struct {
-	mockUserUUID  *uuid.UUID
-	mockTokenUUID *uuid.UUID
+	mockUserUUID          *uuid.UUID
+	mockTokenUUID         *uuid.UUID
+	mockDisabledUserUUID  *uuid.UUID
+	mockDisabledTokenUUID *uuid.UUID
 }
 
 func (m *mockUUIDs) initializeMockUUIDEntities() {
 	mockUserUUID, _ := uuid.NewV7()
 	mockTokenUUID, _ := uuid.NewV7()
+	mockDisabledUserUUID, _ := uuid.NewV7()
+	mockDisabledTokenUUID, _ := uuid.NewV7()
 	m.mockUserUUID = &mockUserUUID
 	m.mockTokenUUID = &mockTokenUUID
+	m.mockDisabledUserUUID = &mockDisabledUserUUID
+	m.mockDisabledTokenUUID = &mockDisabledTokenUUID
 }
 
 type mockRedisUserRepository struct{ mid mockUUIDs }
@@ -31,6 +38,10 @@ func (m *mockRedisUserRepository) SetUserUUID(tokenUUID string, userUUID string,
 }
 
 func (m *mockRedisUserRepository) GetUserUUID(tokenUUID string) (string, *restErr.RestErr) {
+	if tokenUUID == m.mid.mockDisabledTokenUUID.String() {
+		return m.mid.mockDisabledUserUUID.String(), nil
+	}
+
 	return m.mid.mockUserUUID.String(), nil
 }
 
@@ -38,14 +49,109 @@ func (m *mockRedisUserRepository) DelUserUUID(tokenUUID string, accessTokenUUID
 	return 1, nil
 }
 
+func (m *mockRedisUserRepository) SetTokenFamily(familyID string, tokenUUID string, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetTokenFamily(tokenUUID string) (string, *restErr.RestErr) {
+	return "", nil
+}
+
+func (m *mockRedisUserRepository) ConsumeRefreshToken(tokenUUID string) (string, *restErr.RestErr) {
+	return m.mid.mockUserUUID.String(), nil
+}
+
+func (m *mockRedisUserRepository) DelTokenFamily(familyID string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) SetSession(session *entity.Session, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetSession(familyID string) (*entity.Session, *restErr.RestErr) {
+	return &entity.Session{ID: familyID, UserUUID: m.mid.mockUserUUID.String()}, nil
+}
+
+func (m *mockRedisUserRepository) GetSessions(userUUID string) ([]*entity.Session, *restErr.RestErr) {
+	return []*entity.Session{}, nil
+}
+
+func (m *mockRedisUserRepository) DelUserSessions(userUUID string, exceptFamilyIDs ...string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) SetEmailVerification(userUUID string, tokenUUID string, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumeEmailVerification(userUUID string, tokenUUID string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) SetPasswordReset(userUUID string, tokenHash string, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumePasswordReset(tokenHash string) (string, *restErr.RestErr) {
+	return m.mid.mockUserUUID.String(), nil
+}
+
+func (m *mockRedisUserRepository) SetMFAChallenge(tokenHash string, userUUID string, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetMFAChallenge(tokenHash string) (string, *restErr.RestErr) {
+	return m.mid.mockUserUUID.String(), nil
+}
+
+func (m *mockRedisUserRepository) AddFailedMFAAttempt(tokenHash string) (int64, *restErr.RestErr) {
+	return 1, nil
+}
+
+func (m *mockRedisUserRepository) ConsumeMFAChallenge(tokenHash string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) UseTOTPStep(userUUID string, step int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) SetWebAuthnSession(key string, sessionData []byte, expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumeWebAuthnSession(key string) ([]byte, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockRedisUserRepository) SetOAuth2State(stateHash string, state *entity.OAuth2State,
+	expiresIn int64) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumeOAuth2State(stateHash string) (*entity.OAuth2State, *restErr.RestErr) {
+	return nil, nil
+}
+
 type mockTokenService struct{ mid mockUUIDs }
 
-func (m *mockTokenService) CreateToken(userUUID string, ttl time.Duration, privateKey string) (
+func (m *mockTokenService) CreateToken(userUUID string, scope []string, roles []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockTokenService) CreateClientToken(subject string, clientID string, scope []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockTokenService) CreateIDToken(idToken *entity.IDToken, ttl time.Duration, keyRing *entity.JWTKeyRing) (
 	*entity.Token, *restErr.RestErr) {
 	return nil, nil
 }
 
-func (m *mockTokenService) ValidateToken(token string, publicKey string) (
+func (m *mockTokenService) ValidateToken(token string, keyRing *entity.JWTKeyRing) (
 	*entity.Token, *restErr.RestErr) {
 	// Simulate invalid token
 	if token == "" || token == "mockInvalidBearerToken" {
@@ -54,18 +160,40 @@ func (m *mockTokenService) ValidateToken(token string, publicKey string) (
 	}
 
 	// Simulate valid token
+	tokenUUID := m.mid.mockTokenUUID.String()
+	if token == mockDisabledAccessToken {
+		tokenUUID = m.mid.mockDisabledTokenUUID.String()
+	}
+
 	expiresIn := mockExpiresIn
 	mockToken := &entity.Token{
 		Token:     &token,
-		TokenUUID: m.mid.mockTokenUUID.String(),
+		TokenUUID: tokenUUID,
 		UserUUID:  m.mid.mockUserUUID.String(),
 		ExpiresIn: &expiresIn,
+		Roles:     mockRoles,
+	}
+
+	// Simulate a token of an API client and a token that a user delegated to an OAuth client
+	switch token {
+	case mockClientAccessToken:
+		mockToken.UserUUID, mockToken.ClientID, mockToken.Scope, mockToken.Roles =
+			mockClientID, mockClientID, mockTokenScopes, nil
+	case mockDeletedClientAccessToken:
+		mockToken.UserUUID, mockToken.ClientID, mockToken.Scope, mockToken.Roles =
+			"mock-deleted-client", "mock-deleted-client", mockClientScopes, nil
+	case mockDelegatedToken:
+		mockToken.ClientID, mockToken.Scope = mockClientID, mockClientScopes
 	}
 
 	return mockToken, nil
 }
 
-type mockUserService struct{}
+func (m *mockTokenService) GetJWKS(keyRing *entity.JWTKeyRing) (*entity.JWKSet, *restErr.RestErr) {
+	return &entity.JWKSet{}, nil
+}
+
+type mockUserService struct{ mid mockUUIDs }
 
 func (m *mockUserService) GetJWTConfig() *entity.JWTConfig { return &entity.JWTConfig{} }
 
@@ -89,5 +217,156 @@ func (m *mockUserService) GetUserByUUID(userUuid string) (*entity.User, *restErr
 		UpdatedAt: time.Now(),
 	}
 
+	// Simulate a user disabled by an admin
+	if userUuid == m.mid.mockDisabledUserUUID.String() {
+		disabledAt := time.Now()
+		user.DisabledAt = &disabledAt
+	}
+
 	return user, nil
 }
+
+func (m *mockUserService) FindUserByEmail(email string) (*entity.User, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockUserService) FindOrCreateExternalUser(externalUser *entity.ExternalUser) (
+	*entity.User, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockUserService) LinkExternalIdentity(userUuid string, externalUser *entity.ExternalUser) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) UnlinkIdentity(userUuid string, provider string, subject string,
+	hasPasskeys bool) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) GetIdentities(userUuid string) ([]dto.IdentityRecord, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockUserService) VerifyEmail(userUuid string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) ResetPassword(userUuid string, newPassword string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) ChangePassword(userUuid string, payload dto.ChangePasswordInput) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) UpdateUser(userUuid string, payload dto.UpdateUserInput) (*entity.User, *restErr.RestErr) {
+	return nil, nil
+}
+
+func (m *mockUserService) DeleteAccount(userUuid string, payload dto.DeleteAccountInput,
+	loggedInAt time.Time) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) PurgeDeletedUsers() (int64, *restErr.RestErr) {
+	return 0, nil
+}
+
+func (m *mockUserService) ExportUser(userUuid string) (*dto.UserExport, *restErr.RestErr) {
+	return &dto.UserExport{}, nil
+}
+
+func (m *mockUserService) EnrollTOTP(userUuid string) (*dto.TOTPEnrollment, *restErr.RestErr) {
+	return &dto.TOTPEnrollment{}, nil
+}
+
+func (m *mockUserService) ConfirmTOTP(userUuid string, code string) ([]string, *restErr.RestErr) {
+	return []string{}, nil
+}
+
+func (m *mockUserService) ValidateTOTP(userUuid string, code string) (int64, *restErr.RestErr) {
+	return 0, nil
+}
+
+func (m *mockUserService) UseRecoveryCode(userUuid string, code string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockUserService) GetUserRoles(userUuid string) ([]string, *restErr.RestErr) {
+	return mockRoles, nil
+}
+
+func (m *mockUserService) ListUsers(payload dto.ListUsersInput) (*dto.UserPage, *restErr.RestErr) {
+	return &dto.UserPage{}, nil
+}
+
+func (m *mockUserService) GetAdminUserRecord(userUuid string) (*dto.AdminUserRecord, *restErr.RestErr) {
+	return &dto.AdminUserRecord{}, nil
+}
+
+func (m *mockUserService) SetUserDisabled(userUuid string, disabled bool) (*dto.AdminUserRecord, *restErr.RestErr) {
+	return &dto.AdminUserRecord{}, nil
+}
+
+func (m *mockUserService) DeleteUser(userUuid string) *restErr.RestErr {
+	return nil
+}
+
+type mockAPIKeyRepository struct {
+	mid        mockUUIDs
+	lastUsedIP string
+}
+
+func (m *mockAPIKeyRepository) SaveAPIKey(apiKey *entity.APIKey) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockAPIKeyRepository) GetAPIKeys(userUUID string) ([]*entity.APIKey, *restErr.RestErr) {
+	return []*entity.APIKey{}, nil
+}
+
+func (m *mockAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*entity.APIKey, *restErr.RestErr) {
+	apiKey := &entity.APIKey{ID: "mockAPIKeyID", UserUUID: m.mid.mockUserUUID.String(), Scopes: mockAPIKeyScopes}
+	switch keyHash {
+	case randtoken.Hash(mockAPIKey):
+		return apiKey, nil
+	case randtoken.Hash(mockExpiredAPIKey):
+		expiresAt := time.Now().Add(-time.Minute)
+		apiKey.ExpiresAt = &expiresAt
+		return apiKey, nil
+	}
+
+	return nil, restErr.NewNotFoundError(errConst.ErrMsgAPIKeyNotFound)
+}
+
+func (m *mockAPIKeyRepository) DeleteAPIKey(userUUID string, keyID string) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockAPIKeyRepository) UpdateAPIKeyLastUsed(keyID string, ipAddress string) *restErr.RestErr {
+	m.lastUsedIP = ipAddress
+	return nil
+}
+
+type mockAPIClientRepository struct{}
+
+func (m *mockAPIClientRepository) SaveAPIClient(client *entity.APIClient) *restErr.RestErr {
+	return nil
+}
+
+func (m *mockAPIClientRepository) GetAPIClient(clientID string) (*entity.APIClient, *restErr.RestErr) {
+	if clientID != mockClientID {
+		return nil, restErr.NewNotFoundError(errConst.ErrMsgAPIClientNotFound)
+	}
+
+	return &entity.APIClient{ClientID: mockClientID, Scopes: mockClientScopes}, nil
+}
+
+func (m *mockAPIClientRepository) GetAPIClients() ([]*entity.APIClient, *restErr.RestErr) {
+	return []*entity.APIClient{}, nil
+}
+
+func (m *mockAPIClientRepository) DeleteAPIClient(clientID string) *restErr.RestErr {
+	return nil
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	accountKeyPrefix = "account:"
+	ipKeyPrefix      = "ip:"
+)
+
+/*
+`LoginThrottle` guards the login handler against brute-force attacks.
+Failed logins are counted per account and per client IP. Each failure of an account doubles
+the delay before the next attempt is allowed, and either counter reaching its maximum locks
+the account or IP out for `LoginLockoutDuration`. A successful login resets both counters.
+It must run after `PreProcessInputs` so that the email is already sanitized.
+*/
+func LoginThrottle(r r.RedisLoginAttemptRepository, ac *entity.AccountConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		payload, ok := c.Locals("login_payload").(dto.LoginInput)
+		if !ok {
+			return c.Next()
+		}
+
+		accountKey := accountKeyPrefix + payload.Email
+		ipKey := ipKeyPrefix + c.IP()
+
+		accountAttempts, err := r.GetLoginAttempts(accountKey)
+		if err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		ipAttempts, err := r.GetLoginAttempts(ipKey)
+		if err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		now := time.Now()
+		retryAfter := max(
+			accountRetryAfter(accountAttempts, ac, now),
+			lockoutRetryAfter(ipAttempts, ac.MaxLoginAttemptsPerIP, ac.LoginLockoutDuration, now),
+		)
+
+		if retryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			err := restErr.NewTooManyRequestsError(restErr.ErrMsgTooManyRequests)
+			log.Error().Err(err).Str("email", payload.Email).Str("ip", c.IP()).Msg("")
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		switch c.Response().StatusCode() {
+		case fiber.StatusOK:
+			r.DelLoginAttempts(accountKey, ipKey)
+		case fiber.StatusBadRequest: // Wrong password or unregistered account
+			r.AddFailedLoginAttempt(accountKey, ac.LoginLockoutDuration)
+			r.AddFailedLoginAttempt(ipKey, ac.LoginLockoutDuration)
+		}
+
+		return nil
+	}
+}
+
+// accountRetryAfter applies the exponential backoff of the account on top of its lockout.
+func accountRetryAfter(attempts *entity.LoginAttempts, ac *entity.AccountConfig, now time.Time) time.Duration {
+	if lockout := lockoutRetryAfter(attempts, ac.MaxLoginAttempts, ac.LoginLockoutDuration, now); lockout > 0 {
+		return lockout
+	}
+
+	if attempts.Count == 0 || ac.LoginBackoffBase <= 0 {
+		return 0
+	}
+
+	backoff := ac.LoginBackoffBase
+	for i := int64(1); i < attempts.Count && backoff < ac.LoginLockoutDuration; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, ac.LoginLockoutDuration)
+	return attempts.LastFailedAt.Add(backoff).Sub(now)
+}
+
+func lockoutRetryAfter(attempts *entity.LoginAttempts, maxAttempts int, lockout time.Duration, now time.Time) time.Duration {
+	if maxAttempts <= 0 || attempts.Count < int64(maxAttempts) {
+		return 0
+	}
+
+	return attempts.LastFailedAt.Add(lockout).Sub(now)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	for _, test := range loginThrottleTests {
+		t.Run(test.name, func(t *testing.T) {
+			loginAttemptRepo := newMockLoginAttemptRepository()
+			if test.accountAttempts != nil {
+				loginAttemptRepo.attempts[accountKeyPrefix+mockEmail] = test.accountAttempts
+			}
+			if test.ipAttempts != nil {
+				loginAttemptRepo.attempts[ipKeyPrefix+mockIP] = test.ipAttempts
+			}
+
+			app := fiber.New()
+			app.Post("/login", func(c *fiber.Ctx) error {
+				c.Locals("login_payload", dto.LoginInput{Email: mockEmail, Password: string(c.Body())})
+				return c.Next()
+			}, LoginThrottle(loginAttemptRepo, mockAccountConfig), func(c *fiber.Ctx) error {
+				if c.Locals("login_payload").(dto.LoginInput).Password != mockPassword {
+					err := restErr.NewBadRequestError("invalid credentials")
+					return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+				}
+
+				return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+			})
+
+			req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(test.password))
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("LoginThrottle middleware test failed: %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("Expected status '%d' but got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+
+			if retryAfter := resp.Header.Get(fiber.HeaderRetryAfter); retryAfter != test.expectedRetryAfter {
+				t.Errorf("Expected Retry-After '%s' but got '%s'", test.expectedRetryAfter, retryAfter)
+			}
+
+			accountAttempts, _ := loginAttemptRepo.GetLoginAttempts(accountKeyPrefix + mockEmail)
+			if accountAttempts.Count != test.expectedAccount {
+				t.Errorf("Expected '%d' failed attempts of the account but got '%d'",
+					test.expectedAccount, accountAttempts.Count)
+			}
+
+			ipAttempts, _ := loginAttemptRepo.GetLoginAttempts(ipKeyPrefix + mockIP)
+			if ipAttempts.Count != test.expectedIP {
+				t.Errorf("Expected '%d' failed attempts of the IP but got '%d'", test.expectedIP, ipAttempts.Count)
+			}
+		})
+	}
+}
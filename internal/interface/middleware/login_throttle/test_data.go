@@ -0,0 +1,67 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+)
+
+const (
+	mockEmail    = "jiewei@gmail.com"
+	mockIP       = "0.0.0.0" // `app.Test` requests come from this address
+	mockPassword = "P@ssword1"
+)
+
+var mockAccountConfig = &entity.AccountConfig{
+	MaxLoginAttempts:      3,
+	MaxLoginAttemptsPerIP: 5,
+	LoginBackoffBase:      time.Second,
+	LoginLockoutDuration:  15 * time.Minute,
+}
+
+var loginThrottleTests = []struct {
+	name               string
+	accountAttempts    *entity.LoginAttempts
+	ipAttempts         *entity.LoginAttempts
+	password           string
+	expectedStatus     int
+	expectedRetryAfter string
+	expectedAccount    int64 // Failed attempts of the account after the request
+	expectedIP         int64 // Failed attempts of the IP after the request
+}{
+	{
+		name: "First failure is counted", password: "wrong",
+		expectedStatus: 400, expectedAccount: 1, expectedIP: 1,
+	},
+	{
+		name:            "Backoff after a failure",
+		accountAttempts: &entity.LoginAttempts{Count: 2, LastFailedAt: time.Now()},
+		password:        mockPassword, expectedStatus: 429, expectedRetryAfter: "2",
+		expectedAccount: 2, expectedIP: 0,
+	},
+	{
+		name:            "Backoff has elapsed",
+		accountAttempts: &entity.LoginAttempts{Count: 2, LastFailedAt: time.Now().Add(-time.Minute)},
+		password:        "wrong", expectedStatus: 400, expectedAccount: 3, expectedIP: 1,
+	},
+	{
+		name:            "Account is locked out",
+		accountAttempts: &entity.LoginAttempts{Count: 3, LastFailedAt: time.Now().Add(-time.Minute)},
+		password:        mockPassword, expectedStatus: 429, expectedRetryAfter: "840",
+		expectedAccount: 3, expectedIP: 0,
+	},
+	{
+		name:       "IP is locked out",
+		ipAttempts: &entity.LoginAttempts{Count: 5, LastFailedAt: time.Now().Add(-10 * time.Minute)},
+		password:   mockPassword, expectedStatus: 429, expectedRetryAfter: "300",
+		expectedAccount: 0, expectedIP: 5,
+	},
+	{
+		name:            "Lockout has expired and success resets the counters",
+		accountAttempts: &entity.LoginAttempts{Count: 3, LastFailedAt: time.Now().Add(-16 * time.Minute)},
+		ipAttempts:      &entity.LoginAttempts{Count: 4, LastFailedAt: time.Now().Add(-16 * time.Minute)},
+		password:        mockPassword, expectedStatus: 200, expectedAccount: 0, expectedIP: 0,
+	},
+}
@@ -0,0 +1,47 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+type mockLoginAttemptRepository struct {
+	attempts map[string]*entity.LoginAttempts
+}
+
+func newMockLoginAttemptRepository() *mockLoginAttemptRepository {
+	return &mockLoginAttemptRepository{attempts: map[string]*entity.LoginAttempts{}}
+}
+
+func (m *mockLoginAttemptRepository) GetLoginAttempts(key string) (*entity.LoginAttempts, *restErr.RestErr) {
+	if attempts, ok := m.attempts[key]; ok {
+		return &entity.LoginAttempts{Count: attempts.Count, LastFailedAt: attempts.LastFailedAt}, nil
+	}
+
+	return &entity.LoginAttempts{}, nil
+}
+
+func (m *mockLoginAttemptRepository) AddFailedLoginAttempt(key string, ttl time.Duration) (
+	*entity.LoginAttempts, *restErr.RestErr) {
+	attempts, ok := m.attempts[key]
+	if !ok {
+		attempts = &entity.LoginAttempts{}
+		m.attempts[key] = attempts
+	}
+
+	attempts.Count++
+	attempts.LastFailedAt = time.Now()
+	return attempts, nil
+}
+
+func (m *mockLoginAttemptRepository) DelLoginAttempts(keys ...string) *restErr.RestErr {
+	for _, key := range keys {
+		delete(m.attempts, key)
+	}
+
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
@@ -28,6 +30,11 @@ const (
 	alphanumVM = "contain only alphanumeric characters"
 	emailVM    = "be a valid email address"
 	passwdVM   = "contain at least one number, one uppercase letter, one lowercase letter, and one special character"
+	lenVM      = "be exactly %s characters long"
+	hexVM      = "contain only hexadecimal characters"
+	numericVM  = "contain only numeric characters"
+	permVM     = "be a known permission"
+	futureVM   = "be in the future"
 )
 
 func PreProcessInputs(c *fiber.Ctx) error {
@@ -64,6 +71,105 @@ func PreProcessInputs(c *fiber.Ctx) error {
 
 		c.Locals("login_payload", payload)
 
+	case authServicePathName + "/verify-email/resend", authServicePathName + "/forgot-password":
+		var payload dto.EmailInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("email_payload", payload)
+
+	case authServicePathName + "/reset-password":
+		var payload dto.ResetPasswordInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("reset_password_payload", payload)
+
+	case authServicePathName + "/me/password":
+		var payload dto.ChangePasswordInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("change_password_payload", payload)
+
+	case authServicePathName + "/me":
+		// The profile is updated with PATCH and the account deleted with DELETE
+		if c.Method() == fiber.MethodDelete {
+			var payload dto.DeleteAccountInput
+			if err := parseAndSanitize(c, &payload); err != nil {
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+
+			if err := validateStruct(&payload); err != nil {
+				return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+			}
+
+			c.Locals("delete_account_payload", payload)
+			break
+		}
+
+		var payload dto.UpdateUserInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("update_user_payload", payload)
+
+	case authServicePathName + "/me/mfa/totp/confirm":
+		var payload dto.TOTPCodeInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("totp_code_payload", payload)
+
+	case authServicePathName + "/login/mfa":
+		var payload dto.MFALoginInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("mfa_login_payload", payload)
+
+	case authServicePathName + "/me/api-keys":
+		var payload dto.APIKeyInput
+		if err := parseAndSanitize(c, &payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		if err := validateStruct(&payload); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		c.Locals("api_key_payload", payload)
+
 	default:
 		err := restErr.NewBadRequestError(errMsgInvalidEndPoint + endpoint)
 		log.Error().Err(err).Msg("")
@@ -129,16 +235,23 @@ var validate *validator.Validate
 func init() {
 	validate = validator.New(validator.WithRequiredStructEnabled())
 	validate.RegisterValidation("passwd", validatePassword)
+	validate.RegisterValidation("permission", validatePermission)
+	validate.RegisterValidation("future", validateFuture)
 }
 
 var validationMessages = map[string]string{
-	"required": requiredVM,
-	"min":      minVM,
-	"max":      maxVM,
-	"alpha":    alphaVM,
-	"alphanum": alphaVM,
-	"email":    emailVM,
-	"passwd":   passwdVM,
+	"required":    requiredVM,
+	"min":         minVM,
+	"max":         maxVM,
+	"alpha":       alphaVM,
+	"alphanum":    alphaVM,
+	"email":       emailVM,
+	"passwd":      passwdVM,
+	"len":         lenVM,
+	"hexadecimal": hexVM,
+	"numeric":     numericVM,
+	"permission":  permVM,
+	"future":      futureVM,
 }
 
 /*
@@ -200,3 +313,12 @@ func validatePassword(fl validator.FieldLevel) bool {
 	}
 	return hasNumber && hasUpper && hasLower && hasSpecial
 }
+
+func validatePermission(fl validator.FieldLevel) bool {
+	return slices.Contains(entity.Permissions, fl.Field().String())
+}
+
+func validateFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	return ok && t.After(time.Now())
+}
@@ -25,6 +25,15 @@ func TestPreProcessInputs(t *testing.T) {
 	app.Use(PreProcessInputs)
 	app.Post(authServicePathName+"/register", registerHandler)
 	app.Post(authServicePathName+"/login", loginHandler)
+	app.Post(authServicePathName+"/verify-email/resend", emailHandler)
+	app.Post(authServicePathName+"/forgot-password", emailHandler)
+	app.Post(authServicePathName+"/reset-password", resetPasswordHandler)
+	app.Post(authServicePathName+"/me", updateUserHandler)
+	app.Delete(authServicePathName+"/me", deleteAccountHandler)
+	app.Post(authServicePathName+"/me/password", changePasswordHandler)
+	app.Post(authServicePathName+"/me/mfa/totp/confirm", totpCodeHandler)
+	app.Post(authServicePathName+"/login/mfa", mfaLoginHandler)
+	app.Post(authServicePathName+"/me/api-keys", apiKeyHandler)
 
 	for _, test := range preProcessInputsTests {
 		t.Run(test.name, func(t *testing.T) {
@@ -52,6 +61,54 @@ func TestPreProcessInputs(t *testing.T) {
 					t.Errorf("Expected status code %d, got %d.", fiber.StatusOK, resp.StatusCode)
 				}
 
+				if test.expectedNewPassword != "" {
+					newPassword, ok := respBody["new_password"].(string)
+					if !ok || newPassword != test.expectedNewPassword {
+						t.Errorf("Expected new_password '%s' but got '%v'", test.expectedNewPassword, respBody["new_password"])
+					}
+					return
+				}
+
+				if test.expectedPassword != "" {
+					password, ok := respBody["password"].(string)
+					if !ok || password != test.expectedPassword {
+						t.Errorf("Expected password '%s' but got '%v'", test.expectedPassword, respBody["password"])
+					}
+					return
+				}
+
+				if test.expectedFirstName != "" {
+					firstName, ok := respBody["first_name"].(string)
+					if !ok || firstName != test.expectedFirstName {
+						t.Errorf("Expected first_name '%s' but got '%v'", test.expectedFirstName, respBody["first_name"])
+					}
+					return
+				}
+
+				if test.expectedName != "" {
+					name, ok := respBody["name"].(string)
+					if !ok || name != test.expectedName {
+						t.Errorf("Expected name '%s' but got '%v'", test.expectedName, respBody["name"])
+					}
+					return
+				}
+
+				if test.expectedCode != "" {
+					code, ok := respBody["code"].(string)
+					if !ok || code != test.expectedCode {
+						t.Errorf("Expected code '%s' but got '%v'", test.expectedCode, respBody["code"])
+					}
+					return
+				}
+
+				if test.expectedToken != "" {
+					token, ok := respBody["token"].(string)
+					if !ok || token != test.expectedToken {
+						t.Errorf("Expected token '%s' but got '%v'", test.expectedToken, respBody["token"])
+					}
+					return
+				}
+
 				email, ok := respBody["email"].(string)
 				if !ok {
 					t.Errorf("Failed to get email from response body")
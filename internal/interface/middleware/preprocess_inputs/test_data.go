@@ -4,23 +4,38 @@ package middleware
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	"github.com/gofiber/fiber/v2"
 )
 
 const authServicePathName = "/auth/api/v1/users"
 
+var (
+	mockFutureTime = time.Now().Add(time.Hour)
+	mockPastTime   = time.Now().Add(-time.Hour)
+)
+
 type testCase struct {
-	name            string
-	url             string
-	payload         interface{}
-	expectedEmail   string
-	expectedErrMsg  string
-	expectedStatus  int
-	expectedError   string
-	expectedPayload interface{}
-	invalidJSON     bool
+	name                string
+	method              string // Defaults to POST
+	url                 string
+	payload             interface{}
+	expectedEmail       string
+	expectedToken       string
+	expectedNewPassword string
+	expectedPassword    string
+	expectedCode        string
+	expectedFirstName   string
+	expectedName        string
+	expectedErrMsg      string
+	expectedStatus      int
+	expectedError       string
+	expectedPayload     interface{}
+	invalidJSON         bool
 }
 
 var preProcessInputsTests = []testCase{
@@ -44,6 +59,135 @@ var preProcessInputsTests = []testCase{
 		},
 		expectedEmail: "jiewei@gmail.com",
 	},
+	{
+		name:          "Valid resend verification email endpoint",
+		url:           authServicePathName + "/verify-email/resend",
+		payload:       dto.EmailInput{Email: " JieWei@gmail.com "},
+		expectedEmail: "jiewei@gmail.com",
+	},
+	{
+		name:           "Failed to validate resend verification email payload",
+		url:            authServicePathName + "/verify-email/resend",
+		payload:        dto.EmailInput{Email: "invalidEmail"},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s", "email", emailVM),
+	},
+	{
+		name:          "Valid forgot password endpoint",
+		url:           authServicePathName + "/forgot-password",
+		payload:       dto.EmailInput{Email: "JieWei@gmail.com"},
+		expectedEmail: "jiewei@gmail.com",
+	},
+	{
+		name: "Valid reset password endpoint",
+		url:  authServicePathName + "/reset-password",
+		payload: dto.ResetPasswordInput{
+			Token:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			NewPassword: "N3wP@ssword",
+		},
+		expectedToken: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	},
+	{
+		name: "Failed to validate reset password payload",
+		url:  authServicePathName + "/reset-password",
+		payload: dto.ResetPasswordInput{
+			Token:       "not-a-reset-token",
+			NewPassword: "password",
+		},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should "+lenVM+"\n", "token", "64") +
+			fmt.Sprintf("the field [%s] should %s", "new_password", passwdVM),
+	},
+	{
+		name: "Valid change password endpoint",
+		url:  authServicePathName + "/me/password",
+		payload: dto.ChangePasswordInput{
+			CurrentPassword: "P@ssword1",
+			NewPassword:     "N3wP@ssword",
+		},
+		expectedNewPassword: "N3wP@ssword",
+	},
+	{
+		name: "Failed to validate change password payload",
+		url:  authServicePathName + "/me/password",
+		payload: dto.ChangePasswordInput{
+			CurrentPassword: "",
+			NewPassword:     "N3wPassword",
+		},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s\n", "current_password", requiredVM) +
+			fmt.Sprintf("the field [%s] should %s", "new_password", passwdVM),
+	},
+	{
+		name:              "Valid update user endpoint",
+		url:               authServicePathName + "/me",
+		payload:           dto.UpdateUserInput{FirstName: " Jie ", UpdatedAt: time.Now()},
+		expectedFirstName: "jie",
+	},
+	{
+		name:    "Failed to validate update user payload",
+		url:     authServicePathName + "/me",
+		payload: dto.UpdateUserInput{FirstName: "J1e"},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s\n", "first_name", alphaVM) +
+			fmt.Sprintf("the field [%s] should %s", "updated_at", requiredVM),
+	},
+	{
+		name:             "Valid delete account endpoint",
+		method:           "DELETE",
+		url:              authServicePathName + "/me",
+		payload:          dto.DeleteAccountInput{Password: " P@ssword1 "},
+		expectedPassword: "P@ssword1",
+	},
+	{
+		name:           "Failed to validate delete account payload",
+		method:         "DELETE",
+		url:            authServicePathName + "/me",
+		payload:        dto.DeleteAccountInput{Password: strings.Repeat("P@ssword1", 12)},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s", "password", fmt.Sprintf(maxVM, "100")),
+	},
+	{
+		name:         "Valid confirm TOTP endpoint",
+		url:          authServicePathName + "/me/mfa/totp/confirm",
+		payload:      dto.TOTPCodeInput{Code: " 287082 "},
+		expectedCode: "287082",
+	},
+	{
+		name:           "Failed to validate confirm TOTP payload",
+		url:            authServicePathName + "/me/mfa/totp/confirm",
+		payload:        dto.TOTPCodeInput{Code: "28708a"},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s", "code", numericVM),
+	},
+	{
+		name: "Valid MFA login endpoint",
+		url:  authServicePathName + "/login/mfa",
+		payload: dto.MFALoginInput{
+			MFAToken: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Code:     "ABCDE-12345",
+		},
+		expectedCode: "abcde-12345",
+	},
+	{
+		name:    "Failed to validate MFA login payload",
+		url:     authServicePathName + "/login/mfa",
+		payload: dto.MFALoginInput{MFAToken: "not-a-challenge-token", Code: ""},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should "+lenVM+"\n", "mfa_token", "64") +
+			fmt.Sprintf("the field [%s] should %s", "code", requiredVM),
+	},
+	{
+		name: "Valid create API key endpoint",
+		url:  authServicePathName + "/me/api-keys",
+		payload: dto.APIKeyInput{
+			Name: " CI ", Scopes: []string{entity.PermissionUsersRead}, ExpiresAt: &mockFutureTime,
+		},
+		expectedName: "ci",
+	},
+	{
+		name: "Failed to validate create API key payload",
+		url:  authServicePathName + "/me/api-keys",
+		payload: dto.APIKeyInput{
+			Name: "", Scopes: []string{entity.PermissionUsersRead, "users:impersonate"}, ExpiresAt: &mockPastTime,
+		},
+		expectedErrMsg: fmt.Sprintf("the field [%s] should %s\n", "name", requiredVM) +
+			fmt.Sprintf("the field [%s] should %s\n", "scopes[1]", permVM) +
+			fmt.Sprintf("the field [%s] should %s", "expires_at", futureVM),
+	},
 	{
 		name:           "Invalid endpoint",
 		url:            "/auth/invalid",
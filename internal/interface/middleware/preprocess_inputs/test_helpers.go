@@ -31,18 +31,95 @@ func loginHandler(c *fiber.Ctx) error {
 	return c.JSON(payload)
 }
 
+// emailHandler handles the resend verification email route
+func emailHandler(c *fiber.Ctx) error {
+	payload := c.Locals("email_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No email payload found")
+	}
+	return c.JSON(payload)
+}
+
+// resetPasswordHandler handles the reset password route
+func resetPasswordHandler(c *fiber.Ctx) error {
+	payload := c.Locals("reset_password_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No reset password payload found")
+	}
+	return c.JSON(payload)
+}
+
+// updateUserHandler handles the update user route
+func updateUserHandler(c *fiber.Ctx) error {
+	payload := c.Locals("update_user_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No update user payload found")
+	}
+	return c.JSON(payload)
+}
+
+// deleteAccountHandler handles the delete account route
+func deleteAccountHandler(c *fiber.Ctx) error {
+	payload := c.Locals("delete_account_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No delete account payload found")
+	}
+	return c.JSON(payload)
+}
+
+// changePasswordHandler handles the change password route
+func changePasswordHandler(c *fiber.Ctx) error {
+	payload := c.Locals("change_password_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No change password payload found")
+	}
+	return c.JSON(payload)
+}
+
+// totpCodeHandler handles the confirm TOTP enrollment route
+func totpCodeHandler(c *fiber.Ctx) error {
+	payload := c.Locals("totp_code_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No TOTP code payload found")
+	}
+	return c.JSON(payload)
+}
+
+// mfaLoginHandler handles the MFA login route
+func mfaLoginHandler(c *fiber.Ctx) error {
+	payload := c.Locals("mfa_login_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No MFA login payload found")
+	}
+	return c.JSON(payload)
+}
+
+// apiKeyHandler handles the create API key route
+func apiKeyHandler(c *fiber.Ctx) error {
+	payload := c.Locals("api_key_payload")
+	if payload == nil {
+		return c.Status(fiber.StatusBadRequest).SendString("No API key payload found")
+	}
+	return c.JSON(payload)
+}
+
 // createRequest creates a new test request based on the given test case
 func createRequest(t *testing.T, test testCase) *http.Request {
+	method := test.method
+	if method == "" {
+		method = "POST"
+	}
+
 	if test.payload != nil {
 		payloadBytes, err := json.Marshal(test.payload)
 		if err != nil {
 			t.Errorf("Failed to marshal payload: %v", err)
 		}
-		req := httptest.NewRequest("POST", test.url, bytes.NewReader(payloadBytes))
+		req := httptest.NewRequest(method, test.url, bytes.NewReader(payloadBytes))
 		req.Header.Set("Content-Type", "application/json")
 		return req
 	}
-	return httptest.NewRequest("POST", test.url, nil)
+	return httptest.NewRequest(method, test.url, nil)
 }
 
 // createDummyRequest creates a new dummy request based on the test case
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter(t *testing.T) {
+	for _, test := range rateLimiterTests {
+		t.Run(test.name, func(t *testing.T) {
+			var store r.RateLimitRepository = ratelimit.NewInMemoryRateLimitRepository()
+			if test.failing {
+				store = &mockFailingRateLimitRepository{}
+			}
+
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				if userUUID, err := uuid.Parse(c.Get("X-User-UUID")); err == nil {
+					c.Locals("userRecord", &dto.UserRecord{UUID: &userUUID})
+				}
+				return c.Next()
+			}, RateLimiter(store, "test", test.rule, KeyByUser), func(c *fiber.Ctx) error {
+				return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+			})
+
+			for i, userUUID := range test.requests {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set("X-User-UUID", userUUID)
+
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatalf("RateLimiter middleware test failed: %v", err)
+				}
+				resp.Body.Close()
+
+				expected := test.expected[i]
+				if resp.StatusCode != expected.status {
+					t.Errorf("Request %d: expected status '%d' but got '%d'", i, expected.status, resp.StatusCode)
+				}
+
+				if remaining := resp.Header.Get(headerRateLimitRemaining); remaining != expected.remaining {
+					t.Errorf("Request %d: expected RateLimit-Remaining '%s' but got '%s'", i, expected.remaining, remaining)
+				}
+
+				if retryAfter := resp.Header.Get(fiber.HeaderRetryAfter); retryAfter != expected.retryAfter {
+					t.Errorf("Request %d: expected Retry-After '%s' but got '%s'", i, expected.retryAfter, retryAfter)
+				}
+			}
+		})
+	}
+}
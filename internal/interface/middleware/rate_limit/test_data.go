@@ -0,0 +1,47 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+)
+
+type expectedResponse struct {
+	status     int
+	remaining  string
+	retryAfter string
+}
+
+var rateLimiterTests = []struct {
+	name     string
+	rule     *entity.RateLimitRule
+	failing  bool     // Uses a store that always errors
+	requests []string // User UUID of each request; empty for anonymous
+	expected []expectedResponse
+}{
+	{
+		name: "Requests over the limit are rejected", rule: &entity.RateLimitRule{Limit: 2, Window: time.Minute},
+		requests: []string{"", "", ""},
+		expected: []expectedResponse{{200, "1", ""}, {200, "0", ""}, {429, "0", "60"}},
+	},
+	{
+		name: "Users are counted separately", rule: &entity.RateLimitRule{Limit: 1, Window: time.Minute},
+		requests: []string{
+			"0190e6e5-0000-7000-8000-000000000001", "0190e6e5-0000-7000-8000-000000000002",
+			"0190e6e5-0000-7000-8000-000000000001", "",
+		},
+		expected: []expectedResponse{{200, "0", ""}, {200, "0", ""}, {429, "0", "60"}, {200, "0", ""}},
+	},
+	{
+		name: "Zero limit disables the limiter", rule: &entity.RateLimitRule{Limit: 0, Window: time.Minute},
+		requests: []string{"", ""},
+		expected: []expectedResponse{{200, "", ""}, {200, "", ""}},
+	},
+	{
+		name: "Store errors let the request through", rule: &entity.RateLimitRule{Limit: 1, Window: time.Minute},
+		failing: true, requests: []string{"", ""},
+		expected: []expectedResponse{{200, "", ""}, {200, "", ""}},
+	},
+}
@@ -0,0 +1,15 @@
+// coverage:ignore file
+// Test file
+package middleware
+
+import (
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+type mockFailingRateLimitRepository struct{}
+
+func (m *mockFailingRateLimitRepository) Allow(key string, rule *entity.RateLimitRule) (
+	*entity.RateLimit, *restErr.RestErr) {
+	return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+}
@@ -0,0 +1,141 @@
+// coverage:ignore file
+// Testing with integration test
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+
+	errMsgInvalidCursor = "invalid cursor"
+	errMsgInvalidLimit  = "the limit should be between 1 and 100"
+	errMsgInvalidDate   = "the field [%s] should be an RFC 3339 timestamp or a YYYY-MM-DD date"
+)
+
+/*
+ListUsers returns a page of users from the newest.
+The cursor is opaque to clients; it encodes the ID of the last user of the previous page so that
+pages stay consistent while users are being created.
+*/
+func (us *UserService) ListUsers(payload dto.ListUsersInput) (*dto.UserPage, *restErr.RestErr) {
+	filter := &entity.UserFilter{Email: payload.Email, Name: payload.Name, Limit: payload.Limit}
+	if filter.Limit == 0 {
+		filter.Limit = defaultUserPageSize
+	} else if filter.Limit < 0 || filter.Limit > maxUserPageSize {
+		return nil, restErr.NewBadRequestError(errMsgInvalidLimit)
+	}
+
+	var err *restErr.RestErr
+	if filter.CreatedAfter, err = parseDate("created_after", payload.CreatedAfter); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBefore, err = parseDate("created_before", payload.CreatedBefore); err != nil {
+		return nil, err
+	}
+
+	if payload.Cursor != "" {
+		decodedCursor, err := base64.RawURLEncoding.DecodeString(payload.Cursor)
+		if err != nil {
+			return nil, restErr.NewBadRequestError(errMsgInvalidCursor)
+		}
+
+		if filter.BeforeID, err = strconv.ParseInt(string(decodedCursor), 10, 64); err != nil || filter.BeforeID <= 0 {
+			return nil, restErr.NewBadRequestError(errMsgInvalidCursor)
+		}
+	}
+
+	// One more user than the page size tells whether there is a next page
+	filter.Limit++
+	users, err := us.ur.ListUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.UserPage{Users: []*dto.AdminUserRecord{}}
+	if len(users) == filter.Limit {
+		users = users[:len(users)-1]
+		lastID := strconv.FormatInt(users[len(users)-1].ID, 10)
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(lastID))
+	}
+
+	for _, user := range users {
+		page.Users = append(page.Users, newAdminUserRecord(user))
+	}
+
+	return page, nil
+}
+
+func (us *UserService) GetAdminUserRecord(userUuid string) (*dto.AdminUserRecord, *restErr.RestErr) {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	record := newAdminUserRecord(user)
+	if record.Roles, err = us.rr.GetUserRoles(userUuid); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (us *UserService) SetUserDisabled(userUuid string, disabled bool) (*dto.AdminUserRecord, *restErr.RestErr) {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.ur.SetDisabled(user, disabled); err != nil {
+		return nil, err
+	}
+
+	return newAdminUserRecord(user), nil
+}
+
+func (us *UserService) DeleteUser(userUuid string) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	return us.ur.DeleteUser(user)
+}
+
+func newAdminUserRecord(user *entity.User) *dto.AdminUserRecord {
+	return &dto.AdminUserRecord{
+		UUID:            user.UUID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		TOTPEnabledAt:   user.TOTPEnabledAt,
+		DisabledAt:      user.DisabledAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+func parseDate(field string, value string) (*time.Time, *restErr.RestErr) {
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if date, err := time.Parse(layout, value); err == nil {
+			date = date.UTC() // `created_at` is stored in UTC without a time zone
+			return &date, nil
+		}
+	}
+
+	return nil, restErr.NewBadRequestError(fmt.Sprintf(errMsgInvalidDate, field))
+}
@@ -3,23 +3,49 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
 	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	repo "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/aesgcm"
 	password "github.com/DarrelA/starter-go-postgresql/internal/infrastructure/bcrypt"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/totp"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	errMsgTOTPSecretError = "totp secret error"
+	errMsgNothingToUpdate = "provide at least one of the fields [first_name] or [last_name]"
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5 // Shown as two groups of 5 hex characters
+)
+
 type UserService struct {
-	JWTConfig *entity.JWTConfig
-	ur        repo.PostgresUserRepository
+	JWTConfig     *entity.JWTConfig
+	AccountConfig *entity.AccountConfig
+	ur            repo.PostgresUserRepository
+	rr            repo.PostgresRoleRepository
+	ir            repo.PostgresIdentityRepository
 }
 
-func NewUserService(JWTConfig *entity.JWTConfig, ur repo.PostgresUserRepository) appSvc.UserService {
-	return &UserService{JWTConfig, ur}
+func NewUserService(
+	JWTConfig *entity.JWTConfig,
+	AccountConfig *entity.AccountConfig,
+	ur repo.PostgresUserRepository,
+	rr repo.PostgresRoleRepository,
+	ir repo.PostgresIdentityRepository,
+) appSvc.UserService {
+	return &UserService{JWTConfig, AccountConfig, ur, rr, ir}
 }
 
 func (us *UserService) GetJWTConfig() *entity.JWTConfig {
@@ -55,6 +81,137 @@ func (us *UserService) CreateUser(payload dto.RegisterInput) (*dto.UserResponse,
 	return userResponse, nil
 }
 
+/*
+FindOrCreateExternalUser resolves the user who logged in with an external identity provider.
+An identity seen before logs into its linked user. Otherwise the verified email address links the identity
+to the user with that email address, or to a new user without a password if there is none.
+A user who set a password but never verified the email address must login and link the identity instead.
+*/
+func (us *UserService) FindOrCreateExternalUser(externalUser *entity.ExternalUser) (*entity.User, *restErr.RestErr) {
+	identity, err := us.ir.GetIdentity(externalUser.Provider, externalUser.Subject)
+	if err == nil {
+		return us.GetUserByUUID(identity.UserUUID)
+	} else if err.Status != http.StatusNotFound {
+		return nil, err
+	}
+
+	// An unverified email address could belong to someone else, and linking it would hand over their account
+	if !externalUser.EmailVerified {
+		return nil, restErr.NewForbiddenError(restErr.ErrMsgExternalEmailUnverified)
+	}
+
+	user := &entity.User{Email: externalUser.Email}
+	err = us.ur.GetUserByEmail(user)
+	if err != nil && err.Status != http.StatusBadRequest {
+		return nil, err
+	} else if err == nil && user.EmailVerifiedAt == nil && user.Password != "" {
+		/*
+			Anyone can register with an email address they do not own and wait for its owner to login with a provider;
+			linking the identity would keep them in with the password. Only the user logged in with it can link it.
+		*/
+		return nil, restErr.NewConflictError(restErr.ErrMsgExternalAccountUnverified)
+	} else if err != nil { // The email address has not been registered
+		user = &entity.User{
+			FirstName: externalUser.FirstName,
+			LastName:  externalUser.LastName,
+			Email:     externalUser.Email,
+		}
+
+		if err := us.ur.SaveUser(user); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Password = "" // Only needed above, the hash is not handed out
+
+	// The provider has verified the email address
+	if user.EmailVerifiedAt == nil {
+		if err := us.ur.VerifyEmail(user); err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &entity.UserIdentity{
+		Provider: externalUser.Provider,
+		Subject:  externalUser.Subject,
+		UserUUID: user.UUID.String(),
+		Email:    externalUser.Email,
+	}
+
+	if err := us.ir.SaveIdentity(identity); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("user_uuid", identity.UserUUID).Str("provider", identity.Provider).Msg("external identity linked")
+	return user, nil
+}
+
+/*
+LinkExternalIdentity links an external identity to a user who is already logged in.
+The email address of the identity does not have to be verified, since the user proved owning both accounts.
+*/
+func (us *UserService) LinkExternalIdentity(userUuid string, externalUser *entity.ExternalUser) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	identity, err := us.ir.GetIdentity(externalUser.Provider, externalUser.Subject)
+	if err == nil {
+		if identity.UserUUID == user.UUID.String() {
+			return nil
+		}
+		return restErr.NewConflictError(restErr.ErrMsgIdentityIsAlreadyLinked)
+	} else if err.Status != http.StatusNotFound {
+		return err
+	}
+
+	identity = &entity.UserIdentity{
+		Provider: externalUser.Provider,
+		Subject:  externalUser.Subject,
+		UserUUID: user.UUID.String(),
+		Email:    externalUser.Email,
+	}
+
+	if err := us.ir.SaveIdentity(identity); err != nil {
+		return err
+	}
+
+	log.Info().Str("user_uuid", identity.UserUUID).Str("provider", identity.Provider).Msg("external identity linked")
+	return nil
+}
+
+// UnlinkIdentity refuses to remove the last login method of the user; the caller tells if there are passkeys.
+func (us *UserService) UnlinkIdentity(userUuid string, provider string, subject string,
+	hasPasskeys bool) *restErr.RestErr {
+	identity := &entity.UserIdentity{Provider: provider, Subject: subject, UserUUID: userUuid}
+	if err := us.ir.DeleteIdentity(identity, hasPasskeys); err != nil {
+		return err
+	}
+
+	log.Info().Str("user_uuid", userUuid).Str("provider", provider).Msg("external identity unlinked")
+	return nil
+}
+
+func (us *UserService) GetIdentities(userUuid string) ([]dto.IdentityRecord, *restErr.RestErr) {
+	identities, err := us.ir.GetIdentities(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]dto.IdentityRecord, 0, len(identities))
+	for _, identity := range identities {
+		records = append(records, dto.IdentityRecord{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+			LinkedAt: identity.LinkedAt,
+		})
+	}
+
+	return records, nil
+}
+
 func (us *UserService) GetUserByEmail(u dto.LoginInput) (*dto.UserResponse, *restErr.RestErr) {
 	result := &entity.User{Email: u.Email}
 	if err := us.ur.GetUserByEmail(result); err != nil {
@@ -66,10 +223,13 @@ func (us *UserService) GetUserByEmail(u dto.LoginInput) (*dto.UserResponse, *res
 	}
 
 	userResponse := &dto.UserResponse{
-		UUID:      result.UUID,
-		FirstName: result.FirstName,
-		LastName:  result.LastName,
-		Email:     result.Email,
+		UUID:            result.UUID,
+		FirstName:       result.FirstName,
+		LastName:        result.LastName,
+		Email:           result.Email,
+		EmailVerifiedAt: result.EmailVerifiedAt,
+		TOTPEnabledAt:   result.TOTPEnabledAt,
+		DisabledAt:      result.DisabledAt,
 	}
 
 	return userResponse, nil
@@ -89,3 +249,258 @@ func (us *UserService) GetUserByUUID(userUuid string) (*entity.User, *restErr.Re
 	}
 	return result, nil
 }
+
+// FindUserByEmail looks the user up without verifying a password, e.g. to resend the verification email.
+func (us *UserService) FindUserByEmail(email string) (*entity.User, *restErr.RestErr) {
+	result := &entity.User{Email: email}
+	if err := us.ur.GetUserByEmail(result); err != nil {
+		return nil, err
+	}
+
+	result.Password = ""
+	return result, nil
+}
+
+func (us *UserService) VerifyEmail(userUuid string) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	return us.ur.VerifyEmail(user)
+}
+
+// ResetPassword replaces the password without verifying the current one; the caller must have authorized the reset.
+func (us *UserService) ResetPassword(userUuid string, newPassword string) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, errHash := password.HashPassword(newPassword)
+	if errHash != nil {
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	user.Password = hashedPassword
+	return us.ur.UpdatePassword(user)
+}
+
+func (us *UserService) ChangePassword(userUuid string, payload dto.ChangePasswordInput) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	// Only the lookup by email selects the password hash
+	if _, err := us.GetUserByEmail(dto.LoginInput{Email: user.Email, Password: payload.CurrentPassword}); err != nil {
+		return err
+	}
+
+	return us.ResetPassword(userUuid, payload.NewPassword)
+}
+
+func (us *UserService) UpdateUser(userUuid string, payload dto.UpdateUserInput) (*entity.User, *restErr.RestErr) {
+	if payload.FirstName == "" && payload.LastName == "" {
+		return nil, restErr.NewBadRequestError(errMsgNothingToUpdate)
+	}
+
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.FirstName != "" {
+		user.FirstName = payload.FirstName
+	}
+	if payload.LastName != "" {
+		user.LastName = payload.LastName
+	}
+
+	if err := us.ur.UpdateUser(user, payload.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+/*
+DeleteAccount soft-deletes the user once the password is confirmed; the sessions are revoked by the caller.
+Users who only login with an external provider have no password, so they must have logged in at `loggedInAt`
+within `AccountConfig.RecentLoginMaxAge` instead.
+*/
+func (us *UserService) DeleteAccount(userUuid string, payload dto.DeleteAccountInput,
+	loggedInAt time.Time) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	// Only the lookup by email selects the password hash
+	result := &entity.User{Email: user.Email}
+	if err := us.ur.GetUserByEmail(result); err != nil {
+		return err
+	}
+
+	if result.Password == "" {
+		if time.Since(loggedInAt) > us.AccountConfig.RecentLoginMaxAge {
+			return restErr.NewForbiddenError(restErr.ErrMsgRecentLoginRequired)
+		}
+	} else if err := password.VerifyPassword(result.Password, payload.Password); err != nil {
+		return restErr.NewBadRequestError(err.Error())
+	}
+
+	return us.ur.SoftDeleteUser(user)
+}
+
+// PurgeDeletedUsers deletes for good the users whose deletion grace period is over.
+func (us *UserService) PurgeDeletedUsers() (int64, *restErr.RestErr) {
+	return us.ur.PurgeDeletedUsers(time.Now().UTC().Add(-us.AccountConfig.DeletionGracePeriod))
+}
+
+// ExportUser exports the data of the user kept in Postgres; the caller adds the passkeys and sessions.
+func (us *UserService) ExportUser(userUuid string) (*dto.UserExport, *restErr.RestErr) {
+	record, err := us.GetAdminUserRecord(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := us.GetIdentities(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UserExport{
+		ExportedAt: time.Now().UTC(),
+		User:       record,
+		Identities: identities,
+		Passkeys:   []dto.PasskeyRecord{},
+		Sessions:   []dto.SessionRecord{},
+	}, nil
+}
+
+// EnrollTOTP generates a new TOTP secret which only takes effect once `ConfirmTOTP` receives a valid code.
+func (us *UserService) EnrollTOTP(userUuid string) (*dto.TOTPEnrollment, *restErr.RestErr) {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgTOTPAlreadyEnabled)
+	}
+
+	secret, errSecret := totp.GenerateSecret()
+	if errSecret != nil {
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	encryptedSecret, errEncrypt := aesgcm.Encrypt(us.AccountConfig.TOTPEncryptionKey, secret)
+	if errEncrypt != nil {
+		log.Error().Err(errEncrypt).Msg(errMsgTOTPSecretError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	user.TOTPSecret = encryptedSecret
+	if err := us.ur.SetTOTPSecret(user); err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthURI: totp.KeyURI(us.AccountConfig.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled secret and returns the recovery codes, which are never shown again.
+func (us *UserService) ConfirmTOTP(userUuid string, code string) ([]string, *restErr.RestErr) {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgTOTPAlreadyEnabled)
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgTOTPNotEnrolled)
+	}
+
+	if _, err := us.validateTOTPCode(user, code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			log.Error().Err(err).Msg(errMsgTOTPSecretError)
+			return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		}
+
+		code := hex.EncodeToString(b)
+		recoveryCodes[i] = code[:recoveryCodeBytes] + "-" + code[recoveryCodeBytes:]
+		recoveryCodeHashes[i] = hashRecoveryCode(recoveryCodes[i])
+	}
+
+	if err := us.ur.EnableTOTP(user, recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// ValidateTOTP returns the time step of the valid code so that the caller can reject it being replayed.
+func (us *UserService) ValidateTOTP(userUuid string, code string) (int64, *restErr.RestErr) {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return 0, err
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return 0, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidMFACode)
+	}
+
+	return us.validateTOTPCode(user, code)
+}
+
+func (us *UserService) UseRecoveryCode(userUuid string, code string) *restErr.RestErr {
+	user, err := us.GetUserByUUID(userUuid)
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabledAt == nil {
+		return restErr.NewUnauthorizedError(restErr.ErrMsgInvalidMFACode)
+	}
+
+	return us.ur.UseRecoveryCode(user, hashRecoveryCode(code))
+}
+
+func (us *UserService) validateTOTPCode(user *entity.User, code string) (int64, *restErr.RestErr) {
+	secret, err := aesgcm.Decrypt(us.AccountConfig.TOTPEncryptionKey, user.TOTPSecret)
+	if err != nil {
+		log.Error().Err(err).Str("user_uuid", user.UUID.String()).Msg(errMsgTOTPSecretError)
+		return 0, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	step, valid := totp.Validate(secret, code, time.Now())
+	if !valid {
+		return 0, restErr.NewUnauthorizedError(restErr.ErrMsgInvalidMFACode)
+	}
+
+	return step, nil
+}
+
+// hashRecoveryCode ignores the case and the separator so that the codes can be typed back loosely.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (us *UserService) GetUserRoles(userUuid string) ([]string, *restErr.RestErr) {
+	return us.rr.GetUserRoles(userUuid)
+}
@@ -0,0 +1,110 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	"strings"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// The `APIClientUseCase` lets admins register the backend services that call the APIs without a user.
+type APIClientUseCase struct {
+	ar rp.PostgresAPIClientRepository
+}
+
+func NewAPIClientUseCase(ar rp.PostgresAPIClientRepository) usecase.APIClientUseCase {
+	return &APIClientUseCase{ar}
+}
+
+// CreateAPIClient replies with the secret of the client, which is only stored hashed and never shown again.
+func (acuc *APIClientUseCase) CreateAPIClient(c *fiber.Ctx) error {
+	var payload dto.APIClientInput
+	if err := c.BodyParser(&payload); err != nil {
+		clientErr := restErr.NewUnprocessableEntityError(errMsgInvalidJSON)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" || len(payload.Name) > 255 {
+		clientErr := restErr.NewBadRequestError(errMsgInvalidClientName)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	scopes, err := validateClientScopes(payload.Scopes)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	clientID, errUUID := uuid.NewRandom()
+	if errUUID != nil {
+		log.Error().Err(errUUID).Msg(restErr.ErrUUIDError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	secret, err := randtoken.Generate()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	client := &entity.APIClient{
+		ClientID:   clientID.String(),
+		SecretHash: randtoken.Hash(secret),
+		Name:       payload.Name,
+		Scopes:     scopes,
+	}
+
+	if err := acuc.ar.SaveAPIClient(client); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	record := toAPIClientRecord(client)
+	record.ClientSecret = secret
+
+	log.Info().Str("client_id", client.ClientID).Str("admin_uuid", adminUUID(c)).Msg("api client created")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "data": fiber.Map{"client": record}})
+}
+
+func (acuc *APIClientUseCase) ListAPIClients(c *fiber.Ctx) error {
+	clients, err := acuc.ar.GetAPIClients()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	records := make([]*dto.APIClientRecord, 0, len(clients))
+	for _, client := range clients {
+		records = append(records, toAPIClientRecord(client))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"clients": records}})
+}
+
+// DeleteAPIClient stops the client from getting new tokens; the ones it holds stay valid until they expire.
+func (acuc *APIClientUseCase) DeleteAPIClient(c *fiber.Ctx) error {
+	clientID := c.Params("client_id")
+	if err := acuc.ar.DeleteAPIClient(clientID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("client_id", clientID).Str("admin_uuid", adminUUID(c)).Msg("api client deleted")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+func toAPIClientRecord(client *entity.APIClient) *dto.APIClientRecord {
+	return &dto.APIClientRecord{
+		ClientID:  client.ClientID,
+		Name:      client.Name,
+		Scopes:    client.Scopes,
+		CreatedAt: client.CreatedAt,
+	}
+}
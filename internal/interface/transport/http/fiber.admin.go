@@ -0,0 +1,117 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgInvalidQuery    = "invalid query string"
+	errMsgCannotAffectOwn = "admins cannot disable or delete their own account"
+)
+
+// The `AdminUseCase` lets admins manage the users; each route is guarded by a permission in the router.
+type AdminUseCase struct {
+	r  r.RedisUserRepository
+	us appSvc.UserService
+}
+
+func NewAdminUseCase(r r.RedisUserRepository, us appSvc.UserService) usecase.AdminUseCase {
+	return &AdminUseCase{r, us}
+}
+
+func (aduc *AdminUseCase) ListUsers(c *fiber.Ctx) error {
+	var payload dto.ListUsersInput
+	if err := c.QueryParser(&payload); err != nil {
+		clientErr := restErr.NewBadRequestError(errMsgInvalidQuery)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	page, err := aduc.us.ListUsers(payload)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": page})
+}
+
+func (aduc *AdminUseCase) GetUser(c *fiber.Ctx) error {
+	user, err := aduc.us.GetAdminUserRecord(c.Params("uuid"))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"user": user}})
+}
+
+// DisableUser also revokes every session of the user so that their tokens stop working right away.
+func (aduc *AdminUseCase) DisableUser(c *fiber.Ctx) error {
+	if err := aduc.checkNotSelf(c); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	user, err := aduc.us.SetUserDisabled(c.Params("uuid"), true)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := aduc.r.DelUserSessions(user.UUID.String()); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("user_uuid", user.UUID.String()).Str("admin_uuid", adminUUID(c)).Msg("user disabled")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"user": user}})
+}
+
+func (aduc *AdminUseCase) EnableUser(c *fiber.Ctx) error {
+	user, err := aduc.us.SetUserDisabled(c.Params("uuid"), false)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("user_uuid", user.UUID.String()).Str("admin_uuid", adminUUID(c)).Msg("user enabled")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"user": user}})
+}
+
+func (aduc *AdminUseCase) DeleteUser(c *fiber.Ctx) error {
+	if err := aduc.checkNotSelf(c); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	userUUID := c.Params("uuid")
+	if err := aduc.us.DeleteUser(userUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := aduc.r.DelUserSessions(userUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("user_uuid", userUUID).Str("admin_uuid", adminUUID(c)).Msg("user deleted")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// checkNotSelf keeps admins from locking themselves out, which would leave the service without an admin.
+func (aduc *AdminUseCase) checkNotSelf(c *fiber.Ctx) *restErr.RestErr {
+	if adminUUID(c) == c.Params("uuid") {
+		return restErr.NewBadRequestError(errMsgCannotAffectOwn)
+	}
+
+	return nil
+}
+
+func adminUUID(c *fiber.Ctx) string {
+	if userRecord, ok := c.Locals("userRecord").(*dto.UserRecord); ok && userRecord.UUID != nil {
+		return userRecord.UUID.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,178 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgInvalidJSON         = "invalid json body"
+	errMsgInvalidClientName   = "the field [name] should be between 1 and 255 characters long"
+	errMsgInvalidRedirectURIs = "the field [redirect_uris] should list absolute URIs without a fragment; " +
+		"http is only allowed for localhost"
+	errMsgInvalidClientScopes = "the field [scopes] should list at least one scope without spaces"
+)
+
+// The `OAuthClientUseCase` lets admins register the apps that delegate the login of their users to this service.
+type OAuthClientUseCase struct {
+	cr rp.PostgresOAuthClientRepository
+}
+
+func NewOAuthClientUseCase(cr rp.PostgresOAuthClientRepository) usecase.OAuthClientUseCase {
+	return &OAuthClientUseCase{cr}
+}
+
+// CreateClient replies with the secret of a confidential client, which is only stored hashed and never shown again.
+func (ocuc *OAuthClientUseCase) CreateClient(c *fiber.Ctx) error {
+	var payload dto.OAuthClientInput
+	if err := c.BodyParser(&payload); err != nil {
+		clientErr := restErr.NewUnprocessableEntityError(errMsgInvalidJSON)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	if err := validateOAuthClientInput(&payload); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	clientID, errUUID := uuid.NewRandom()
+	if errUUID != nil {
+		log.Error().Err(errUUID).Msg(restErr.ErrUUIDError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	client := &entity.OAuthClient{
+		ClientID:     clientID.String(),
+		Name:         payload.Name,
+		RedirectURIs: payload.RedirectURIs,
+		Scopes:       payload.Scopes,
+	}
+
+	var clientSecret string
+	if !payload.Public {
+		secret, err := randtoken.Generate()
+		if err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		clientSecret = secret
+		client.SecretHash = randtoken.Hash(secret)
+	}
+
+	if err := ocuc.cr.SaveClient(client); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	record := toOAuthClientRecord(client)
+	record.ClientSecret = clientSecret
+
+	log.Info().Str("client_id", client.ClientID).Str("admin_uuid", adminUUID(c)).Msg("oauth client created")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "data": fiber.Map{"client": record}})
+}
+
+func (ocuc *OAuthClientUseCase) ListClients(c *fiber.Ctx) error {
+	clients, err := ocuc.cr.GetClients()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	records := make([]*dto.OAuthClientRecord, 0, len(clients))
+	for _, client := range clients {
+		records = append(records, toOAuthClientRecord(client))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"clients": records}})
+}
+
+/*
+DeleteClient also deletes the consents given to the client. Its access tokens stay valid until they expire,
+while its authorization codes and refresh tokens are rejected as they are only redeemed by a registered client.
+*/
+func (ocuc *OAuthClientUseCase) DeleteClient(c *fiber.Ctx) error {
+	clientID := c.Params("client_id")
+	if err := ocuc.cr.DeleteClient(clientID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("client_id", clientID).Str("admin_uuid", adminUUID(c)).Msg("oauth client deleted")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+/*
+validateOAuthClientInput checks the redirect URIs as they are compared verbatim by `/oauth/authorize`.
+Plain http is only accepted on localhost, e.g. for native apps and development.
+*/
+func validateOAuthClientInput(payload *dto.OAuthClientInput) *restErr.RestErr {
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" || len(payload.Name) > 255 {
+		return restErr.NewBadRequestError(errMsgInvalidClientName)
+	}
+
+	if len(payload.RedirectURIs) == 0 {
+		return restErr.NewBadRequestError(errMsgInvalidRedirectURIs)
+	}
+
+	for _, redirectURI := range payload.RedirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !u.IsAbs() || u.Fragment != "" || (u.Scheme == "https" && u.Host == "") {
+			return restErr.NewBadRequestError(errMsgInvalidRedirectURIs)
+		}
+
+		if u.Scheme == "http" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+			return restErr.NewBadRequestError(errMsgInvalidRedirectURIs)
+		}
+	}
+
+	scopes, err := validateClientScopes(payload.Scopes)
+	if err != nil {
+		return err
+	}
+
+	payload.Scopes = scopes
+	return nil
+}
+
+// validateClientScopes returns the scopes without duplicates, as they are joined with spaces in the tokens.
+func validateClientScopes(clientScopes []string) ([]string, *restErr.RestErr) {
+	scopes := []string{}
+	for _, scope := range clientScopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\n") {
+			return nil, restErr.NewBadRequestError(errMsgInvalidClientScopes)
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, restErr.NewBadRequestError(errMsgInvalidClientScopes)
+	}
+
+	return scopes, nil
+}
+
+func toOAuthClientRecord(client *entity.OAuthClient) *dto.OAuthClientRecord {
+	return &dto.OAuthClientRecord{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		Public:       client.IsPublic(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,167 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// apiKeyPrefix tells the API keys apart from other secrets, e.g. for secret scanners
+	apiKeyPrefix       = "ak_"
+	apiKeyVisibleChars = 8 // Characters of the key after `apiKeyPrefix` that are kept in clear
+
+	errMsgInvalidAPIKeyScopes = "the field [scopes] should only list permissions of the user"
+)
+
+// The `APIKeyUseCase` lets users manage the personal API keys that the `Deserializer` accepts instead of a JWT.
+type APIKeyUseCase struct {
+	kr rp.PostgresAPIKeyRepository
+	rr rp.PostgresRoleRepository
+}
+
+func NewAPIKeyUseCase(kr rp.PostgresAPIKeyRepository, rr rp.PostgresRoleRepository) usecase.APIKeyUseCase {
+	return &APIKeyUseCase{kr, rr}
+}
+
+/*
+CreateAPIKey replies with the key, which is only stored hashed and never shown again.
+`PreProcessInputs` only lets through known permissions as scopes; the user must also have them through their roles.
+*/
+func (akuc *APIKeyUseCase) CreateAPIKey(c *fiber.Ctx) error {
+	payload, ok := c.Locals("api_key_payload").(dto.APIKeyInput)
+	if !ok {
+		clientErr := restErr.NewUnprocessableEntityError(errMsgInvalidJSON)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+	if err := akuc.checkAPIKeyScopes(userRecord, &payload); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	secret, err := randtoken.Generate()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	key := apiKeyPrefix + secret
+	userUUID := userRecord.UUID.String()
+	apiKey := &entity.APIKey{
+		UserUUID:  userUUID,
+		Name:      payload.Name,
+		Prefix:    key[:len(apiKeyPrefix)+apiKeyVisibleChars],
+		KeyHash:   randtoken.Hash(key),
+		Scopes:    payload.Scopes,
+		ExpiresAt: payload.ExpiresAt,
+	}
+
+	if err := akuc.kr.SaveAPIKey(apiKey); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	record := toAPIKeyRecord(apiKey)
+	record.Key = key
+
+	log.Info().Str("user_uuid", userUUID).Str("api_key_id", apiKey.ID).Msg("api key created")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "data": fiber.Map{"api_key": record}})
+}
+
+func (akuc *APIKeyUseCase) ListAPIKeys(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	userUUID := userRecord.UUID.String()
+	apiKeys, err := akuc.kr.GetAPIKeys(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	records := make([]*dto.APIKeyRecord, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		records = append(records, toAPIKeyRecord(apiKey))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"api_keys": records}})
+}
+
+// RevokeAPIKey deletes the key, which is rejected from the next request on.
+func (akuc *APIKeyUseCase) RevokeAPIKey(c *fiber.Ctx) error {
+	keyID := c.Params("id")
+	if _, err := uuid.Parse(keyID); err != nil {
+		notFoundErr := restErr.NewNotFoundError(restErr.ErrMsgAPIKeyNotFound)
+		return c.Status(notFoundErr.Status).JSON(fiber.Map{"status": "fail", "error": notFoundErr})
+	}
+
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	userUUID := userRecord.UUID.String()
+	if err := akuc.kr.DeleteAPIKey(userUUID, keyID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("user_uuid", userUUID).Str("api_key_id", keyID).Msg("api key revoked")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// checkAPIKeyScopes accepts an empty scope list, which keeps the key off every route that requires a permission.
+func (akuc *APIKeyUseCase) checkAPIKeyScopes(userRecord *dto.UserRecord, payload *dto.APIKeyInput) *restErr.RestErr {
+	payload.Scopes = uniqueScope(payload.Scopes)
+	granted := []string{}
+	if len(payload.Scopes) > 0 && len(userRecord.Roles) > 0 {
+		var err *restErr.RestErr
+		if granted, err = akuc.rr.GetPermissions(userRecord.Roles); err != nil {
+			return err
+		}
+	}
+
+	if !isSubset(payload.Scopes, granted) {
+		return restErr.NewBadRequestError(errMsgInvalidAPIKeyScopes)
+	}
+
+	if payload.ExpiresAt != nil {
+		expiresAt := payload.ExpiresAt.UTC()
+		payload.ExpiresAt = &expiresAt
+	}
+
+	return nil
+}
+
+func toAPIKeyRecord(apiKey *entity.APIKey) *dto.APIKeyRecord {
+	return &dto.APIKeyRecord{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		LastUsedIP: apiKey.LastUsedIP,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -3,16 +3,22 @@
 package http
 
 import (
+	"fmt"
+	"net/url"
+	"sort"
 	"time"
 
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
 	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
 	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
 	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,20 +26,37 @@ const (
 	errMsgRegisterPayload = "register_payload is not of type users.RegisterInput"
 	errMsgLoginPayload    = "login_payload is not of type users.RegisterInput"
 	errMsgAccessTokenUUID = "accessTokenUUID is not a string or not set"
+	errMsgUserRecord      = "userRecord is not of type *dto.UserRecord or not set"
+	errMsgEmailPayload    = "email_payload is not of type dto.EmailInput"
+	errMsgBaseURLsConfig  = "baseURLsConfig is not of type *entity.BaseURLsConfig or not set"
+	errMsgResetPayload    = "reset_password_payload is not of type dto.ResetPasswordInput"
+	errMsgChangePayload   = "change_password_payload is not of type dto.ChangePasswordInput"
+
+	msgVerificationEmailSent = "if the account exists and is not verified yet, a verification email has been sent"
+	verificationEmailSubject = "Verify your email address"
+	verificationEmailBody    = "Hi %s,\n\nPlease verify your email address by opening the link below within %s.\n\n%s\n"
+	msgPasswordResetSent     = "if the account exists, a password reset email has been sent"
+	passwordResetSubject     = "Reset your password"
+	passwordResetBody        = "Hi %s,\n\nReset your password by opening the link below within %s.\n" +
+		"If you did not request a password reset, you can ignore this email.\n\n%s\n"
 )
 
 type AuthUseCase struct {
 	r  r.RedisUserRepository
 	us appSvc.UserService
 	ts domainSvc.TokenService
+	m  domainSvc.Mailer
+	ac *entity.AccountConfig
 }
 
 func NewAuthUseCase(
 	r r.RedisUserRepository,
 	us appSvc.UserService,
 	ts domainSvc.TokenService,
+	m domainSvc.Mailer,
+	ac *entity.AccountConfig,
 ) usecase.AuthUseCase {
-	return &AuthUseCase{r, us, ts}
+	return &AuthUseCase{r, us, ts, m, ac}
 }
 
 func (auc *AuthUseCase) Register(c *fiber.Ctx) error {
@@ -48,6 +71,11 @@ func (auc *AuthUseCase) Register(c *fiber.Ctx) error {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
+	// The account is created regardless; the verification email can be resent
+	if err := auc.sendVerificationEmail(c, result.UUID.String(), result.FirstName, result.Email); err != nil {
+		log.Error().Err(err).Str("user_uuid", result.UUID.String()).Msg(restErr.ErrMsgMailerError)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "user": result})
 }
 
@@ -63,71 +91,29 @@ func (auc *AuthUseCase) Login(c *fiber.Ctx) error {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	jwtConfig := auc.us.GetJWTConfig()
-	accessTokenDetails, err := auc.ts.CreateToken(
-		user.UUID.String(),
-		jwtConfig.AccessTokenExpiredIn,
-		jwtConfig.AccessTokenPrivateKey,
-	)
-	if err != nil {
+	if user.DisabledAt != nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	refreshTokenDetails, err := auc.ts.CreateToken(
-		user.UUID.String(),
-		jwtConfig.RefreshTokenExpiredIn,
-		jwtConfig.RefreshTokenPrivateKey,
-	)
-	if err != nil {
+	if auc.ac.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgEmailNotVerified)
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	errAccess := auc.r.SetUserUUID(
-		accessTokenDetails.TokenUUID,
-		user.UUID.String(),
-		*accessTokenDetails.ExpiresIn,
-	)
-
-	if errAccess != nil {
-		return c.Status(errAccess.Status).JSON(fiber.Map{"status": "fail", "error": errAccess})
+	if user.TOTPEnabledAt != nil {
+		return auc.startMFAChallenge(c, user.UUID.String())
 	}
 
-	errRefresh := auc.r.SetUserUUID(
-		refreshTokenDetails.TokenUUID,
-		user.UUID.String(),
-		*refreshTokenDetails.ExpiresIn,
-	)
-
-	if errRefresh != nil {
-		return c.Status(errRefresh.Status).JSON(fiber.Map{"status": "fail", "error": errRefresh})
-	}
-
-	c.Cookie(&fiber.Cookie{
-		Name:     "access_token",
-		Value:    *accessTokenDetails.Token,
-		Path:     "/",
-		Domain:   jwtConfig.Domain,
-		MaxAge:   jwtConfig.AccessTokenMaxAge * 60,
-		Secure:   jwtConfig.Secure,
-		HTTPOnly: jwtConfig.HttpOnly,
-		SameSite: "strict",
-	})
-
-	c.Cookie(&fiber.Cookie{
-		Name:     "refresh_token",
-		Value:    *refreshTokenDetails.Token,
-		Path:     "/",
-		Domain:   jwtConfig.Domain,
-		MaxAge:   jwtConfig.RefreshTokenMaxAge * 60,
-		Secure:   jwtConfig.Secure,
-		HTTPOnly: jwtConfig.HttpOnly,
-		SameSite: "strict",
-	})
-
-	return c.Status(fiber.StatusOK).
-		JSON(fiber.Map{"status": "success", "access_token": accessTokenDetails.Token})
+	return auc.startSession(c, user.UUID.String())
 }
 
+/*
+RefreshAccessToken rotates the refresh token on every call.
+The presented refresh token is consumed and a new access/refresh token pair is issued within the same family.
+Presenting a refresh token that has already been rotated means it has leaked,
+so the whole family is revoked and the user has to login again.
+*/
 func (auc *AuthUseCase) RefreshAccessToken(c *fiber.Ctx) error {
 	refresh_token := c.Cookies("refresh_token")
 
@@ -137,14 +123,48 @@ func (auc *AuthUseCase) RefreshAccessToken(c *fiber.Ctx) error {
 	}
 
 	jwtConfig := auc.us.GetJWTConfig()
-	tokenClaims, err := auc.ts.ValidateToken(refresh_token, jwtConfig.RefreshTokenPublicKey)
+	tokenClaims, err := auc.ts.ValidateToken(refresh_token, jwtConfig.RefreshTokenKeyRing)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// Refresh tokens issued before token families were introduced have none
+	familyID, err := auc.r.GetTokenFamily(tokenClaims.TokenUUID)
+	if err != nil && err.Status != fiber.StatusUnauthorized {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+	legacy := err != nil
+
+	userUuid, err := auc.r.ConsumeRefreshToken(tokenClaims.TokenUUID)
 	if err != nil {
+		if err.Status == fiber.StatusUnauthorized {
+			// A refresh token without a family has never been refreshed, so it has expired or been revoked
+			if !legacy {
+				log.Warn().
+					Str("family_id", familyID).
+					Str("user_uuid", tokenClaims.UserUUID).
+					Msg(restErr.ErrMsgRefreshTokenReuse)
+				auc.r.DelTokenFamily(familyID)
+			}
+			clearTokenCookies(c, auc.us.GetJWTConfig())
+		}
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	userUuid, errGetTokenUUID := auc.r.GetUserUUID(tokenClaims.TokenUUID)
-	if errGetTokenUUID != nil {
-		return c.Status(errGetTokenUUID.Status).JSON(fiber.Map{"status": "fail", "error": errGetTokenUUID})
+	// A legacy refresh token is adopted into a new family so that presenting it again is detected as a reuse
+	if legacy {
+		newFamilyID, errUUID := uuid.NewV7()
+		if errUUID != nil {
+			log.Error().Err(errUUID).Msg(restErr.ErrUUIDError)
+			err := restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		familyID = newFamilyID.String()
+		expiresIn := time.Now().Add(jwtConfig.RefreshTokenExpiredIn).Unix()
+		if err := auc.r.SetTokenFamily(familyID, tokenClaims.TokenUUID, expiresIn); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
 	}
 
 	user, err := auc.us.GetUserByUUID(userUuid)
@@ -152,36 +172,43 @@ func (auc *AuthUseCase) RefreshAccessToken(c *fiber.Ctx) error {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	accessTokenDetails, err := auc.ts.CreateToken(
-		user.UUID.String(),
-		jwtConfig.AccessTokenExpiredIn,
-		jwtConfig.AccessTokenPrivateKey,
-	)
+	// Disabling the user revokes its sessions, this only catches a refresh racing with it
+	if user.DisabledAt != nil {
+		auc.r.DelTokenFamily(familyID)
+		clearTokenCookies(c, auc.us.GetJWTConfig())
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	accessTokenDetails, refreshTokenDetails, err := auc.createTokenPair(user.UUID.String(), familyID, tokenClaims.Scope)
 	if err != nil {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	errAccess := auc.r.SetUserUUID(
-		accessTokenDetails.TokenUUID,
-		user.UUID.String(),
-		*accessTokenDetails.ExpiresIn,
-	)
+	/*
+		A concurrent refresh with the same token is a reuse which revokes the family, possibly before the new tokens
+		were added to it. Revoking the family drops the family of the consumed token, so the new tokens are revoked too.
+	*/
+	if _, err := auc.r.GetTokenFamily(tokenClaims.TokenUUID); err != nil {
+		auc.r.DelTokenFamily(familyID)
+		auc.r.DelUserUUID(refreshTokenDetails.TokenUUID, accessTokenDetails.TokenUUID)
+		clearTokenCookies(c, auc.us.GetJWTConfig())
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
 
-	if errAccess != nil {
-		return c.Status(errAccess.Status).JSON(fiber.Map{"status": "fail", "error": errAccess})
+	// Families issued before sessions were recorded have no session yet
+	session, err := auc.r.GetSession(familyID)
+	if err != nil && err.Status != fiber.StatusNotFound {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	} else if err != nil {
+		session = &entity.Session{ID: familyID, UserUUID: user.UUID.String(), CreatedAt: time.Now().UTC()}
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name:     "access_token",
-		Value:    *accessTokenDetails.Token,
-		Path:     "/",
-		Domain:   jwtConfig.Domain,
-		MaxAge:   jwtConfig.AccessTokenMaxAge * 60,
-		Secure:   jwtConfig.Secure,
-		HTTPOnly: jwtConfig.HttpOnly,
-		SameSite: "strict",
-	})
+	if err := auc.saveSession(c, session, *refreshTokenDetails.ExpiresIn); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
 
+	auc.setTokenCookies(c, accessTokenDetails, refreshTokenDetails)
 	return c.Status(fiber.StatusOK).
 		JSON(fiber.Map{"status": "success", "access_token": accessTokenDetails.Token})
 }
@@ -195,7 +222,7 @@ func (auc *AuthUseCase) Logout(c *fiber.Ctx) error {
 	}
 
 	jwtConfig := auc.us.GetJWTConfig()
-	tokenClaims, err := auc.ts.ValidateToken(refresh_token, jwtConfig.RefreshTokenPublicKey)
+	tokenClaims, err := auc.ts.ValidateToken(refresh_token, jwtConfig.RefreshTokenKeyRing)
 	if err != nil {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
@@ -208,20 +235,448 @@ func (auc *AuthUseCase) Logout(c *fiber.Ctx) error {
 		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
 	}
 
+	if familyID, err := auc.r.GetTokenFamily(tokenClaims.TokenUUID); err == nil {
+		auc.r.DelTokenFamily(familyID)
+	}
+
 	auc.r.DelUserUUID(tokenClaims.TokenUUID, accessTokenUUID)
-	expired := time.Now().Add(-time.Hour * 24)
+	clearTokenCookies(c, auc.us.GetJWTConfig())
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
 
-	c.Cookie(&fiber.Cookie{
-		Name:    "access_token",
-		Value:   "",
-		Expires: expired,
-	})
+// VerifyEmail consumes the token of the link sent by `sendVerificationEmail`.
+func (auc *AuthUseCase) VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		err := restErr.NewBadRequestError(restErr.ErrMsgInvalidToken)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	keyRing, err := auc.emailVerificationKeyRing(c)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	tokenClaims, err := auc.ts.ValidateToken(token, keyRing)
+	if err != nil {
+		clientErr := restErr.NewBadRequestError(restErr.ErrMsgInvalidToken)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	if err := auc.r.ConsumeEmailVerification(tokenClaims.UserUUID, tokenClaims.TokenUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.us.VerifyEmail(tokenClaims.UserUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+/*
+ResendVerificationEmail replaces any pending verification email with a new one.
+The response is the same whether or not the account exists so that it cannot be used to probe emails.
+*/
+func (auc *AuthUseCase) ResendVerificationEmail(c *fiber.Ctx) error {
+	payload, ok := c.Locals("email_payload").(dto.EmailInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgEmailPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	user, err := auc.us.FindUserByEmail(payload.Email)
+	if err != nil && err.Status != fiber.StatusBadRequest {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err == nil && user.EmailVerifiedAt == nil {
+		if err := auc.sendVerificationEmail(c, user.UUID.String(), user.FirstName, user.Email); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": msgVerificationEmailSent})
+}
+
+/*
+ForgotPassword mails a single-use reset link.
+The response is the same whether or not the account exists so that it cannot be used to probe emails.
+*/
+func (auc *AuthUseCase) ForgotPassword(c *fiber.Ctx) error {
+	payload, ok := c.Locals("email_payload").(dto.EmailInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgEmailPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	user, err := auc.us.FindUserByEmail(payload.Email)
+	if err != nil && err.Status != fiber.StatusBadRequest {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err == nil {
+		if err := auc.sendPasswordResetEmail(user); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": msgPasswordResetSent})
+}
+
+// ResetPassword sets the new password and revokes every session, as the old password may have been compromised.
+func (auc *AuthUseCase) ResetPassword(c *fiber.Ctx) error {
+	payload, ok := c.Locals("reset_password_payload").(dto.ResetPasswordInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgResetPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	userUUID, err := auc.r.ConsumePasswordReset(randtoken.Hash(payload.Token))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.us.ResetPassword(userUUID, payload.NewPassword); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.r.DelUserSessions(userUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	clearTokenCookies(c, auc.us.GetJWTConfig())
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// ChangePassword revokes every other session of the user while keeping the current one signed in.
+func (auc *AuthUseCase) ChangePassword(c *fiber.Ctx) error {
+	payload, ok := c.Locals("change_password_payload").(dto.ChangePasswordInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgChangePayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	if err := auc.us.ChangePassword(userRecord.UUID.String(), payload); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.r.DelUserSessions(userRecord.UUID.String(), currentSessionID(c, auc.r)); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
 
-	c.Cookie(&fiber.Cookie{
-		Name:    "refresh_token",
-		Value:   "",
-		Expires: expired,
+/*
+GetJWKS publishes the active and retired access token public keys as a JWK Set so that other services
+can verify access tokens by their `kid` header without sharing env files.
+*/
+func (auc *AuthUseCase) GetJWKS(c *fiber.Ctx) error {
+	jwtConfig := auc.us.GetJWTConfig()
+	jwks, err := auc.ts.GetJWKS(jwtConfig.AccessTokenKeyRing)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(jwks)
+}
+
+// GetSessions lists the active sessions of the user, flagging the one making the request.
+func (auc *AuthUseCase) GetSessions(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	sessions, err := auc.r.GetSessions(userRecord.UUID.String())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	currentSessionID := currentSessionID(c, auc.r)
+	sessionRecords := make([]dto.SessionRecord, 0, len(sessions))
+	for _, session := range sessions {
+		sessionRecords = append(sessionRecords, dto.SessionRecord{
+			ID:              session.ID,
+			CreatedAt:       session.CreatedAt,
+			LastRefreshedAt: session.LastRefreshedAt,
+			IPAddress:       session.IPAddress,
+			UserAgent:       session.UserAgent,
+			Current:         session.ID == currentSessionID,
+		})
+	}
+
+	sort.Slice(sessionRecords, func(i, j int) bool {
+		return sessionRecords[i].LastRefreshedAt.After(sessionRecords[j].LastRefreshedAt)
 	})
 
+	return c.Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "data": fiber.Map{"sessions": sessionRecords}})
+}
+
+// RevokeSession revokes one of the user's sessions, e.g. a lost device, by revoking its token family.
+func (auc *AuthUseCase) RevokeSession(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	session, err := auc.r.GetSession(c.Params("id"))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// Sessions of other users are reported as missing rather than forbidden so that their IDs cannot be probed
+	if session.UserUUID != userRecord.UUID.String() {
+		err := restErr.NewNotFoundError(restErr.ErrMsgSessionNotFound)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.r.DelTokenFamily(session.ID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if session.ID == currentSessionID(c, auc.r) {
+		clearTokenCookies(c, auc.us.GetJWTConfig())
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
 }
+
+// LogoutAll revokes every session of the user, including the current one.
+func (auc *AuthUseCase) LogoutAll(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	if err := auc.r.DelUserSessions(userRecord.UUID.String()); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	clearTokenCookies(c, auc.us.GetJWTConfig())
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// sendVerificationEmail mails a signed verification link and registers its token as the only valid one.
+func (auc *AuthUseCase) sendVerificationEmail(c *fiber.Ctx, userUUID string, firstName string, email string) *restErr.RestErr {
+	keyRing, err := auc.emailVerificationKeyRing(c)
+	if err != nil {
+		return err
+	}
+
+	tokenDetails, err := auc.ts.CreateToken(userUUID, nil, nil, auc.ac.EmailVerificationExpiredIn, keyRing)
+	if err != nil {
+		return err
+	}
+
+	if err := auc.r.SetEmailVerification(userUUID, tokenDetails.TokenUUID, *tokenDetails.ExpiresIn); err != nil {
+		return err
+	}
+
+	link := keyRing.Audience + "?token=" + url.QueryEscape(*tokenDetails.Token)
+	return auc.m.Send(&entity.Mail{
+		To:      email,
+		Subject: verificationEmailSubject,
+		Body:    fmt.Sprintf(verificationEmailBody, firstName, auc.ac.EmailVerificationExpiredIn, link),
+	})
+}
+
+// sendPasswordResetEmail mails a reset link; only the hash of its token is kept in Redis.
+func (auc *AuthUseCase) sendPasswordResetEmail(user *entity.User) *restErr.RestErr {
+	token, err := randtoken.Generate()
+	if err != nil {
+		return err
+	}
+
+	expiresIn := time.Now().Add(auc.ac.PasswordResetExpiredIn).Unix()
+	if err := auc.r.SetPasswordReset(user.UUID.String(), randtoken.Hash(token), expiresIn); err != nil {
+		return err
+	}
+
+	link := auc.ac.PasswordResetURL + "?token=" + token
+	return auc.m.Send(&entity.Mail{
+		To:      user.Email,
+		Subject: passwordResetSubject,
+		Body:    fmt.Sprintf(passwordResetBody, user.FirstName, auc.ac.PasswordResetExpiredIn, link),
+	})
+}
+
+/*
+emailVerificationKeyRing signs verification tokens with the access token keys.
+Its audience is the verification endpoint, so neither token type is accepted in place of the other.
+*/
+func (auc *AuthUseCase) emailVerificationKeyRing(c *fiber.Ctx) (*entity.JWTKeyRing, *restErr.RestErr) {
+	baseURLsConfig, ok := c.Locals("baseURLsConfig").(*entity.BaseURLsConfig)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgBaseURLsConfig)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		return nil, restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	keyRing := *auc.us.GetJWTConfig().AccessTokenKeyRing
+	keyRing.Audience = baseURLsConfig.AuthService + "/verify-email"
+	return &keyRing, nil
+}
+
+// startSession signs the user in with a new session and replies with the access token and the token cookies.
+func (auc *AuthUseCase) startSession(c *fiber.Ctx, userUUID string) error {
+	accessTokenDetails, err := auc.IssueSession(c, userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "access_token": accessTokenDetails.Token})
+}
+
+/*
+IssueSession starts a new session for a user who has been authenticated and sets the token cookies.
+It is shared with the login methods that reply with something other than JSON, e.g. the OAuth2 redirects.
+*/
+func (auc *AuthUseCase) IssueSession(c *fiber.Ctx, userUUID string) (*entity.Token, *restErr.RestErr) {
+	// Every login starts a new token family which is carried over on each refresh
+	familyID, errUUID := uuid.NewV7()
+	if errUUID != nil {
+		log.Error().Err(errUUID).Msg(restErr.ErrUUIDError)
+		return nil, restErr.NewUnprocessableEntityError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	accessTokenDetails, refreshTokenDetails, err := auc.createTokenPair(
+		userUUID, familyID.String(), auc.us.GetJWTConfig().DefaultScope)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &entity.Session{ID: familyID.String(), UserUUID: userUUID, CreatedAt: time.Now().UTC()}
+	if err := auc.saveSession(c, session, *refreshTokenDetails.ExpiresIn); err != nil {
+		return nil, err
+	}
+
+	auc.setTokenCookies(c, accessTokenDetails, refreshTokenDetails)
+	return accessTokenDetails, nil
+}
+
+// currentSessionID resolves the token family of the access token set by the `Deserializer`.
+func currentSessionID(c *fiber.Ctx, r r.RedisUserRepository) string {
+	accessTokenUUID, ok := c.Locals("accessTokenUUID").(string)
+	if !ok {
+		return ""
+	}
+
+	familyID, err := r.GetTokenFamily(accessTokenUUID)
+	if err != nil {
+		return ""
+	}
+
+	return familyID
+}
+
+// saveSession records the client of the latest login or refresh on the session.
+func (auc *AuthUseCase) saveSession(c *fiber.Ctx, session *entity.Session, expiresIn int64) *restErr.RestErr {
+	session.LastRefreshedAt = time.Now().UTC()
+	session.IPAddress = c.IP()
+	session.UserAgent = c.Get(fiber.HeaderUserAgent)
+	return auc.r.SetSession(session, expiresIn)
+}
+
+/*
+createTokenPair issues an access and a refresh token with the same `scope` and registers both in Redis under `familyID`.
+The refresh token carries the scope so that refreshing never widens it.
+Roles are read again on every refresh so that role changes apply once the access token expires.
+*/
+func (auc *AuthUseCase) createTokenPair(userUUID string, familyID string, scope []string) (
+	*entity.Token, *entity.Token, *restErr.RestErr) {
+	roles, err := auc.us.GetUserRoles(userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jwtConfig := auc.us.GetJWTConfig()
+	accessTokenDetails, err := auc.ts.CreateToken(
+		userUUID,
+		scope,
+		roles,
+		jwtConfig.AccessTokenExpiredIn,
+		jwtConfig.AccessTokenKeyRing,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshTokenDetails, err := auc.ts.CreateToken(
+		userUUID,
+		scope,
+		nil,
+		jwtConfig.RefreshTokenExpiredIn,
+		jwtConfig.RefreshTokenKeyRing,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The refresh token is registered last as it outlives the access token
+	for _, tokenDetails := range []*entity.Token{accessTokenDetails, refreshTokenDetails} {
+		if err := auc.r.SetUserUUID(tokenDetails.TokenUUID, userUUID, *tokenDetails.ExpiresIn); err != nil {
+			return nil, nil, err
+		}
+
+		if err := auc.r.SetTokenFamily(familyID, tokenDetails.TokenUUID, *tokenDetails.ExpiresIn); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return accessTokenDetails, refreshTokenDetails, nil
+}
+
+func (auc *AuthUseCase) setTokenCookies(c *fiber.Ctx, accessTokenDetails *entity.Token, refreshTokenDetails *entity.Token) {
+	jwtConfig := auc.us.GetJWTConfig()
+	c.Cookie(tokenCookie(jwtConfig, "access_token", *accessTokenDetails.Token, jwtConfig.AccessTokenMaxAge))
+	c.Cookie(tokenCookie(jwtConfig, "refresh_token", *refreshTokenDetails.Token, jwtConfig.RefreshTokenMaxAge))
+}
+
+// clearTokenCookies expires the token cookies, which browsers only match with the same path and domain.
+func clearTokenCookies(c *fiber.Ctx, jwtConfig *entity.JWTConfig) {
+	c.Cookie(tokenCookie(jwtConfig, "access_token", "", 0))
+	c.Cookie(tokenCookie(jwtConfig, "refresh_token", "", 0))
+}
+
+// tokenCookie sets the token for `maxAge` minutes, or expires the cookie when the token is empty.
+func tokenCookie(jwtConfig *entity.JWTConfig, name string, token string, maxAge int) *fiber.Cookie {
+	cookie := &fiber.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     "/",
+		Domain:   jwtConfig.Domain,
+		Secure:   jwtConfig.Secure,
+		HTTPOnly: jwtConfig.HttpOnly,
+		SameSite: "strict",
+	}
+
+	if token == "" {
+		cookie.Expires = time.Now().Add(-time.Hour * 24)
+	} else {
+		cookie.MaxAge = maxAge * 60
+	}
+
+	return cookie
+}
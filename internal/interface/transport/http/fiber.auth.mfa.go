@@ -0,0 +1,173 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgTOTPCodePayload = "totp_code_payload is not of type dto.TOTPCodeInput"
+	errMsgMFALoginPayload = "mfa_login_payload is not of type dto.MFALoginInput"
+
+	maxFailedMFAAttempts = 5 // The challenge is revoked after this many wrong codes
+	totpCodeLength       = 6
+)
+
+// EnrollTOTP starts the enrollment with a new secret that the user adds to an authenticator app.
+func (auc *AuthUseCase) EnrollTOTP(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	enrollment, err := auc.us.EnrollTOTP(userRecord.UUID.String())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "totp": enrollment})
+}
+
+// ConfirmTOTP enables 2FA once the first code from the authenticator app is valid.
+func (auc *AuthUseCase) ConfirmTOTP(c *fiber.Ctx) error {
+	payload, ok := c.Locals("totp_code_payload").(dto.TOTPCodeInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgTOTPCodePayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	recoveryCodes, err := auc.us.ConfirmTOTP(userRecord.UUID.String(), payload.Code)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "recovery_codes": recoveryCodes})
+}
+
+/*
+LoginMFA finishes a login that `Login` answered with `mfa_required`.
+The code is either the current TOTP code or one of the unused recovery codes.
+*/
+func (auc *AuthUseCase) LoginMFA(c *fiber.Ctx) error {
+	payload, ok := c.Locals("mfa_login_payload").(dto.MFALoginInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgMFALoginPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+	}
+
+	tokenHash := randtoken.Hash(payload.MFAToken)
+	userUUID, err := auc.r.GetMFAChallenge(tokenHash)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.verifyMFACode(userUUID, payload.Code); err != nil {
+		if err.Status == fiber.StatusUnauthorized {
+			auc.addFailedMFAAttempt(tokenHash)
+		}
+
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := auc.r.ConsumeMFAChallenge(tokenHash); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// The user may have been disabled after the password step
+	user, err := auc.us.GetUserByUUID(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if user.DisabledAt != nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return auc.startSession(c, userUUID)
+}
+
+// startMFAChallenge replies with a challenge token instead of a session once the password is verified.
+func (auc *AuthUseCase) startMFAChallenge(c *fiber.Ctx, userUUID string) error {
+	mfaToken, err := auc.IssueMFAChallenge(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":     "mfa_required",
+		"mfa_token":  mfaToken,
+		"expires_in": int64(auc.ac.MFAChallengeExpiredIn.Seconds()),
+	})
+}
+
+// IssueMFAChallenge returns the token that `LoginMFA` completes with the second factor of the user.
+func (auc *AuthUseCase) IssueMFAChallenge(userUUID string) (string, *restErr.RestErr) {
+	mfaToken, err := randtoken.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	expiresIn := time.Now().Add(auc.ac.MFAChallengeExpiredIn).Unix()
+	if err := auc.r.SetMFAChallenge(randtoken.Hash(mfaToken), userUUID, expiresIn); err != nil {
+		return "", err
+	}
+
+	return mfaToken, nil
+}
+
+func (auc *AuthUseCase) verifyMFACode(userUUID string, code string) *restErr.RestErr {
+	if !isTOTPCode(code) {
+		return auc.us.UseRecoveryCode(userUUID, code)
+	}
+
+	step, err := auc.us.ValidateTOTP(userUUID, code)
+	if err != nil {
+		return err
+	}
+
+	return auc.r.UseTOTPStep(userUUID, step)
+}
+
+func (auc *AuthUseCase) addFailedMFAAttempt(tokenHash string) {
+	failedAttempts, err := auc.r.AddFailedMFAAttempt(tokenHash)
+	if err != nil || failedAttempts < maxFailedMFAAttempts {
+		return
+	}
+
+	// Further guesses need the password again
+	auc.r.ConsumeMFAChallenge(tokenHash)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpCodeLength {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestAuthApp mounts the refresh route and a stand-in for the login methods that starts a session for `user`.
+func newTestAuthApp(user *entity.User) (*fiber.App, *AuthUseCase, *mockRedisUserRepository, *mockTokenService) {
+	r, ts := newMockRedisUserRepository(), newMockTokenService()
+	auc := NewAuthUseCase(r, newMockUserService(user), ts, nil, &entity.AccountConfig{}).(*AuthUseCase)
+
+	app := fiber.New()
+	app.Post("/login", func(c *fiber.Ctx) error { return auc.startSession(c, user.UUID.String()) })
+	app.Post("/refresh", auc.RefreshAccessToken)
+	return app, auc, r, ts
+}
+
+func refreshTokenCookie(t *testing.T, resp *http.Response) *http.Cookie {
+	t.Helper()
+
+	cookie := findCookie(resp, "refresh_token")
+	if resp.StatusCode != fiber.StatusOK || cookie == nil || cookie.Value == "" {
+		t.Fatalf("expected a refresh token with status '%d', got '%d'", fiber.StatusOK, resp.StatusCode)
+	}
+	return cookie
+}
+
+func TestRefreshAccessTokenRotation(t *testing.T) {
+	user := newMockUser(true, false)
+	app, _, r, _ := newTestAuthApp(user)
+
+	loginCookie := refreshTokenCookie(t, post(t, app, "/login", ""))
+	refreshedCookie := refreshTokenCookie(t, post(t, app, "/refresh", "", loginCookie))
+	if refreshedCookie.Value == loginCookie.Value {
+		t.Fatal("expected the refresh token to be rotated")
+	}
+
+	// The rotated token stays in the family and session of the login
+	if len(r.families) != 1 || len(r.sessions) != 1 {
+		t.Fatalf("expected a single family and session, got '%d' and '%d'", len(r.families), len(r.sessions))
+	}
+
+	refreshTokenCookie(t, post(t, app, "/refresh", "", refreshedCookie))
+}
+
+func TestRefreshAccessTokenReuse(t *testing.T) {
+	user := newMockUser(true, false)
+	app, _, r, _ := newTestAuthApp(user)
+
+	loginCookie := refreshTokenCookie(t, post(t, app, "/login", ""))
+	refreshedCookie := refreshTokenCookie(t, post(t, app, "/refresh", "", loginCookie))
+
+	resp := post(t, app, "/refresh", "", loginCookie)
+	expectError(t, resp, fiber.StatusUnauthorized, "please login again")
+	if cleared := findCookie(resp, "refresh_token"); cleared == nil || cleared.Value != "" {
+		t.Error("expected the refresh token cookie to be cleared")
+	}
+
+	// The whole family is revoked, including the lookups of its tokens
+	if len(r.families) != 0 || len(r.familyOf) != 0 || len(r.tokens) != 0 || len(r.sessions) != 0 {
+		t.Errorf("expected the token family to be revoked, got '%d' tokens", len(r.tokens))
+	}
+
+	expectError(t, post(t, app, "/refresh", "", refreshedCookie), fiber.StatusUnauthorized, "please login again")
+}
+
+func TestRefreshAccessTokenConcurrentReuse(t *testing.T) {
+	user := newMockUser(true, false)
+	app, _, r, _ := newTestAuthApp(user)
+	loginCookie := refreshTokenCookie(t, post(t, app, "/login", ""))
+
+	var wg sync.WaitGroup
+	responses := make([]*http.Response, 2)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = post(t, app, "/refresh", "", loginCookie)
+		}(i)
+	}
+	wg.Wait()
+
+	refreshed := []*http.Cookie{}
+	for _, resp := range responses {
+		if resp.StatusCode == fiber.StatusOK {
+			refreshed = append(refreshed, findCookie(resp, "refresh_token"))
+		} else if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("expected status '%d' or '%d', got '%d'", fiber.StatusOK, fiber.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+
+	// However the two refreshes interleave, the token is only exchanged once and the reuse revokes the family
+	if len(refreshed) > 1 {
+		t.Fatal("expected the refresh token to be exchanged at most once")
+	}
+
+	if len(r.tokens) != 0 || len(r.families) != 0 || len(r.sessions) != 0 {
+		t.Errorf("expected the token family to be revoked, got '%d' tokens", len(r.tokens))
+	}
+
+	for _, cookie := range refreshed {
+		expectError(t, post(t, app, "/refresh", "", cookie), fiber.StatusUnauthorized, "please login again")
+	}
+}
+
+func TestRefreshAccessTokenLegacy(t *testing.T) {
+	user := newMockUser(true, false)
+	app, auc, r, ts := newTestAuthApp(user)
+
+	// A refresh token issued before token families is only registered with its user
+	jwtConfig := auc.us.GetJWTConfig()
+	legacyToken, _ := ts.CreateToken(user.UUID.String(), jwtConfig.DefaultScope, nil, time.Hour,
+		jwtConfig.RefreshTokenKeyRing)
+	r.SetUserUUID(legacyToken.TokenUUID, user.UUID.String(), *legacyToken.ExpiresIn)
+	legacyCookie := &http.Cookie{Name: "refresh_token", Value: *legacyToken.Token}
+
+	refreshedCookie := refreshTokenCookie(t, post(t, app, "/refresh", "", legacyCookie))
+	if len(r.families) != 1 || len(r.sessions) != 1 {
+		t.Fatalf("expected the token to be adopted into a new family, got '%d' families", len(r.families))
+	}
+
+	// From then on the token is rotated like any other, and reusing it revokes its new family
+	refreshTokenCookie(t, post(t, app, "/refresh", "", refreshedCookie))
+	expectError(t, post(t, app, "/refresh", "", legacyCookie), fiber.StatusUnauthorized, "please login again")
+	if len(r.families) != 0 || len(r.tokens) != 0 {
+		t.Errorf("expected the adopted family to be revoked, got '%d' tokens", len(r.tokens))
+	}
+}
@@ -0,0 +1,657 @@
+// coverage:ignore file
+// Testing with integration test
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+	dumw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/deserialize_user"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+const (
+	errMsgInvalidRedirectURI = "redirect_uri is not registered for the client"
+	errMsgPKCERequired       = "code_challenge with the S256 code_challenge_method is required"
+	errMsgUnknownScope       = "the client is not allowed to request the scope"
+	errMsgInvalidClient      = "client authentication failed"
+	errMsgUnsupportedGrant   = "grant_type must be authorization_code, refresh_token or client_credentials"
+	errMsgRedirectURIChanged = "redirect_uri does not match the authorization request"
+	errMsgPKCEFailed         = "code_verifier does not match the code_challenge"
+	errMsgInvalidAccessToken = "the access token is invalid or expired"
+	errMsgOpenIDScope        = "the access token was not granted the openid scope"
+
+	// Error codes of RFC 6749 and OpenID Connect Core
+	oauthErrInvalidRequest          = "invalid_request"
+	oauthErrInvalidClient           = "invalid_client"
+	oauthErrInvalidGrant            = "invalid_grant"
+	oauthErrInvalidScope            = "invalid_scope"
+	oauthErrUnsupportedGrantType    = "unsupported_grant_type"
+	oauthErrUnsupportedResponseType = "unsupported_response_type"
+	oauthErrAccessDenied            = "access_denied"
+	oauthErrServerError             = "server_error"
+	oauthErrLoginRequired           = "login_required"
+	oauthErrConsentRequired         = "consent_required"
+	oauthErrInvalidToken            = "invalid_token"
+	oauthErrInsufficientScope       = "insufficient_scope"
+)
+
+/*
+The `OAuthServerUseCase` makes this service the OAuth 2.0 / OpenID Connect provider of other apps.
+The authorization code flow requires PKCE from every client, and the cookie session of the user
+is the login state of `/oauth/authorize`: users without one are sent to the login page of the SPA first.
+The API clients of backend services get tokens for themselves with the client credentials grant instead.
+*/
+type OAuthServerUseCase struct {
+	cr  rp.PostgresOAuthClientRepository
+	ar  rp.PostgresAPIClientRepository
+	or  r.RedisOAuthRepository
+	r   r.RedisUserRepository
+	us  appSvc.UserService
+	ts  domainSvc.TokenService
+	osc *entity.OAuthServerConfig
+}
+
+func NewOAuthServerUseCase(
+	cr rp.PostgresOAuthClientRepository,
+	ar rp.PostgresAPIClientRepository,
+	or r.RedisOAuthRepository,
+	r r.RedisUserRepository,
+	us appSvc.UserService,
+	ts domainSvc.TokenService,
+	osc *entity.OAuthServerConfig,
+) usecase.OAuthServerUseCase {
+	return &OAuthServerUseCase{cr, ar, or, r, us, ts, osc}
+}
+
+// oauthError is an error response of the token and userinfo endpoints in the format of RFC 6749 section 5.2.
+type oauthError struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *oauthError) reply(c *fiber.Ctx) error {
+	return c.Status(e.status).JSON(fiber.Map{"error": e.code, "error_description": e.description})
+}
+
+/*
+Authorize validates the client and its redirect URI before anything else, and replies with JSON when they are invalid
+so that the browser is never sent to a URI that was not registered. Every later error is sent to the client.
+*/
+func (osuc *OAuthServerUseCase) Authorize(c *fiber.Ctx) error {
+	client, err := osuc.cr.GetClient(c.Query("client_id"))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	redirectURI := c.Query("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		clientErr := restErr.NewBadRequestError(errMsgInvalidRedirectURI)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	grant := &entity.AuthorizationGrant{
+		ClientID:        client.ClientID,
+		RedirectURI:     redirectURI,
+		RedirectURISent: c.Query("redirect_uri") != "",
+		Scope:           uniqueScope(strings.Fields(c.Query("scope"))),
+		State:           c.Query("state"),
+		Nonce:           c.Query("nonce"),
+		CodeChallenge:   c.Query("code_challenge"),
+	}
+
+	if c.Query("response_type") != "code" {
+		return osuc.redirectError(c, grant, oauthErrUnsupportedResponseType, "")
+	}
+
+	if grant.CodeChallenge == "" || c.Query("code_challenge_method") != "S256" {
+		return osuc.redirectError(c, grant, oauthErrInvalidRequest, errMsgPKCERequired)
+	}
+
+	if len(grant.Scope) == 0 {
+		grant.Scope = client.Scopes
+	}
+
+	if !isSubset(grant.Scope, client.Scopes) {
+		return osuc.redirectError(c, grant, oauthErrInvalidScope, errMsgUnknownScope)
+	}
+
+	/*
+		The session cookies are SameSite=strict, so they are not sent when another site links here;
+		the login page, which is on the same site, then sends the browser back with them.
+	*/
+	prompt := strings.Fields(c.Query("prompt"))
+	user, err := dumw.LoggedInUser(c, osuc.r, osuc.ts, osuc.us)
+	if err != nil {
+		if slices.Contains(prompt, "none") {
+			return osuc.redirectError(c, grant, oauthErrLoginRequired, "")
+		}
+
+		// The login page sends the browser back here once logged in
+		returnTo := c.BaseURL() + c.OriginalURL()
+		return c.Redirect(osuc.osc.LoginURL+"?return_to="+url.QueryEscape(returnTo), fiber.StatusSeeOther)
+	}
+
+	grant.UserUUID = user.UUID.String()
+	consent, err := osuc.cr.GetConsent(grant.UserUUID, client.ClientID)
+	if err != nil {
+		return osuc.redirectError(c, grant, oauthErrServerError, "")
+	}
+
+	if isSubset(grant.Scope, consent) && !slices.Contains(prompt, "consent") {
+		redirectTo, err := osuc.issueAuthorizationCode(grant)
+		if err != nil {
+			return osuc.redirectError(c, grant, oauthErrServerError, "")
+		}
+
+		return c.Redirect(redirectTo, fiber.StatusSeeOther)
+	}
+
+	if slices.Contains(prompt, "none") {
+		return osuc.redirectError(c, grant, oauthErrConsentRequired, "")
+	}
+
+	requestID, err := randtoken.Generate()
+	if err != nil {
+		return osuc.redirectError(c, grant, oauthErrServerError, "")
+	}
+
+	expiresIn := time.Now().Add(osuc.osc.AuthorizationRequestExpiredIn).Unix()
+	if err := osuc.or.SetConsentRequest(randtoken.Hash(requestID), grant, expiresIn); err != nil {
+		return osuc.redirectError(c, grant, oauthErrServerError, "")
+	}
+
+	return c.Redirect(osuc.osc.ConsentURL+"?consent_request="+requestID, fiber.StatusSeeOther)
+}
+
+// GetConsentRequest tells the consent page which client asks for which scopes.
+func (osuc *OAuthServerUseCase) GetConsentRequest(c *fiber.Ctx) error {
+	grant, err := osuc.or.GetConsentRequest(randtoken.Hash(c.Params("id")))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := checkConsentRequestUser(c, grant); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	client, err := osuc.cr.GetClient(grant.ClientID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	consentRequest := &dto.ConsentRequestRecord{ClientID: client.ClientID, ClientName: client.Name, Scopes: grant.Scope}
+	return c.Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "data": fiber.Map{"consent_request": consentRequest}})
+}
+
+/*
+AnswerConsentRequest records the consent of the user, or their refusal, and replies with the URL of the client
+that the consent page sends the browser to: with an authorization code, or with the `access_denied` error.
+*/
+func (osuc *OAuthServerUseCase) AnswerConsentRequest(c *fiber.Ctx) error {
+	var payload dto.ConsentInput
+	if err := c.BodyParser(&payload); err != nil {
+		clientErr := restErr.NewUnprocessableEntityError(errMsgInvalidJSON)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	grant, err := osuc.or.ConsumeConsentRequest(randtoken.Hash(c.Params("id")))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := checkConsentRequestUser(c, grant); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if !payload.Approve {
+		redirectTo := osuc.authorizationResponseURL(grant, url.Values{"error": {oauthErrAccessDenied}})
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"redirect_to": redirectTo}})
+	}
+
+	if err := osuc.cr.SaveConsent(grant.UserUUID, grant.ClientID, grant.Scope); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	redirectTo, err := osuc.issueAuthorizationCode(grant)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	log.Info().Str("user_uuid", grant.UserUUID).Str("client_id", grant.ClientID).Msg("oauth consent granted")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{"redirect_to": redirectTo}})
+}
+
+/*
+Token redeems an authorization code or a refresh token for an access token, a rotated refresh token and an ID token.
+The client credentials grant is only for API clients, which are registered apart from the OAuth clients.
+*/
+func (osuc *OAuthServerUseCase) Token(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+
+	if c.FormValue("grant_type") == "client_credentials" {
+		return osuc.issueClientCredentialsToken(c)
+	}
+
+	client, oErr := osuc.authenticateClient(c)
+	if oErr != nil {
+		return oErr.reply(c)
+	}
+
+	switch c.FormValue("grant_type") {
+	case "authorization_code":
+		return osuc.redeemAuthorizationCode(c, client)
+	case "refresh_token":
+		return osuc.redeemRefreshToken(c, client)
+	default:
+		return (&oauthError{fiber.StatusBadRequest, oauthErrUnsupportedGrantType, errMsgUnsupportedGrant}).reply(c)
+	}
+}
+
+// UserInfo returns the claims of the user that the scopes of the access token of the client release.
+func (osuc *OAuthServerUseCase) UserInfo(c *fiber.Ctx) error {
+	authorization := c.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer`)
+		return (&oauthError{fiber.StatusUnauthorized, oauthErrInvalidToken, errMsgInvalidAccessToken}).reply(c)
+	}
+
+	jwtConfig := osuc.us.GetJWTConfig()
+	accessToken, err := osuc.ts.ValidateToken(strings.TrimPrefix(authorization, "Bearer "), jwtConfig.AccessTokenKeyRing)
+	// The tokens of API clients have no user, and the ones of the users are not meant for other apps
+	if err != nil || accessToken.ClientID == "" || accessToken.UserUUID == accessToken.ClientID {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		return (&oauthError{fiber.StatusUnauthorized, oauthErrInvalidToken, errMsgInvalidAccessToken}).reply(c)
+	}
+
+	if !slices.Contains(accessToken.Scope, entity.ScopeOpenID) {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope"`)
+		return (&oauthError{fiber.StatusForbidden, oauthErrInsufficientScope, errMsgOpenIDScope}).reply(c)
+	}
+
+	user, err := osuc.us.GetUserByUUID(accessToken.UserUUID)
+	if err != nil || user.DisabledAt != nil {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		return (&oauthError{fiber.StatusUnauthorized, oauthErrInvalidToken, errMsgInvalidAccessToken}).reply(c)
+	}
+
+	claims := userClaims(user, accessToken.Scope)
+	claims["sub"] = accessToken.UserUUID
+	return c.Status(fiber.StatusOK).JSON(claims)
+}
+
+// GetOpenIDConfiguration publishes the discovery document of OpenID Connect, whose issuer is `JWT_ISSUER`.
+func (osuc *OAuthServerUseCase) GetOpenIDConfiguration(c *fiber.Ctx) error {
+	keyRing := osuc.us.GetJWTConfig().AccessTokenKeyRing
+	issuer := keyRing.Issuer
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{keyRing.Algorithm},
+		"scopes_supported":                      []string{entity.ScopeOpenID, entity.ScopeProfile, entity.ScopeEmail},
+		"claims_supported": []string{
+			"sub", "name", "given_name", "family_name", "updated_at", "email", "email_verified",
+		},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"prompt_values_supported":               []string{"none", "consent"},
+	})
+}
+
+// issueAuthorizationCode stores the grant under a new code and returns the redirect URI of the client with the code.
+func (osuc *OAuthServerUseCase) issueAuthorizationCode(grant *entity.AuthorizationGrant) (string, *restErr.RestErr) {
+	code, err := randtoken.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	expiresIn := time.Now().Add(osuc.osc.AuthorizationCodeExpiredIn).Unix()
+	if err := osuc.or.SetAuthorizationCode(randtoken.Hash(code), grant, expiresIn); err != nil {
+		return "", err
+	}
+
+	return osuc.authorizationResponseURL(grant, url.Values{"code": {code}}), nil
+}
+
+func (osuc *OAuthServerUseCase) redirectError(c *fiber.Ctx, grant *entity.AuthorizationGrant, code string,
+	description string) error {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+
+	return c.Redirect(osuc.authorizationResponseURL(grant, params), fiber.StatusSeeOther)
+}
+
+/*
+authorizationResponseURL adds the response parameters to the redirect URI, keeping its own query.
+The `iss` parameter of RFC 9207 lets clients that use several providers detect a mix-up of the responses.
+*/
+func (osuc *OAuthServerUseCase) authorizationResponseURL(grant *entity.AuthorizationGrant, params url.Values) string {
+	u, err := url.Parse(grant.RedirectURI)
+	if err != nil { // coverage:ignore
+		log.Error().Err(err).Str("client_id", grant.ClientID).Msg(errMsgInvalidRedirectURI)
+		return grant.RedirectURI
+	}
+
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+
+	if grant.State != "" {
+		query.Set("state", grant.State)
+	}
+
+	query.Set("iss", osuc.us.GetJWTConfig().AccessTokenKeyRing.Issuer)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+/*
+authenticateClient accepts the credentials in the Basic authorization header or in the form.
+Public clients only send their `client_id`; they prove the possession of their codes with PKCE instead.
+*/
+func (osuc *OAuthServerUseCase) authenticateClient(c *fiber.Ctx) (*entity.OAuthClient, *oauthError) {
+	clientID, clientSecret, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization))
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+
+	client, err := osuc.cr.GetClient(clientID)
+	if err != nil {
+		if err.Status != fiber.StatusNotFound {
+			return nil, &oauthError{fiber.StatusInternalServerError, oauthErrServerError, err.Message}
+		}
+	} else if client.IsPublic() && clientSecret == "" {
+		return client, nil
+	} else if !client.IsPublic() &&
+		subtle.ConstantTimeCompare([]byte(randtoken.Hash(clientSecret)), []byte(client.SecretHash)) == 1 {
+		return client, nil
+	}
+
+	if ok {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+
+	return nil, &oauthError{fiber.StatusUnauthorized, oauthErrInvalidClient, errMsgInvalidClient}
+}
+
+/*
+authenticateAPIClient accepts the credentials of an API client in the Basic authorization header or in the form,
+like `authenticateClient`; every API client is confidential.
+*/
+func (osuc *OAuthServerUseCase) authenticateAPIClient(c *fiber.Ctx) (*entity.APIClient, *oauthError) {
+	clientID, clientSecret, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization))
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+
+	client, err := osuc.ar.GetAPIClient(clientID)
+	if err != nil {
+		if err.Status != fiber.StatusNotFound {
+			return nil, &oauthError{fiber.StatusInternalServerError, oauthErrServerError, err.Message}
+		}
+	} else if subtle.ConstantTimeCompare([]byte(randtoken.Hash(clientSecret)), []byte(client.SecretHash)) == 1 {
+		return client, nil
+	}
+
+	if ok {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+
+	return nil, &oauthError{fiber.StatusUnauthorized, oauthErrInvalidClient, errMsgInvalidClient}
+}
+
+/*
+issueClientCredentialsToken replies with a short-lived access token whose subject is the API client itself.
+There is no refresh token (RFC 6749 section 4.4.3): the client authenticates again when the token expires.
+*/
+func (osuc *OAuthServerUseCase) issueClientCredentialsToken(c *fiber.Ctx) error {
+	client, oErr := osuc.authenticateAPIClient(c)
+	if oErr != nil {
+		return oErr.reply(c)
+	}
+
+	scope := uniqueScope(strings.Fields(c.FormValue("scope")))
+	if len(scope) == 0 {
+		scope = client.Scopes
+	}
+
+	if !isSubset(scope, client.Scopes) {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidScope, errMsgUnknownScope}).reply(c)
+	}
+
+	expiredIn := osuc.osc.ClientCredentialsExpiredIn
+	accessToken, err := osuc.ts.CreateClientToken(
+		client.ClientID, client.ClientID, scope, expiredIn, osuc.us.GetJWTConfig().AccessTokenKeyRing)
+	if err != nil {
+		return (&oauthError{err.Status, oauthErrServerError, err.Message}).reply(c)
+	}
+
+	log.Info().Str("client_id", client.ClientID).Msg("client credentials token issued")
+	return c.Status(fiber.StatusOK).JSON(&dto.TokenResponse{
+		AccessToken: *accessToken.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(expiredIn.Seconds()),
+		Scope:       strings.Join(scope, " "),
+	})
+}
+
+func (osuc *OAuthServerUseCase) redeemAuthorizationCode(c *fiber.Ctx, client *entity.OAuthClient) error {
+	grant, err := osuc.or.ConsumeAuthorizationCode(randtoken.Hash(c.FormValue("code")))
+	if err != nil {
+		return grantError(err).reply(c)
+	}
+
+	if grant.ClientID != client.ClientID {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, restErr.ErrMsgInvalidGrant}).reply(c)
+	}
+
+	// RFC 6749 section 4.1.3: the redirect_uri is only required when the authorization request included it
+	redirectURI := c.FormValue("redirect_uri")
+	if (grant.RedirectURISent || redirectURI != "") && grant.RedirectURI != redirectURI {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, errMsgRedirectURIChanged}).reply(c)
+	}
+
+	// RFC 7636 section 4.1: the verifier is 43 to 128 characters long
+	codeVerifier := c.FormValue("code_verifier")
+	if len(codeVerifier) < 43 || len(codeVerifier) > 128 ||
+		oauth2.S256ChallengeFromVerifier(codeVerifier) != grant.CodeChallenge {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, errMsgPKCEFailed}).reply(c)
+	}
+
+	return osuc.issueTokens(c, grant, grant.Scope)
+}
+
+/*
+redeemRefreshToken rotates the refresh token. The client may ask for fewer scopes than it was granted,
+which only narrows the new access token; the new refresh token keeps the whole grant.
+*/
+func (osuc *OAuthServerUseCase) redeemRefreshToken(c *fiber.Ctx, client *entity.OAuthClient) error {
+	grant, err := osuc.or.ConsumeOAuthRefreshToken(randtoken.Hash(c.FormValue("refresh_token")))
+	if err != nil {
+		return grantError(err).reply(c)
+	}
+
+	if grant.ClientID != client.ClientID {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, restErr.ErrMsgInvalidGrant}).reply(c)
+	}
+
+	scope := uniqueScope(strings.Fields(c.FormValue("scope")))
+	if len(scope) == 0 {
+		scope = grant.Scope
+	}
+
+	if !isSubset(scope, grant.Scope) {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidScope, errMsgUnknownScope}).reply(c)
+	}
+
+	return osuc.issueTokens(c, grant, scope)
+}
+
+// issueTokens replies with an access token for `scope`, a new refresh token for the grant and, for `openid`, an ID token.
+func (osuc *OAuthServerUseCase) issueTokens(c *fiber.Ctx, grant *entity.AuthorizationGrant, scope []string) error {
+	// The grant ends with the user: a disabled or deleted user cannot redeem a code or refresh token any more
+	user, err := osuc.us.GetUserByUUID(grant.UserUUID)
+	if err != nil || user.DisabledAt != nil {
+		return (&oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, restErr.ErrMsgInvalidGrant}).reply(c)
+	}
+
+	jwtConfig := osuc.us.GetJWTConfig()
+	accessToken, err := osuc.ts.CreateClientToken(
+		grant.UserUUID, grant.ClientID, scope, jwtConfig.AccessTokenExpiredIn, jwtConfig.AccessTokenKeyRing)
+	if err != nil {
+		return (&oauthError{err.Status, oauthErrServerError, err.Message}).reply(c)
+	}
+
+	refreshToken, err := randtoken.Generate()
+	if err != nil {
+		return (&oauthError{err.Status, oauthErrServerError, err.Message}).reply(c)
+	}
+
+	refreshGrant := &entity.AuthorizationGrant{ClientID: grant.ClientID, UserUUID: grant.UserUUID, Scope: grant.Scope}
+	expiresIn := time.Now().Add(jwtConfig.RefreshTokenExpiredIn).Unix()
+	if err := osuc.or.SetOAuthRefreshToken(randtoken.Hash(refreshToken), refreshGrant, expiresIn); err != nil {
+		return (&oauthError{err.Status, oauthErrServerError, err.Message}).reply(c)
+	}
+
+	response := &dto.TokenResponse{
+		AccessToken:  *accessToken.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(jwtConfig.AccessTokenExpiredIn.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scope, " "),
+	}
+
+	if slices.Contains(scope, entity.ScopeOpenID) {
+		idToken, err := osuc.ts.CreateIDToken(&entity.IDToken{
+			Subject:  grant.UserUUID,
+			ClientID: grant.ClientID,
+			Nonce:    grant.Nonce,
+			Claims:   userClaims(user, scope),
+		}, osuc.osc.IDTokenExpiredIn, jwtConfig.AccessTokenKeyRing)
+		if err != nil {
+			return (&oauthError{err.Status, oauthErrServerError, err.Message}).reply(c)
+		}
+
+		response.IDToken = *idToken.Token
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// checkConsentRequestUser keeps a consent request from being answered by someone other than the user who made it.
+func checkConsentRequestUser(c *fiber.Ctx, grant *entity.AuthorizationGrant) *restErr.RestErr {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok || userRecord.UUID == nil || userRecord.UUID.String() != grant.UserUUID {
+		return restErr.NewBadRequestError(restErr.ErrMsgAuthorizationExpired)
+	}
+
+	return nil
+}
+
+// grantError tells an unknown, expired or used code or refresh token apart from a failure of Redis.
+func grantError(err *restErr.RestErr) *oauthError {
+	if err.Status == fiber.StatusBadRequest {
+		return &oauthError{fiber.StatusBadRequest, oauthErrInvalidGrant, err.Message}
+	}
+
+	return &oauthError{err.Status, oauthErrServerError, err.Message}
+}
+
+// userClaims are the standard claims of OpenID Connect that the `profile` and `email` scopes release.
+func userClaims(user *entity.User, scope []string) map[string]interface{} {
+	claims := map[string]interface{}{}
+	if slices.Contains(scope, entity.ScopeProfile) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+
+	if slices.Contains(scope, entity.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+
+	return claims
+}
+
+// parseBasicAuth decodes the client credentials, which are form-encoded before the Basic encoding (RFC 6749 section 2.3.1).
+func parseBasicAuth(authorization string) (string, string, bool) {
+	encoded, ok := strings.CutPrefix(authorization, "Basic ")
+	if !ok {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+
+	rawClientID, rawClientSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+
+	clientID, errID := url.QueryUnescape(rawClientID)
+	clientSecret, errSecret := url.QueryUnescape(rawClientSecret)
+	if errID != nil || errSecret != nil {
+		return "", "", false
+	}
+
+	return clientID, clientSecret, true
+}
+
+func isSubset(scope []string, allowed []string) bool {
+	for _, s := range scope {
+		if !slices.Contains(allowed, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func uniqueScope(scope []string) []string {
+	unique := []string{}
+	for _, s := range scope {
+		if !slices.Contains(unique, s) {
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
@@ -7,13 +7,18 @@ import (
 
 	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
 	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
 	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
 	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/config"
 	mw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware"
+	azmw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/authorize"
 	dumw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/deserialize_user"
+	ltmw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/login_throttle"
 	ppmw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/preprocess_inputs"
+	rlmw "github.com/DarrelA/starter-go-postgresql/internal/interface/middleware/rate_limit"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -37,11 +42,24 @@ func StartServer(app *fiber.App, port string) {
 func NewRouter(
 	envConfig *config.EnvConfig,
 	redisRepo r.RedisUserRepository,
+	loginAttemptRepo r.RedisLoginAttemptRepository,
+	rateLimitRepo r.RateLimitRepository,
+	roleRepo rp.PostgresRoleRepository,
+	apiKeyRepo rp.PostgresAPIKeyRepository,
+	apiClientRepo rp.PostgresAPIClientRepository,
 	tokenService domainSvc.TokenService,
 	userService appSvc.UserService,
 	userUseCase usecase.UserUseCase,
 	authUseCase usecase.AuthUseCase,
+	webAuthnUseCase usecase.WebAuthnUseCase,
+	adminUseCase usecase.AdminUseCase,
 	googleOAuth2UseCase usecase.OAuth2UseCase,
+	gitHubOAuth2UseCase usecase.OAuth2UseCase,
+	oidcOAuth2UseCase usecase.OAuth2UseCase,
+	oauthServerUseCase usecase.OAuthServerUseCase,
+	oauthClientUseCase usecase.OAuthClientUseCase,
+	apiClientUseCase usecase.APIClientUseCase,
+	apiKeyUseCase usecase.APIKeyUseCase,
 ) *fiber.App {
 	log.Info().Msg("creating fiber instances")
 	appInstance := fiber.New()
@@ -57,24 +75,108 @@ func NewRouter(
 		return c.Next()
 	})
 
+	// Public routes are limited per client IP and authenticated routes per user
+	authRateLimit := rlmw.RateLimiter(rateLimitRepo, "auth", envConfig.RateLimitConfig.Auth, rlmw.KeyByIP)
+	userRateLimit := rlmw.RateLimiter(rateLimitRepo, "user", envConfig.RateLimitConfig.User, rlmw.KeyByUser)
+	deserializer := dumw.Deserializer(redisRepo, tokenService, userService, apiKeyRepo, apiClientRepo)
+
 	/********************
 	 *   Refresh Token  *
 	 ********************/
 	user := v1.Group("/users")
-	user.Post("/register", ppmw.PreProcessInputs, authUseCase.Register)
-	user.Post("/login", ppmw.PreProcessInputs, authUseCase.Login)
-
-	authUser := user.Group("/").Use(dumw.Deserializer(redisRepo, tokenService, userService))
+	user.Post("/register", authRateLimit, ppmw.PreProcessInputs, authUseCase.Register)
+	user.Post("/login", authRateLimit, ppmw.PreProcessInputs,
+		ltmw.LoginThrottle(loginAttemptRepo, envConfig.AccountConfig), authUseCase.Login)
+	user.Post("/login/mfa", authRateLimit, ppmw.PreProcessInputs, authUseCase.LoginMFA)
+	user.Get("/verify-email", authRateLimit, authUseCase.VerifyEmail)
+	user.Post("/verify-email/resend", authRateLimit, ppmw.PreProcessInputs, authUseCase.ResendVerificationEmail)
+	user.Post("/forgot-password", authRateLimit, ppmw.PreProcessInputs, authUseCase.ForgotPassword)
+	user.Post("/reset-password", authRateLimit, ppmw.PreProcessInputs, authUseCase.ResetPassword)
+	user.Post("/webauthn/login/begin", authRateLimit, webAuthnUseCase.BeginLogin)
+	user.Post("/webauthn/login/finish", authRateLimit, webAuthnUseCase.FinishLogin)
+
+	// Registered before `authUser`, whose middlewares match every path under `/users` that comes after it
+	user.Get("/refresh", authRateLimit, authUseCase.RefreshAccessToken)
+
+	// API keys are for the routes that require a permission; a leaked key cannot take over the account
+	authUser := user.Group("/").Use(deserializer, dumw.RequireUser, dumw.RequireSession, userRateLimit)
 	authUser.Get("/logout", authUseCase.Logout)
 	authUser.Get("/me", userUseCase.GetUserRecord)
+	authUser.Patch("/me", ppmw.PreProcessInputs, userUseCase.UpdateUserRecord)
+	authUser.Delete("/me", ppmw.PreProcessInputs, userUseCase.DeleteAccount)
+	authUser.Get("/me/export", userUseCase.ExportUserData)
+	authUser.Get("/me/identities", userUseCase.GetIdentities)
+	authUser.Get("/me/identities/google/link", googleOAuth2UseCase.Link)
+	authUser.Get("/me/identities/github/link", gitHubOAuth2UseCase.Link)
+	authUser.Get("/me/identities/:provider/link", oidcOAuth2UseCase.Link)
+	authUser.Delete("/me/identities/:provider/:subject", userUseCase.UnlinkIdentity)
+	authUser.Post("/me/password", ppmw.PreProcessInputs, authUseCase.ChangePassword)
+	authUser.Get("/sessions", authUseCase.GetSessions)
+	authUser.Delete("/sessions/:id", authUseCase.RevokeSession)
+	authUser.Post("/logout-all", authUseCase.LogoutAll)
+	authUser.Post("/me/mfa/totp", authUseCase.EnrollTOTP)
+	authUser.Post("/me/mfa/totp/confirm", ppmw.PreProcessInputs, authUseCase.ConfirmTOTP)
+	authUser.Post("/webauthn/register/begin", webAuthnUseCase.BeginRegistration)
+	authUser.Post("/webauthn/register/finish", webAuthnUseCase.FinishRegistration)
+	authUser.Get("/me/api-keys", apiKeyUseCase.ListAPIKeys)
+	authUser.Post("/me/api-keys", ppmw.PreProcessInputs, apiKeyUseCase.CreateAPIKey)
+	authUser.Delete("/me/api-keys/:id", apiKeyUseCase.RevokeAPIKey)
 
-	user.Get("/refresh", authUseCase.RefreshAccessToken)
+	/********************
+	 *       Admin      *
+	 ********************/
+	// The backend services that look users up do it with an API client granted the `users:read` scope
+	admin := v1.Group("/admin/users", deserializer, userRateLimit)
+	admin.Get("/", azmw.RequireScope(roleRepo, entity.PermissionUsersRead), adminUseCase.ListUsers)
+	admin.Get("/:uuid", azmw.RequireScope(roleRepo, entity.PermissionUsersRead), adminUseCase.GetUser)
+	admin.Post("/:uuid/disable", azmw.RequirePermission(roleRepo, entity.PermissionUsersWrite), adminUseCase.DisableUser)
+	admin.Post("/:uuid/enable", azmw.RequirePermission(roleRepo, entity.PermissionUsersWrite), adminUseCase.EnableUser)
+	admin.Delete("/:uuid", azmw.RequirePermission(roleRepo, entity.PermissionUsersDelete), adminUseCase.DeleteUser)
+
+	oauthClients := v1.Group("/admin/oauth/clients", deserializer, userRateLimit)
+	oauthClients.Get("/", azmw.RequirePermission(roleRepo, entity.PermissionOAuthClientsRead),
+		oauthClientUseCase.ListClients)
+	oauthClients.Post("/", azmw.RequirePermission(roleRepo, entity.PermissionOAuthClientsWrite),
+		oauthClientUseCase.CreateClient)
+	oauthClients.Delete("/:client_id", azmw.RequirePermission(roleRepo, entity.PermissionOAuthClientsWrite),
+		oauthClientUseCase.DeleteClient)
+
+	apiClients := v1.Group("/admin/api-clients", deserializer, userRateLimit)
+	apiClients.Get("/", azmw.RequirePermission(roleRepo, entity.PermissionAPIClientsRead),
+		apiClientUseCase.ListAPIClients)
+	apiClients.Post("/", azmw.RequirePermission(roleRepo, entity.PermissionAPIClientsWrite),
+		apiClientUseCase.CreateAPIClient)
+	apiClients.Delete("/:client_id", azmw.RequirePermission(roleRepo, entity.PermissionAPIClientsWrite),
+		apiClientUseCase.DeleteAPIClient)
 
 	/********************
 	 *      OAuth2      *
 	 ********************/
-	authServiceInstance.Get("/google_login", googleOAuth2UseCase.Login)
-	authServiceInstance.Get("/google_callback", googleOAuth2UseCase.Callback)
+	authServiceInstance.Get("/google_login", authRateLimit, googleOAuth2UseCase.Login)
+	authServiceInstance.Get("/google_callback", authRateLimit, googleOAuth2UseCase.Callback)
+	authServiceInstance.Get("/github_login", authRateLimit, gitHubOAuth2UseCase.Login)
+	authServiceInstance.Get("/github_callback", authRateLimit, gitHubOAuth2UseCase.Callback)
+	authServiceInstance.Get("/:provider/login", authRateLimit, oidcOAuth2UseCase.Login)
+	authServiceInstance.Get("/:provider/callback", authRateLimit, oidcOAuth2UseCase.Callback)
+
+	/********************
+	 *   OAuth Server   *
+	 ********************/
+	oauthServer := authServiceInstance.Group("/oauth")
+	oauthServer.Get("/authorize", authRateLimit, oauthServerUseCase.Authorize)
+	oauthServer.Post("/token", authRateLimit, oauthServerUseCase.Token)
+	oauthServer.Get("/userinfo", authRateLimit, oauthServerUseCase.UserInfo)
+	oauthServer.Post("/userinfo", authRateLimit, oauthServerUseCase.UserInfo)
+
+	consent := oauthServer.Group("/consent", deserializer, dumw.RequireUser, dumw.RequireSession, userRateLimit)
+	consent.Get("/:id", oauthServerUseCase.GetConsentRequest)
+	consent.Post("/:id", oauthServerUseCase.AnswerConsentRequest)
+
+	/********************
+	 *       JWKS       *
+	 ********************/
+	authServiceInstance.Get("/.well-known/jwks.json", authUseCase.GetJWKS)
+	authServiceInstance.Get("/.well-known/openid-configuration", oauthServerUseCase.GetOpenIDConfiguration)
 
 	authServiceInstance.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
@@ -111,9 +213,9 @@ func useMiddlewares(authServiceInstance *fiber.App, envConfig *config.EnvConfig)
 
 	authServiceInstance.Use(cors.New(cors.Config{
 		AllowOrigins:     envConfig.CORSConfig.AllowedOrigins,
-		AllowMethods:     "GET,POST",
+		AllowMethods:     "GET,POST,PATCH,DELETE",
 		AllowHeaders:     "Content-Type",
-		ExposeHeaders:    "Content-Length",
+		ExposeHeaders:    "Content-Length,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset",
 		AllowCredentials: true,
 		MaxAge:           12 * 60 * 60,
 	}))
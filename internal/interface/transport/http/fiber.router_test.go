@@ -0,0 +1,165 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/config"
+	oauth2 "github.com/DarrelA/starter-go-postgresql/internal/interface/transport/http/oauth2"
+	"github.com/gofiber/fiber/v2"
+)
+
+const testUsersPath = "/auth/api/v1/users"
+
+/*
+newTestRouter mounts every route with the repositories and services that the routes under test reach,
+and returns the access and refresh token cookies of a login session of `user` along with the app.
+*/
+func newTestRouter(t *testing.T, user *entity.User) (*fiber.App, *http.Cookie, *http.Cookie) {
+	t.Helper()
+
+	envConfig := &config.EnvConfig{}
+	envConfig.BaseURLsConfig = &entity.BaseURLsConfig{AuthServicePathName: testUsersPath}
+	envConfig.CORSConfig = &entity.CORSConfig{AllowedOrigins: "http://localhost:3000"}
+	envConfig.AccountConfig = &entity.AccountConfig{}
+	envConfig.RateLimitConfig = &entity.RateLimitConfig{}
+
+	r, ts, us := newMockRedisUserRepository(), newMockTokenService(), newMockUserService(user)
+	kr := newMockAPIKeyRepository(user.UUID.String(), mockAPIKey, []string{entity.PermissionUsersRead})
+	oauth2Config := &entity.OAuth2Config{}
+	auc := NewAuthUseCase(r, us, ts, nil, envConfig.AccountConfig)
+
+	app := NewRouter(
+		envConfig, r, nil, nil, &mockRoleRepository{}, kr, nil, ts, us,
+		NewUserUseCase(r, us, nil), auc, NewWebAuthnUseCase(r, us, ts, envConfig.AccountConfig, nil, nil, nil),
+		NewAdminUseCase(r, us),
+		oauth2.NewGoogleOAuth2(oauth2Config, r, us, auc), oauth2.NewGitHubOAuth2(oauth2Config, r, us, auc),
+		oauth2.NewOIDCOAuth2(oauth2Config, nil, r, us, auc),
+		NewOAuthServerUseCase(nil, nil, nil, r, us, ts, &entity.OAuthServerConfig{}),
+		NewOAuthClientUseCase(nil), NewAPIClientUseCase(nil), NewAPIKeyUseCase(kr, &mockRoleRepository{}),
+	)
+
+	// The login methods all end with `startSession`, which is mounted on its own to skip their checks
+	login := fiber.New()
+	login.Post("/login", func(c *fiber.Ctx) error { return auc.(*AuthUseCase).startSession(c, user.UUID.String()) })
+	resp := post(t, login, "/login", "")
+	return app, expectTokenCookie(t, resp, "access_token", false), expectTokenCookie(t, resp, "refresh_token", false)
+}
+
+/*
+expectTokenCookie checks that the token cookie is set, or cleared, with the attributes of `setTokenCookies`:
+browsers keep the cookie when it is cleared with another path or domain.
+*/
+func expectTokenCookie(t *testing.T, resp *http.Response, name string, cleared bool) *http.Cookie {
+	t.Helper()
+
+	cookie := findCookie(resp, name)
+	if cookie == nil {
+		t.Fatalf("expected the '%s' cookie to be set", name)
+	}
+
+	if cookie.Path != "/" || cookie.Domain != "localhost" || !cookie.HttpOnly ||
+		cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected an HttpOnly and SameSite=Strict '%s' cookie of the path '/' and the domain 'localhost', got '%+v'",
+			name, cookie)
+	}
+
+	if isCleared := cookie.Value == "" && cookie.Expires.Before(time.Now()); isCleared != cleared {
+		t.Errorf("expected the '%s' cookie to be cleared '%t', got '%+v'", name, cleared, cookie)
+	}
+
+	return cookie
+}
+
+func request(t *testing.T, app *fiber.App, method string, path string, body string,
+	modify func(req *http.Request)) *http.Response {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	req := httptest.NewRequest(method, path, reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	modify(req)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request to '%s' failed: %v", path, err)
+	}
+	return resp
+}
+
+func TestRouterAPIKeySessionRoutes(t *testing.T) {
+	app, _, _ := newTestRouter(t, newMockUser(true, false))
+	withAPIKey := func(req *http.Request) { req.Header.Set("X-API-Key", mockAPIKey) }
+
+	for _, route := range apiKeySessionRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			resp := request(t, app, route.method, testUsersPath+route.path, "", withAPIKey)
+			expectError(t, resp, fiber.StatusForbidden, "this route requires a login session instead of an api key")
+		})
+	}
+
+	// The key still reaches the routes that require a permission among its scopes
+	resp := request(t, app, "GET", "/auth/api/v1/admin/users", "", withAPIKey)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("expected status '%d' with the API key, got '%d'", fiber.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestRouterCreateAPIKey(t *testing.T) {
+	for _, test := range createAPIKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			app, sessionCookie, _ := newTestRouter(t, newMockUser(true, false))
+			resp := request(t, app, "POST", testUsersPath+"/me/api-keys", test.body,
+				func(req *http.Request) { req.AddCookie(sessionCookie) })
+
+			if test.expectedErrMsg != "" {
+				expectError(t, resp, test.expectedStatus, test.expectedErrMsg)
+			} else if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected status '%d', got '%d'", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRouterRefreshAccessToken(t *testing.T) {
+	app, _, refreshCookie := newTestRouter(t, newMockUser(true, false))
+
+	// The access token has expired by the time the client refreshes it, and an API key changes nothing
+	expiredCookie := &http.Cookie{Name: "access_token", Value: "mock-token-expired"}
+	withCookies := func(cookies ...*http.Cookie) func(req *http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set("X-API-Key", mockAPIKey)
+			for _, cookie := range cookies {
+				req.AddCookie(cookie)
+			}
+		}
+	}
+
+	resp := request(t, app, "GET", testUsersPath+"/refresh", "", withCookies(expiredCookie, refreshCookie))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status '%d', got '%d'", fiber.StatusOK, resp.StatusCode)
+	}
+
+	expectTokenCookie(t, resp, "access_token", false)
+	if rotatedCookie := expectTokenCookie(t, resp, "refresh_token", false); rotatedCookie.Value == refreshCookie.Value {
+		t.Error("expected the refresh token to be rotated")
+	}
+
+	// Reusing the rotated token revokes the session and clears the cookies that the browser holds
+	resp = request(t, app, "GET", testUsersPath+"/refresh", "", withCookies(expiredCookie, refreshCookie))
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected status '%d', got '%d'", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+
+	expectTokenCookie(t, resp, "access_token", true)
+	expectTokenCookie(t, resp, "refresh_token", true)
+}
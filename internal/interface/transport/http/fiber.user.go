@@ -3,15 +3,36 @@
 package http
 
 import (
+	"encoding/base64"
+	"sort"
+	"time"
+
 	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	errMsgUpdateUserPayload    = "invalid update user payload"
+	errMsgDeleteAccountPayload = "invalid delete account payload"
 )
 
-type UserUseCase struct{}
+type UserUseCase struct {
+	r  r.RedisUserRepository
+	us appSvc.UserService
+	wr rp.PostgresWebAuthnRepository
+}
 
-func NewUserUseCase() usecase.UserUseCase {
-	return &UserUseCase{}
+func NewUserUseCase(
+	r r.RedisUserRepository, us appSvc.UserService, wr rp.PostgresWebAuthnRepository,
+) usecase.UserUseCase {
+	return &UserUseCase{r, us, wr}
 }
 
 func (uuc *UserUseCase) GetUserRecord(c *fiber.Ctx) error {
@@ -19,3 +40,170 @@ func (uuc *UserUseCase) GetUserRecord(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).
 		JSON(fiber.Map{"status": "success", "data": fiber.Map{"userRecord": userRecord}})
 }
+
+// UpdateUserRecord replies with a conflict if the user has been updated since the record sent by the client.
+func (uuc *UserUseCase) UpdateUserRecord(c *fiber.Ctx) error {
+	payload, ok := c.Locals("update_user_payload").(dto.UpdateUserInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgUpdateUserPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	userRecord := c.Locals("userRecord").(*dto.UserRecord)
+	user, err := uuc.us.UpdateUser(userRecord.UUID.String(), payload)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	userRecord = &dto.UserRecord{
+		UUID:      user.UUID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Roles:     userRecord.Roles,
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "data": fiber.Map{"userRecord": userRecord}})
+}
+
+/*
+DeleteAccount soft-deletes the user and revokes every session.
+The account is purged for good once `AccountConfig.DeletionGracePeriod` is over.
+*/
+func (uuc *UserUseCase) DeleteAccount(c *fiber.Ctx) error {
+	payload, ok := c.Locals("delete_account_payload").(dto.DeleteAccountInput)
+	if !ok {
+		err := restErr.NewBadRequestError(errMsgDeleteAccountPayload)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	userUUID := c.Locals("userRecord").(*dto.UserRecord).UUID.String()
+	if err := uuc.us.DeleteAccount(userUUID, payload, uuc.loggedInAt(c)); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := uuc.r.DelUserSessions(userUUID); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	clearTokenCookies(c, uuc.us.GetJWTConfig())
+	log.Info().Str("user_uuid", userUUID).Msg("account deleted")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// loggedInAt is when the current session started, or the zero time without a session, e.g. with an API key.
+func (uuc *UserUseCase) loggedInAt(c *fiber.Ctx) time.Time {
+	familyID := currentSessionID(c, uuc.r)
+	if familyID == "" {
+		return time.Time{}
+	}
+
+	session, err := uuc.r.GetSession(familyID)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return session.CreatedAt
+}
+
+// ExportUserData replies with every piece of personal data kept about the user as a JSON attachment.
+func (uuc *UserUseCase) ExportUserData(c *fiber.Ctx) error {
+	userUUID := c.Locals("userRecord").(*dto.UserRecord).UUID.String()
+	export, err := uuc.us.ExportUser(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	credentials, err := uuc.wr.GetCredentials(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	for _, credential := range credentials {
+		passkey := dto.PasskeyRecord{
+			ID:              base64.RawURLEncoding.EncodeToString(credential.ID),
+			AttestationType: credential.AttestationType,
+			Transports:      credential.Transports,
+			BackupEligible:  credential.BackupEligible,
+			BackupState:     credential.BackupState,
+			CreatedAt:       credential.CreatedAt,
+			LastUsedAt:      credential.LastUsedAt,
+		}
+
+		if aaguid, err := uuid.FromBytes(credential.AAGUID); err == nil {
+			passkey.AAGUID = aaguid.String()
+		}
+
+		export.Passkeys = append(export.Passkeys, passkey)
+	}
+
+	sessions, err := uuc.r.GetSessions(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, dto.SessionRecord{
+			ID:              session.ID,
+			CreatedAt:       session.CreatedAt,
+			LastRefreshedAt: session.LastRefreshedAt,
+			IPAddress:       session.IPAddress,
+			UserAgent:       session.UserAgent,
+		})
+	}
+
+	sort.Slice(export.Sessions, func(i, j int) bool {
+		return export.Sessions[i].CreatedAt.Before(export.Sessions[j].CreatedAt)
+	})
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="user-export.json"`)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(export)
+}
+
+func (uuc *UserUseCase) GetIdentities(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	userUUID := userRecord.UUID.String()
+	identities, err := uuc.us.GetIdentities(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "data": fiber.Map{"identities": identities}})
+}
+
+// UnlinkIdentity replies with a conflict if the identity is the last way for the user to login.
+func (uuc *UserUseCase) UnlinkIdentity(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	userUUID := userRecord.UUID.String()
+	credentials, err := uuc.wr.GetCredentials(userUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := uuc.us.UnlinkIdentity(userUUID, c.Params("provider"), c.Params("subject"), len(credentials) > 0); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	webAuthnSessionCookie   = "webauthn_session" // Links the two requests of a passkey login
+	webAuthnRegistrationKey = "registration:"
+	webAuthnLoginKey        = "login:"
+)
+
+/*
+The `WebAuthnUseCase` registers passkeys for logged in users and logs users in with them.
+A passkey login issues the same session as `AuthUseCase.Login`, so the auth use case is reused for it.
+*/
+type WebAuthnUseCase struct {
+	auc *AuthUseCase
+	was domainSvc.WebAuthnService
+	wr  rp.PostgresWebAuthnRepository
+	wac *entity.WebAuthnConfig
+}
+
+func NewWebAuthnUseCase(
+	r r.RedisUserRepository,
+	us appSvc.UserService,
+	ts domainSvc.TokenService,
+	ac *entity.AccountConfig,
+	was domainSvc.WebAuthnService,
+	wr rp.PostgresWebAuthnRepository,
+	wac *entity.WebAuthnConfig,
+) usecase.WebAuthnUseCase {
+	return &WebAuthnUseCase{&AuthUseCase{r: r, us: us, ts: ts, ac: ac}, was, wr, wac}
+}
+
+// BeginRegistration returns the options for `navigator.credentials.create()`.
+func (wuc *WebAuthnUseCase) BeginRegistration(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	user, credentials, err := wuc.findUser(userRecord.UUID.String())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	ceremony, err := wuc.was.BeginRegistration(user, credentials)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// Starting another registration replaces the pending one
+	expiresIn := time.Now().Add(wuc.wac.Timeout).Unix()
+	key := webAuthnRegistrationKey + user.UUID.String()
+	if err := wuc.auc.r.SetWebAuthnSession(key, ceremony.SessionData, expiresIn); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "options": ceremony.Options})
+}
+
+// FinishRegistration verifies the attestation of the authenticator and saves the new passkey.
+func (wuc *WebAuthnUseCase) FinishRegistration(c *fiber.Ctx) error {
+	userRecord, ok := c.Locals("userRecord").(*dto.UserRecord)
+	if !ok {
+		err := restErr.NewInternalServerError(errMsgUserRecord)
+		log.Error().Err(err).Msg(restErr.ErrTypeError)
+		clientErr := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return c.Status(clientErr.Status).JSON(fiber.Map{"status": "fail", "error": clientErr})
+	}
+
+	sessionData, err := wuc.auc.r.ConsumeWebAuthnSession(webAuthnRegistrationKey + userRecord.UUID.String())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	user, credentials, err := wuc.findUser(userRecord.UUID.String())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	credential, err := wuc.was.FinishRegistration(user, credentials, sessionData, c.Body())
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := wuc.wr.SaveCredential(credential); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "credential": credential})
+}
+
+/*
+BeginLogin returns the options for `navigator.credentials.get()`.
+The passkey is discoverable, so the user is only known once the authenticator answers;
+the challenge is tied to the browser with the `webauthn_session` cookie instead.
+*/
+func (wuc *WebAuthnUseCase) BeginLogin(c *fiber.Ctx) error {
+	ceremony, err := wuc.was.BeginLogin()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	sessionToken, err := randtoken.Generate()
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	expiresIn := time.Now().Add(wuc.wac.Timeout).Unix()
+	key := webAuthnLoginKey + randtoken.Hash(sessionToken)
+	if err := wuc.auc.r.SetWebAuthnSession(key, ceremony.SessionData, expiresIn); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	c.Cookie(wuc.newSessionCookie(sessionToken))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "options": ceremony.Options})
+}
+
+// FinishLogin verifies the assertion of the authenticator and starts a session like `AuthUseCase.Login`.
+func (wuc *WebAuthnUseCase) FinishLogin(c *fiber.Ctx) error {
+	sessionToken := c.Cookies(webAuthnSessionCookie)
+	if sessionToken == "" {
+		err := restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	c.Cookie(wuc.newSessionCookie(""))
+	sessionData, err := wuc.auc.r.ConsumeWebAuthnSession(webAuthnLoginKey + randtoken.Hash(sessionToken))
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	user, credential, err := wuc.was.FinishLogin(sessionData, c.Body(), wuc.findUser)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if err := wuc.wr.UpdateCredential(credential); err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if user.DisabledAt != nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	if wuc.auc.ac.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgEmailNotVerified)
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	// A passkey already verifies the user with a second factor, so TOTP is not asked for
+	return wuc.auc.startSession(c, user.UUID.String())
+}
+
+// newSessionCookie sets the `webauthn_session` cookie, or clears it with the same attributes when the token is empty.
+func (wuc *WebAuthnUseCase) newSessionCookie(sessionToken string) *fiber.Cookie {
+	jwtConfig := wuc.auc.us.GetJWTConfig()
+	cookie := &fiber.Cookie{
+		Name:     webAuthnSessionCookie,
+		Value:    sessionToken,
+		Path:     "/",
+		Domain:   jwtConfig.Domain,
+		Secure:   jwtConfig.Secure,
+		HTTPOnly: true,
+		SameSite: "strict",
+	}
+
+	if sessionToken == "" {
+		cookie.Expires = time.Now().Add(-time.Hour * 24)
+	} else {
+		cookie.MaxAge = int(wuc.wac.Timeout.Seconds())
+	}
+
+	return cookie
+}
+
+func (wuc *WebAuthnUseCase) findUser(userUUID string) (
+	*entity.User, []*entity.WebAuthnCredential, *restErr.RestErr) {
+	user, err := wuc.auc.us.GetUserByUUID(userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentials, err := wuc.wr.GetCredentials(userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, credentials, nil
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestWebAuthnUseCase(user *entity.User, requireEmailVerification bool) (
+	*WebAuthnUseCase, *mockRedisUserRepository, *mockWebAuthnRepository) {
+	r := newMockRedisUserRepository()
+	wr := &mockWebAuthnRepository{credentials: map[string][]*entity.WebAuthnCredential{}}
+	wuc := NewWebAuthnUseCase(
+		r,
+		newMockUserService(user),
+		newMockTokenService(),
+		&entity.AccountConfig{RequireEmailVerification: requireEmailVerification},
+		&mockWebAuthnService{},
+		wr,
+		&entity.WebAuthnConfig{Timeout: time.Minute},
+	).(*WebAuthnUseCase)
+	return wuc, r, wr
+}
+
+// newTestWebAuthnApp mounts the passkey routes; the registration routes stand in for the `Deserializer` with `user`.
+func newTestWebAuthnApp(wuc *WebAuthnUseCase, user *entity.User) *fiber.App {
+	app := fiber.New()
+	deserializer := func(c *fiber.Ctx) error {
+		c.Locals("userRecord", &dto.UserRecord{UUID: user.UUID})
+		return c.Next()
+	}
+	app.Post("/webauthn/register/begin", deserializer, wuc.BeginRegistration)
+	app.Post("/webauthn/register/finish", deserializer, wuc.FinishRegistration)
+	app.Post("/webauthn/login/begin", wuc.BeginLogin)
+	app.Post("/webauthn/login/finish", wuc.FinishLogin)
+	return app
+}
+
+func post(t *testing.T, app *fiber.App, path string, body string, cookies ...*http.Cookie) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request to '%s' failed: %v", path, err)
+	}
+	return resp
+}
+
+func findCookie(resp *http.Response, name string) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func expectError(t *testing.T, resp *http.Response, expectedStatus int, expectedErrMsg string) {
+	t.Helper()
+
+	if resp.StatusCode != expectedStatus {
+		t.Fatalf("expected status '%d', got '%d'", expectedStatus, resp.StatusCode)
+	}
+
+	var respBody map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+
+	errorMsg, _ := respBody["error"].(map[string]interface{})["message"].(string)
+	if errorMsg != expectedErrMsg {
+		t.Errorf("expected error message '%s', got '%s'", expectedErrMsg, errorMsg)
+	}
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	user := newMockUser(true, false)
+	wuc, _, wr := newTestWebAuthnUseCase(user, true)
+	app := newTestWebAuthnApp(wuc, user)
+
+	resp := post(t, app, "/webauthn/register/begin", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status '%d', got '%d'", fiber.StatusOK, resp.StatusCode)
+	}
+
+	resp = post(t, app, "/webauthn/register/finish", "credential")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected status '%d', got '%d'", fiber.StatusCreated, resp.StatusCode)
+	}
+
+	if credentials := wr.credentials[user.UUID.String()]; len(credentials) != 1 {
+		t.Fatalf("expected the passkey to be saved, got '%d' passkeys", len(credentials))
+	}
+
+	// The registration session is consumed by the first answer
+	resp = post(t, app, "/webauthn/register/finish", "credential")
+	expectError(t, resp, fiber.StatusBadRequest, "the passkey request has expired; please try again")
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	for _, test := range webAuthnLoginTests {
+		t.Run(test.name, func(t *testing.T) {
+			wuc, r, wr := newTestWebAuthnUseCase(test.user, test.requireEmailVerification)
+			wr.credentials[test.user.UUID.String()] = []*entity.WebAuthnCredential{{ID: []byte("credential")}}
+			app := newTestWebAuthnApp(wuc, test.user)
+
+			resp := post(t, app, "/webauthn/login/begin", "")
+			cookie := findCookie(resp, webAuthnSessionCookie)
+			if resp.StatusCode != fiber.StatusOK || cookie == nil {
+				t.Fatalf("expected the '%s' cookie with status '%d', got '%d'",
+					webAuthnSessionCookie, fiber.StatusOK, resp.StatusCode)
+			}
+
+			if cookie.Domain != "localhost" || !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+				t.Errorf("expected an HttpOnly and SameSite=Strict cookie of the domain 'localhost', got '%+v'", cookie)
+			}
+
+			resp = post(t, app, "/webauthn/login/finish", test.user.UUID.String(), cookie)
+			if test.expectedErrMsg != "" {
+				expectError(t, resp, test.expectedStatus, test.expectedErrMsg)
+				if len(r.sessions) != 0 {
+					t.Errorf("expected no session to be started, got '%d'", len(r.sessions))
+				}
+			} else if resp.StatusCode != test.expectedStatus || findCookie(resp, "refresh_token") == nil {
+				t.Fatalf("expected the token cookies with status '%d', got '%d'", test.expectedStatus, resp.StatusCode)
+			} else if len(r.sessions) != 1 || wr.updated != 1 {
+				t.Errorf("expected a session and the sign count to be saved, got '%d' sessions", len(r.sessions))
+			}
+
+			// The session cookie is cleared with the domain it was set with, or the browser would keep it
+			cleared := findCookie(resp, webAuthnSessionCookie)
+			if cleared == nil || cleared.Value != "" || cleared.Domain != cookie.Domain || cleared.Path != "/" ||
+				!cleared.Expires.Before(time.Now()) {
+				t.Errorf("expected the '%s' cookie of the domain 'localhost' to be cleared, got '%+v'",
+					webAuthnSessionCookie, cleared)
+			}
+
+			// The login session is consumed by the first answer, whatever its outcome
+			resp = post(t, app, "/webauthn/login/finish", test.user.UUID.String(), cookie)
+			expectError(t, resp, fiber.StatusBadRequest, "the passkey request has expired; please try again")
+		})
+	}
+}
+
+func TestWebAuthnLoginWithoutSession(t *testing.T) {
+	user := newMockUser(true, false)
+	wuc, _, _ := newTestWebAuthnUseCase(user, true)
+	app := newTestWebAuthnApp(wuc, user)
+
+	resp := post(t, app, "/webauthn/login/finish", user.UUID.String())
+	expectError(t, resp, fiber.StatusBadRequest, "the passkey request has expired; please try again")
+}
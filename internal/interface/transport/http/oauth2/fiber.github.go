@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const gitHubAPIURL = "https://api.github.com"
+
+type GitHubOAuth2 struct {
+	GitHubLoginConfig    oauth2.Config
+	APIURL               string
+	PostLoginRedirectURL string
+	StateExpiredIn       time.Duration
+	r                    rp.RedisUserRepository
+	us                   appSvc.UserService
+	auc                  usecase.AuthUseCase
+}
+
+// gitHubUser is the part of the `/user` response of GitHub that identifies the user.
+type gitHubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+// gitHubEmail is an entry of the `/user/emails` response, which also lists the private emails.
+type gitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func NewGitHubOAuth2(
+	OAuth2Config *entity.OAuth2Config, r rp.RedisUserRepository, us appSvc.UserService, auc usecase.AuthUseCase,
+) usecase.OAuth2UseCase {
+	gitHubLoginConfig := oauth2.Config{
+		RedirectURL:  OAuth2Config.GitHubRedirectURL,
+		ClientID:     OAuth2Config.GitHubClientID,
+		ClientSecret: OAuth2Config.GitHubClientSecret,
+		Scopes:       OAuth2Config.GitHubScopes,
+		Endpoint:     github.Endpoint,
+	}
+
+	return &GitHubOAuth2{
+		GitHubLoginConfig:    gitHubLoginConfig,
+		APIURL:               gitHubAPIURL,
+		PostLoginRedirectURL: OAuth2Config.PostLoginRedirectURL,
+		StateExpiredIn:       OAuth2Config.StateExpiredIn,
+		r:                    r,
+		us:                   us,
+		auc:                  auc,
+	}
+}
+
+// Login redirects to GitHub, which is not an OpenID Connect provider, so the login has no `nonce`.
+func (oa GitHubOAuth2) Login(c *fiber.Ctx) error {
+	return oa.authorize(c, "")
+}
+
+func (oa GitHubOAuth2) Link(c *fiber.Ctx) error {
+	return oa.authorize(c, loggedInUserUUID(c))
+}
+
+func (oa GitHubOAuth2) authorize(c *fiber.Ctx, linkUserUUID string) error {
+	state, oauth2State, err := startAuthorization(c, oa.r, oa.us, entity.ProviderGitHub, oa.StateExpiredIn,
+		linkUserUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	url := oa.GitHubLoginConfig.AuthCodeURL(state, oauth2.S256ChallengeOption(oauth2State.CodeVerifier))
+	return c.Redirect(url, fiber.StatusSeeOther)
+}
+
+// Callback logs in the user of the GitHub account like `GoogleOAuth2.Callback`.
+func (oa GitHubOAuth2) Callback(c *fiber.Ctx) error {
+	state, errState := consumeAuthorization(c, oa.r, oa.us, entity.ProviderGitHub)
+	if errState != nil {
+		return c.Status(errState.Status).JSON(fiber.Map{"status": "fail", "error": errState})
+	}
+
+	externalUser, errExchange := oa.exchange(c.Query("code"), state.CodeVerifier)
+	if errExchange != nil {
+		return c.Status(errExchange.Status).JSON(fiber.Map{"status": "fail", "error": errExchange})
+	}
+
+	return finishExternalLogin(c, oa.us, oa.auc, state, externalUser, oa.PostLoginRedirectURL)
+}
+
+// exchange redeems the code of the callback and returns the GitHub account with its primary email.
+func (oa GitHubOAuth2) exchange(code, codeVerifier string) (*entity.ExternalUser, *restErr.RestErr) {
+	ctx := context.Background()
+	token, err := oa.GitHubLoginConfig.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	client := oa.GitHubLoginConfig.Client(ctx, token)
+	var user gitHubUser
+	if err := oa.getJSON(client, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	if user.ID == 0 {
+		log.Error().Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	var emails []gitHubEmail
+	if err := oa.getJSON(client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	// Only the primary email is used; a verified secondary email must not link someone else's account
+	externalUser := &entity.ExternalUser{Provider: entity.ProviderGitHub, Subject: strconv.FormatInt(user.ID, 10)}
+	for _, email := range emails {
+		if email.Primary {
+			externalUser.Email = strings.ToLower(email.Email)
+			externalUser.EmailVerified = email.Verified
+			break
+		}
+	}
+
+	// GitHub only has a display name, which defaults to the login
+	name := strings.Fields(strings.ToLower(user.Name))
+	if len(name) == 0 {
+		name = []string{strings.ToLower(user.Login)}
+	}
+
+	externalUser.FirstName = name[0]
+	externalUser.LastName = strings.Join(name[1:], " ")
+	return externalUser, nil
+}
+
+func (oa GitHubOAuth2) getJSON(client *http.Client, path string, v interface{}) *restErr.RestErr {
+	req, err := http.NewRequest(http.MethodGet, oa.APIURL+path, nil)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusOK {
+		log.Error().Int("status", resp.StatusCode).Str("path", path).Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		log.Error().Err(err).Msg(restErr.ErrMsgGitHubOAuth2Error)
+		return restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testGitHubCode        = "test-code"
+	testPostLoginRedirect = "http://localhost:3030/"
+)
+
+/*
+gitHubStub stands in for the OAuth2 endpoints and the REST API of GitHub.
+The token endpoint redeems `testGitHubCode` when the code verifier matches the code challenge of the login.
+*/
+type gitHubStub struct {
+	*httptest.Server
+	codeChallenge string
+	user          map[string]interface{}
+	emails        []map[string]interface{}
+	apiStatus     int
+}
+
+func newGitHubStub() *gitHubStub {
+	s := &gitHubStub{
+		user: map[string]interface{}{"id": 583231, "login": "octocat", "name": "The Octocat"},
+		emails: []map[string]interface{}{
+			{"email": "octocat@users.noreply.github.com", "primary": false, "verified": true},
+			{"email": "OctoCat@GitHub.com", "primary": true, "verified": true},
+		},
+		apiStatus: http.StatusOK,
+	}
+
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	authorized := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer test-access-token"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != testGitHubCode ||
+			oauth2.S256ChallengeFromVerifier(r.FormValue("code_verifier")) != s.codeChallenge {
+			writeJSON(w, http.StatusOK, map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "test-access-token", "token_type": "bearer"})
+	})
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, nil)
+			return
+		}
+		writeJSON(w, s.apiStatus, s.user)
+	})
+
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, nil)
+			return
+		}
+		writeJSON(w, s.apiStatus, s.emails)
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func newTestGitHubOAuth2(s *gitHubStub) (*GitHubOAuth2, *mockRedisUserRepository, *mockUserService,
+	*mockAuthUseCase) {
+	r, us, auc := newMockRedisUserRepository(), &mockUserService{}, &mockAuthUseCase{}
+	oa := NewGitHubOAuth2(&entity.OAuth2Config{
+		GitHubRedirectURL:    "http://localhost:4040/auth/github_callback",
+		GitHubClientID:       "github-client-id",
+		GitHubClientSecret:   "github-client-secret",
+		GitHubScopes:         []string{"read:user", "user:email"},
+		PostLoginRedirectURL: testPostLoginRedirect,
+		StateExpiredIn:       10 * time.Minute,
+	}, r, us, auc).(*GitHubOAuth2)
+
+	oa.GitHubLoginConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:   s.URL + "/login/oauth/authorize",
+		TokenURL:  s.URL + "/login/oauth/access_token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	oa.APIURL = s.URL
+	return oa, r, us, auc
+}
+
+// newTestApp mounts the link route behind a stand-in for the `Deserializer` that logs in `linkUserUUID`.
+func newTestApp(oa *GitHubOAuth2, linkUserUUID *uuid.UUID) *fiber.App {
+	app := fiber.New()
+	app.Get("/github_login", oa.Login)
+	app.Get("/github_callback", oa.Callback)
+	app.Get("/me/identities/github/link", func(c *fiber.Ctx) error {
+		c.Locals("userRecord", &dto.UserRecord{UUID: linkUserUUID})
+		return c.Next()
+	}, oa.Link)
+	return app
+}
+
+// login starts a login at `path` and returns the state cookie and the `state` of the redirect to GitHub.
+func login(t *testing.T, app *fiber.App, s *gitHubStub, path string) (*http.Cookie, string) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected status '%d', got '%d'", fiber.StatusSeeOther, resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), s.URL+"/login/oauth/authorize") {
+		t.Fatalf("expected a redirect to the authorization endpoint, got '%s'", resp.Header.Get("Location"))
+	}
+
+	query := location.Query()
+	if query.Get("client_id") != "github-client-id" || query.Get("scope") != "read:user user:email" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Errorf("expected the client, scopes and PKCE code challenge in the redirect, got '%s'", location.RawQuery)
+	}
+	s.codeChallenge = query.Get("code_challenge")
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oauth2StateCookie {
+			if cookie.Value != query.Get("state") || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("expected an HttpOnly and SameSite=Lax cookie with the state, got '%+v'", cookie)
+			}
+			return cookie, query.Get("state")
+		}
+	}
+
+	t.Fatalf("expected the '%s' cookie to be set", oauth2StateCookie)
+	return nil, ""
+}
+
+func callback(t *testing.T, app *fiber.App, cookie *http.Cookie, state string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/github_callback?"+url.Values{"code": {testGitHubCode}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("callback failed: %v", err)
+	}
+	return resp
+}
+
+func TestGitHubLogin(t *testing.T) {
+	s := newGitHubStub()
+	defer s.Close()
+	oa, _, us, auc := newTestGitHubOAuth2(s)
+	app := newTestApp(oa, nil)
+
+	cookie, state := login(t, app, s, "/github_login")
+	resp := callback(t, app, cookie, state)
+	if resp.StatusCode != fiber.StatusSeeOther || resp.Header.Get("Location") != testPostLoginRedirect {
+		t.Fatalf("expected a redirect to '%s', got '%d' to '%s'",
+			testPostLoginRedirect, resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if auc.sessions != 1 {
+		t.Errorf("expected a session to be issued, got '%d'", auc.sessions)
+	}
+
+	// The state cookie is cleared with the domain it was set with, or the browser would keep it
+	cleared := false
+	for _, c := range resp.Cookies() {
+		if c.Name == oauth2StateCookie {
+			cleared = c.Value == "" && c.Domain == cookie.Domain && c.Path == "/" && c.Expires.Before(time.Now())
+		}
+	}
+	if !cleared || cookie.Domain != "localhost" {
+		t.Errorf("expected the '%s' cookie of the domain 'localhost' to be cleared", oauth2StateCookie)
+	}
+
+	expected := entity.ExternalUser{Provider: entity.ProviderGitHub, Subject: "583231", Email: "octocat@github.com",
+		EmailVerified: true, FirstName: "the", LastName: "octocat"}
+	if us.externalUser == nil || *us.externalUser != expected || us.linkedUser != "" {
+		t.Errorf("expected '%+v' to login, got '%+v'", expected, us.externalUser)
+	}
+
+	// The state is consumed, so the callback cannot be replayed
+	if resp := callback(t, app, cookie, state); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status '%d' for a replayed callback, got '%d'", fiber.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestGitHubLink(t *testing.T) {
+	s := newGitHubStub()
+	defer s.Close()
+	oa, _, us, auc := newTestGitHubOAuth2(s)
+	userUUID := uuid.New()
+	app := newTestApp(oa, &userUUID)
+
+	cookie, state := login(t, app, s, "/me/identities/github/link")
+	resp := callback(t, app, cookie, state)
+	if location := resp.Header.Get("Location"); resp.StatusCode != fiber.StatusSeeOther ||
+		location != testPostLoginRedirect+"#linked=github" {
+		t.Fatalf("expected a redirect to '%s#linked=github', got '%d' to '%s'",
+			testPostLoginRedirect, resp.StatusCode, location)
+	}
+
+	if us.linkedUser != userUUID.String() || us.externalUser == nil || us.externalUser.Subject != "583231" {
+		t.Errorf("expected the GitHub account to be linked to '%s', got '%s'", userUUID.String(), us.linkedUser)
+	}
+
+	if auc.sessions != 0 {
+		t.Errorf("expected no session to be issued when linking, got '%d'", auc.sessions)
+	}
+}
+
+func TestGitHubCallbackState(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie func(cookie *http.Cookie) *http.Cookie
+		state  func(state string) string
+	}{
+		{name: "Missing cookie",
+			cookie: func(cookie *http.Cookie) *http.Cookie { return nil },
+			state:  func(state string) string { return state }},
+		{name: "Cookie of another login",
+			cookie: func(cookie *http.Cookie) *http.Cookie {
+				return &http.Cookie{Name: oauth2StateCookie, Value: "another-state"}
+			},
+			state: func(state string) string { return state }},
+		{name: "Missing state",
+			cookie: func(cookie *http.Cookie) *http.Cookie { return cookie },
+			state:  func(state string) string { return "" }},
+		{name: "Forged state",
+			cookie: func(cookie *http.Cookie) *http.Cookie {
+				return &http.Cookie{Name: oauth2StateCookie, Value: "forged-state"}
+			},
+			state: func(state string) string { return "forged-state" }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newGitHubStub()
+			defer s.Close()
+			oa, _, us, auc := newTestGitHubOAuth2(s)
+			app := newTestApp(oa, nil)
+
+			cookie, state := login(t, app, s, "/github_login")
+			resp := callback(t, app, test.cookie(cookie), test.state(state))
+			if resp.StatusCode != fiber.StatusBadRequest {
+				t.Errorf("expected status '%d', got '%d'", fiber.StatusBadRequest, resp.StatusCode)
+			}
+
+			if us.externalUser != nil || auc.sessions != 0 {
+				t.Errorf("expected no login, got '%+v'", us.externalUser)
+			}
+		})
+	}
+}
+
+func TestGitHubExchange(t *testing.T) {
+	tests := []struct {
+		name           string
+		setup          func(s *gitHubStub)
+		verifier       string
+		expected       entity.ExternalUser
+		expectedStatus int
+	}{
+		{name: "Primary email",
+			setup: func(s *gitHubStub) {},
+			expected: entity.ExternalUser{Provider: entity.ProviderGitHub, Subject: "583231",
+				Email: "octocat@github.com", EmailVerified: true, FirstName: "the", LastName: "octocat"}},
+		{name: "Unverified primary email",
+			setup: func(s *gitHubStub) { s.emails[1]["verified"] = false },
+			expected: entity.ExternalUser{Provider: entity.ProviderGitHub, Subject: "583231",
+				Email: "octocat@github.com", EmailVerified: false, FirstName: "the", LastName: "octocat"}},
+		{name: "No display name",
+			setup: func(s *gitHubStub) { s.user["name"] = nil },
+			expected: entity.ExternalUser{Provider: entity.ProviderGitHub, Subject: "583231",
+				Email: "octocat@github.com", EmailVerified: true, FirstName: "octocat"}},
+		{name: "Wrong code verifier", setup: func(s *gitHubStub) {},
+			verifier: oauth2.GenerateVerifier(), expectedStatus: fiber.StatusBadRequest},
+		{name: "API error",
+			setup:          func(s *gitHubStub) { s.apiStatus = http.StatusInternalServerError },
+			expectedStatus: fiber.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newGitHubStub()
+			defer s.Close()
+			oa, _, _, _ := newTestGitHubOAuth2(s)
+			test.setup(s)
+
+			verifier := oauth2.GenerateVerifier()
+			s.codeChallenge = oauth2.S256ChallengeFromVerifier(verifier)
+			if test.verifier != "" {
+				verifier = test.verifier
+			}
+
+			externalUser, err := oa.exchange(testGitHubCode, verifier)
+			if test.expectedStatus != 0 {
+				if err == nil || err.Status != test.expectedStatus {
+					t.Errorf("expected status '%d', got '%v'", test.expectedStatus, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("exchange failed: %v", err)
+			}
+
+			if *externalUser != test.expected {
+				t.Errorf("expected '%+v', got '%+v'", test.expected, externalUser)
+			}
+		})
+	}
+}
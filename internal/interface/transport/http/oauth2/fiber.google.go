@@ -3,11 +3,14 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"io"
-	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
 	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
 	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
 	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
@@ -16,13 +19,32 @@ import (
 	"golang.org/x/oauth2/google"
 )
 
-const googleUserInfoEndpoint = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
+const googleUserInfoEndpoint = "https://www.googleapis.com/oauth2/v2/userinfo"
+
+// googleIssuers are the `iss` claims that Google puts in its ID tokens.
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
 
 type GoogleOAuth2 struct {
-	GoogleLoginConfig oauth2.Config
+	GoogleLoginConfig    oauth2.Config
+	PostLoginRedirectURL string
+	StateExpiredIn       time.Duration
+	r                    rp.RedisUserRepository
+	us                   appSvc.UserService
+	auc                  usecase.AuthUseCase
+}
+
+// googleUserInfo is the part of the userinfo response of Google that identifies the user.
+type googleUserInfo struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
 }
 
-func NewGoogleOAuth2(OAuth2Config *entity.OAuth2Config) usecase.OAuth2UseCase {
+func NewGoogleOAuth2(
+	OAuth2Config *entity.OAuth2Config, r rp.RedisUserRepository, us appSvc.UserService, auc usecase.AuthUseCase,
+) usecase.OAuth2UseCase {
 	googleLoginConfig := oauth2.Config{
 		RedirectURL:  OAuth2Config.GoogleRedirectURL,
 		ClientID:     OAuth2Config.GoogleClientID,
@@ -31,48 +53,146 @@ func NewGoogleOAuth2(OAuth2Config *entity.OAuth2Config) usecase.OAuth2UseCase {
 		Endpoint:     google.Endpoint,
 	}
 
-	return &GoogleOAuth2{GoogleLoginConfig: googleLoginConfig}
+	return &GoogleOAuth2{
+		GoogleLoginConfig:    googleLoginConfig,
+		PostLoginRedirectURL: OAuth2Config.PostLoginRedirectURL,
+		StateExpiredIn:       OAuth2Config.StateExpiredIn,
+		r:                    r,
+		us:                   us,
+		auc:                  auc,
+	}
 }
 
 func (oa GoogleOAuth2) Login(c *fiber.Ctx) error {
-	url := oa.GoogleLoginConfig.AuthCodeURL("randomstate")
+	return oa.authorize(c, "")
+}
+
+func (oa GoogleOAuth2) Link(c *fiber.Ctx) error {
+	return oa.authorize(c, loggedInUserUUID(c))
+}
+
+func (oa GoogleOAuth2) authorize(c *fiber.Ctx, linkUserUUID string) error {
+	state, oauth2State, err := startAuthorization(c, oa.r, oa.us, entity.ProviderGoogle, oa.StateExpiredIn,
+		linkUserUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	url := oa.GoogleLoginConfig.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(oauth2State.CodeVerifier),
+		oauth2.SetAuthURLParam("nonce", oauth2State.Nonce),
+	)
+
 	return c.Redirect(url, fiber.StatusSeeOther)
 }
 
+/*
+Callback logs in the user of the Google account, creating or linking a local user on the first login,
+and redirects the browser to the post-login page with the same session cookies as `AuthUseCase.Login`.
+After `Link`, it links the Google account to the user who started it instead.
+*/
 func (oa GoogleOAuth2) Callback(c *fiber.Ctx) error {
-	state := c.Query("state")
-	if state != "randomstate" {
-		err := restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
-		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	state, errState := consumeAuthorization(c, oa.r, oa.us, entity.ProviderGoogle)
+	if errState != nil {
+		return c.Status(errState.Status).JSON(fiber.Map{"status": "fail", "error": errState})
 	}
 
 	code := c.Query("code")
-	token, err := oa.GoogleLoginConfig.Exchange(context.Background(), code)
+	token, err := oa.GoogleLoginConfig.Exchange(context.Background(), code, oauth2.VerifierOption(state.CodeVerifier))
 	if err != nil {
 		err := restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	resp, err := http.Get(googleUserInfoEndpoint + token.AccessToken)
-	if err != nil {
-		log.Error().Err(err).Msg(restErr.ErrMsgGoogleOAuth2Error)
-		err := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	if err := checkIDToken(token, oa.GoogleLoginConfig.ClientID, state.Nonce, googleIssuers...); err != nil {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	userInfo, err := io.ReadAll(resp.Body)
+	externalUser, errUserInfo := oa.getUserInfo(token)
+	if errUserInfo != nil {
+		return c.Status(errUserInfo.Status).JSON(fiber.Map{"status": "fail", "error": errUserInfo})
+	}
+
+	return finishExternalLogin(c, oa.us, oa.auc, state, externalUser, oa.PostLoginRedirectURL)
+}
+
+func (oa GoogleOAuth2) getUserInfo(token *oauth2.Token) (*entity.ExternalUser, *restErr.RestErr) {
+	resp, err := oa.GoogleLoginConfig.Client(context.Background(), token).Get(googleUserInfoEndpoint)
 	if err != nil {
 		log.Error().Err(err).Msg(restErr.ErrMsgGoogleOAuth2Error)
-		err := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+		return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusOK {
+		log.Error().Int("status", resp.StatusCode).Msg(restErr.ErrMsgGoogleOAuth2Error)
+		return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	var userInfo googleUserInfo
+	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil || userInfo.ID == "" {
+		log.Error().Err(err).Msg(restErr.ErrMsgGoogleOAuth2Error)
+		return nil, restErr.NewBadGatewayError(restErr.ErrMsgSomethingWentWrong)
+	}
+
+	return &entity.ExternalUser{
+		Provider:      entity.ProviderGoogle,
+		Subject:       userInfo.ID,
+		Email:         strings.ToLower(userInfo.Email),
+		EmailVerified: userInfo.VerifiedEmail,
+		FirstName:     strings.ToLower(strings.TrimSpace(userInfo.GivenName)),
+		LastName:      strings.ToLower(strings.TrimSpace(userInfo.FamilyName)),
+	}, nil
+}
+
+/*
+finishExternalLogin links the external account to the user who started a link, who is sent back to `redirectURL`
+with the provider in the fragment. Otherwise it logs in the user of the external account.
+*/
+func finishExternalLogin(c *fiber.Ctx, us appSvc.UserService, auc usecase.AuthUseCase, state *entity.OAuth2State,
+	externalUser *entity.ExternalUser, redirectURL string) error {
+	if state.LinkUserUUID != "" {
+		if err := us.LinkExternalIdentity(state.LinkUserUUID, externalUser); err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		fragment := url.Values{"linked": {externalUser.Provider}}
+		return c.Redirect(redirectURL+"#"+fragment.Encode(), fiber.StatusSeeOther)
+	}
+
+	user, err := us.FindOrCreateExternalUser(externalUser)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return finishLogin(c, auc, user, redirectURL)
+}
+
+/*
+finishLogin starts the session of the user and redirects the browser to `redirectURL`.
+Users with two-factor authentication are sent there with an MFA token in the fragment instead,
+which keeps it out of server logs, and finish the login with `LoginMFA`.
+*/
+func finishLogin(c *fiber.Ctx, auc usecase.AuthUseCase, user *entity.User, redirectURL string) error {
+	if user.DisabledAt != nil {
+		err := restErr.NewForbiddenError(restErr.ErrMsgAccountDisabled)
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	var user map[string]interface{}
-	if err := json.Unmarshal(userInfo, &user); err != nil {
-		log.Error().Err(err).Msg("")
-		err := restErr.NewInternalServerError(restErr.ErrMsgSomethingWentWrong)
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := auc.IssueMFAChallenge(user.UUID.String())
+		if err != nil {
+			return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+		}
+
+		fragment := url.Values{"mfa_token": {mfaToken}}
+		return c.Redirect(redirectURL+"#"+fragment.Encode(), fiber.StatusSeeOther)
+	}
+
+	if _, err := auc.IssueSession(c, user.UUID.String()); err != nil {
 		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "user": user})
+	return c.Redirect(redirectURL, fiber.StatusSeeOther)
 }
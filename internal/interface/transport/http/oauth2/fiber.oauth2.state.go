@@ -0,0 +1,158 @@
+package http
+
+import (
+	"crypto/subtle"
+	"slices"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+// oauth2StateCookie binds the `state` of a login to the browser that started it.
+const oauth2StateCookie = "oauth2_state"
+
+const (
+	errMsgInvalidState = "oauth2 state does not match the state cookie"
+	errMsgInvalidToken = "oauth2 ID token is invalid"
+)
+
+/*
+startAuthorization generates the random `state`, PKCE code verifier and `nonce` of a login at the provider.
+The state is kept in a short-lived cookie and, hashed, in Redis along with the code verifier and the nonce,
+so that `consumeAuthorization` only accepts the callback of this login and only once.
+`linkUserUUID` is the logged in user that the external account is linked to, or empty for a login.
+*/
+func startAuthorization(
+	c *fiber.Ctx, r rp.RedisUserRepository, us appSvc.UserService, provider string, expiresIn time.Duration,
+	linkUserUUID string,
+) (string, *entity.OAuth2State, *restErr.RestErr) {
+	state, err := randtoken.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := randtoken.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	oauth2State := &entity.OAuth2State{
+		Provider:     provider,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        nonce,
+		LinkUserUUID: linkUserUUID,
+	}
+	if err := r.SetOAuth2State(randtoken.Hash(state), oauth2State, time.Now().Add(expiresIn).Unix()); err != nil {
+		return "", nil, err
+	}
+
+	c.Cookie(newStateCookie(us.GetJWTConfig(), state, expiresIn))
+	return state, oauth2State, nil
+}
+
+/*
+consumeAuthorization checks that the `state` of the callback is the one in the cookie of the browser
+and returns what `startAuthorization` kept for it. The cookie and the Redis entry are both removed,
+so a callback URL cannot be replayed.
+*/
+func consumeAuthorization(
+	c *fiber.Ctx, r rp.RedisUserRepository, us appSvc.UserService, provider string,
+) (*entity.OAuth2State, *restErr.RestErr) {
+	state := c.Query("state")
+	cookieState := c.Cookies(oauth2StateCookie)
+	c.Cookie(newStateCookie(us.GetJWTConfig(), "", 0))
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		log.Warn().Msg(errMsgInvalidState)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	oauth2State, err := r.ConsumeOAuth2State(randtoken.Hash(state))
+	if err != nil {
+		return nil, err
+	}
+
+	if oauth2State.Provider != provider {
+		log.Warn().Str("provider", provider).Msg(errMsgInvalidState)
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	return oauth2State, nil
+}
+
+/*
+newStateCookie sets the state cookie or, without a `state`, clears it.
+Browsers only delete a cookie that is set again with the same domain and path.
+*/
+func newStateCookie(jwtConfig *entity.JWTConfig, state string, expiresIn time.Duration) *fiber.Cookie {
+	cookie := &fiber.Cookie{
+		Name:     oauth2StateCookie,
+		Value:    state,
+		Path:     "/",
+		Domain:   jwtConfig.Domain,
+		Secure:   jwtConfig.Secure,
+		HTTPOnly: true,
+		SameSite: "lax", // The callback is a cross-site navigation from the provider
+	}
+
+	if state == "" {
+		cookie.Expires = time.Now().Add(-time.Hour)
+	} else {
+		cookie.MaxAge = int(expiresIn.Seconds())
+	}
+
+	return cookie
+}
+
+// loggedInUserUUID is the user set by the `Deserializer` on the routes that link an external account.
+func loggedInUserUUID(c *fiber.Ctx) string {
+	return c.Locals("userRecord").(*dto.UserRecord).UUID.String()
+}
+
+/*
+checkIDToken checks that the ID token returned with `token` was issued by one of `issuers` for `clientID`
+and carries the nonce of this login. The token comes straight from the token endpoint of the provider
+over TLS, so its claims are read without verifying the signature.
+*/
+func checkIDToken(token *oauth2.Token, clientID, nonce string, issuers ...string) *restErr.RestErr {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		log.Error().Msg(errMsgInvalidToken)
+		return restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(rawIDToken, claims); err != nil {
+		log.Error().Err(err).Msg(errMsgInvalidToken)
+		return restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		log.Warn().Msg(errMsgInvalidToken)
+		return restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil || !slices.Contains(audience, clientID) {
+		log.Warn().Err(err).Msg(errMsgInvalidToken)
+		return restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	issuer, err := claims.GetIssuer()
+	if err != nil || !slices.Contains(issuers, issuer) {
+		log.Warn().Err(err).Str("iss", issuer).Msg(errMsgInvalidToken)
+		return restErr.NewBadRequestError(restErr.ErrMsgPleaseLoginAgain)
+	}
+
+	return nil
+}
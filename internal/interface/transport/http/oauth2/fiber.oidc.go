@@ -0,0 +1,98 @@
+package http
+
+import (
+	"time"
+
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OIDCOAuth2 logs users in at the OpenID Connect provider named by the `:provider` param of the route.
+type OIDCOAuth2 struct {
+	providers            map[string]service.OIDCProvider
+	PostLoginRedirectURL string
+	StateExpiredIn       time.Duration
+	r                    rp.RedisUserRepository
+	us                   appSvc.UserService
+	auc                  usecase.AuthUseCase
+}
+
+func NewOIDCOAuth2(
+	OAuth2Config *entity.OAuth2Config, providers []service.OIDCProvider,
+	r rp.RedisUserRepository, us appSvc.UserService, auc usecase.AuthUseCase,
+) usecase.OAuth2UseCase {
+	providersByName := map[string]service.OIDCProvider{}
+	for _, provider := range providers {
+		providersByName[provider.Name()] = provider
+	}
+
+	return &OIDCOAuth2{
+		providers:            providersByName,
+		PostLoginRedirectURL: OAuth2Config.PostLoginRedirectURL,
+		StateExpiredIn:       OAuth2Config.StateExpiredIn,
+		r:                    r,
+		us:                   us,
+		auc:                  auc,
+	}
+}
+
+func (oa OIDCOAuth2) Login(c *fiber.Ctx) error {
+	return oa.authorize(c, "")
+}
+
+func (oa OIDCOAuth2) Link(c *fiber.Ctx) error {
+	return oa.authorize(c, loggedInUserUUID(c))
+}
+
+func (oa OIDCOAuth2) authorize(c *fiber.Ctx, linkUserUUID string) error {
+	provider, errProvider := oa.provider(c)
+	if errProvider != nil {
+		return c.Status(errProvider.Status).JSON(fiber.Map{"status": "fail", "error": errProvider})
+	}
+
+	state, oauth2State, err := startAuthorization(c, oa.r, oa.us, provider.Name(), oa.StateExpiredIn, linkUserUUID)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	url, err := provider.AuthCodeURL(state, oauth2State.CodeVerifier, oauth2State.Nonce)
+	if err != nil {
+		return c.Status(err.Status).JSON(fiber.Map{"status": "fail", "error": err})
+	}
+
+	return c.Redirect(url, fiber.StatusSeeOther)
+}
+
+// Callback logs in the user of the verified ID token like `GoogleOAuth2.Callback`.
+func (oa OIDCOAuth2) Callback(c *fiber.Ctx) error {
+	provider, errProvider := oa.provider(c)
+	if errProvider != nil {
+		return c.Status(errProvider.Status).JSON(fiber.Map{"status": "fail", "error": errProvider})
+	}
+
+	state, errState := consumeAuthorization(c, oa.r, oa.us, provider.Name())
+	if errState != nil {
+		return c.Status(errState.Status).JSON(fiber.Map{"status": "fail", "error": errState})
+	}
+
+	externalUser, errExchange := provider.Exchange(c.Query("code"), state.CodeVerifier, state.Nonce)
+	if errExchange != nil {
+		return c.Status(errExchange.Status).JSON(fiber.Map{"status": "fail", "error": errExchange})
+	}
+
+	return finishExternalLogin(c, oa.us, oa.auc, state, externalUser, oa.PostLoginRedirectURL)
+}
+
+func (oa OIDCOAuth2) provider(c *fiber.Ctx) (service.OIDCProvider, *restErr.RestErr) {
+	provider, ok := oa.providers[c.Params("provider")]
+	if !ok {
+		return nil, restErr.NewNotFoundError(restErr.ErrMsgOIDCProviderNotFound)
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,73 @@
+// coverage:ignore file
+// Test file
+package http
+
+import (
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/application/usecase"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// The mocks embed their interface so that only the methods used by the OAuth2 logins are implemented.
+
+type mockRedisUserRepository struct {
+	rp.RedisUserRepository
+	states map[string]*entity.OAuth2State
+}
+
+func newMockRedisUserRepository() *mockRedisUserRepository {
+	return &mockRedisUserRepository{states: map[string]*entity.OAuth2State{}}
+}
+
+func (m *mockRedisUserRepository) SetOAuth2State(stateHash string, state *entity.OAuth2State,
+	expiresIn int64) *restErr.RestErr {
+	m.states[stateHash] = state
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumeOAuth2State(stateHash string) (*entity.OAuth2State, *restErr.RestErr) {
+	state, ok := m.states[stateHash]
+	if !ok {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgOAuth2StateExpired)
+	}
+
+	delete(m.states, stateHash)
+	return state, nil
+}
+
+type mockUserService struct {
+	appSvc.UserService
+	externalUser *entity.ExternalUser // Last user passed to `FindOrCreateExternalUser`
+	linkedUser   string               // Last user passed to `LinkExternalIdentity`
+}
+
+func (m *mockUserService) GetJWTConfig() *entity.JWTConfig {
+	return &entity.JWTConfig{Domain: "localhost"}
+}
+
+func (m *mockUserService) FindOrCreateExternalUser(externalUser *entity.ExternalUser) (
+	*entity.User, *restErr.RestErr) {
+	m.externalUser = externalUser
+	userUUID := uuid.New()
+	return &entity.User{UUID: &userUUID, Email: externalUser.Email}, nil
+}
+
+func (m *mockUserService) LinkExternalIdentity(userUuid string, externalUser *entity.ExternalUser) *restErr.RestErr {
+	m.externalUser = externalUser
+	m.linkedUser = userUuid
+	return nil
+}
+
+type mockAuthUseCase struct {
+	usecase.AuthUseCase
+	sessions int
+}
+
+func (m *mockAuthUseCase) IssueSession(c *fiber.Ctx, userUUID string) (*entity.Token, *restErr.RestErr) {
+	m.sessions++
+	return &entity.Token{}, nil
+}
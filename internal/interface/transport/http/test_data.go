@@ -0,0 +1,86 @@
+// coverage:ignore file
+// Test file
+package http
+
+import (
+	"time"
+
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	"github.com/google/uuid"
+)
+
+var mockVerifiedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newMockUser(verified bool, disabled bool) *entity.User {
+	userUUID := uuid.New()
+	user := &entity.User{UUID: &userUUID, Email: "user@test.com"}
+	if verified {
+		user.EmailVerifiedAt = &mockVerifiedAt
+	}
+	if disabled {
+		user.DisabledAt = &mockVerifiedAt
+	}
+	return user
+}
+
+var webAuthnLoginTests = []struct {
+	name                     string
+	user                     *entity.User
+	requireEmailVerification bool
+	expectedStatus           int
+	expectedErrMsg           string
+}{
+	{name: "Verified user", user: newMockUser(true, false), requireEmailVerification: true, expectedStatus: 200},
+	{
+		name: "Unverified user without required verification", user: newMockUser(false, false),
+		requireEmailVerification: false, expectedStatus: 200,
+	},
+	{
+		name: "Unverified user", user: newMockUser(false, false), requireEmailVerification: true,
+		expectedStatus: 403, expectedErrMsg: "please verify your email address before logging in",
+	},
+	{
+		name: "Disabled user", user: newMockUser(true, true), requireEmailVerification: true,
+		expectedStatus: 403, expectedErrMsg: "this account has been disabled",
+	},
+}
+
+const mockAPIKey = "ak_mockAPIKey"
+
+var mockRolePermissions = map[string][]string{"user": {entity.PermissionUsersRead}}
+
+// The self-service routes that a personal API key must not reach
+var apiKeySessionRoutes = []struct {
+	method string
+	path   string
+}{
+	{method: "GET", path: "/me"},
+	{method: "DELETE", path: "/me"},
+	{method: "GET", path: "/me/export"},
+	{method: "POST", path: "/me/password"},
+	{method: "GET", path: "/me/identities"},
+	{method: "GET", path: "/sessions"},
+	{method: "POST", path: "/logout-all"},
+	{method: "POST", path: "/me/mfa/totp"},
+	{method: "POST", path: "/webauthn/register/begin"},
+	{method: "GET", path: "/me/api-keys"},
+	{method: "POST", path: "/me/api-keys"},
+}
+
+var createAPIKeyTests = []struct {
+	name           string
+	body           string
+	expectedStatus int
+	expectedErrMsg string
+}{
+	{name: "Granted scope", body: `{"name":"ci","scopes":["users:read"]}`, expectedStatus: 201},
+	{name: "No scopes", body: `{"name":"ci"}`, expectedStatus: 201},
+	{
+		name: "Scope not granted to the user", body: `{"name":"ci","scopes":["users:write"]}`,
+		expectedStatus: 400, expectedErrMsg: "the field [scopes] should only list permissions of the user",
+	},
+	{
+		name: "Unknown scope", body: `{"name":"ci","scopes":["users:impersonate"]}`,
+		expectedStatus: 400, expectedErrMsg: "validation error: the field [scopes[0]] should be a known permission",
+	},
+}
@@ -0,0 +1,359 @@
+// coverage:ignore file
+// Test file
+package http
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"time"
+
+	dto "github.com/DarrelA/starter-go-postgresql/internal/application/dto"
+	appSvc "github.com/DarrelA/starter-go-postgresql/internal/application/service"
+	"github.com/DarrelA/starter-go-postgresql/internal/domain/entity"
+	rp "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/postgres"
+	r "github.com/DarrelA/starter-go-postgresql/internal/domain/repository/redis"
+	domainSvc "github.com/DarrelA/starter-go-postgresql/internal/domain/service"
+	restErr "github.com/DarrelA/starter-go-postgresql/internal/error"
+	"github.com/DarrelA/starter-go-postgresql/internal/infrastructure/randtoken"
+	"github.com/google/uuid"
+)
+
+// The mocks embed their interface so that only the methods used by the handlers under test are implemented.
+
+/*
+mockRedisUserRepository keeps the tokens, token families and sessions in memory.
+Like Redis, every operation is atomic, so concurrent refreshes race the same way they do against Redis.
+*/
+type mockRedisUserRepository struct {
+	r.RedisUserRepository
+	mu               sync.Mutex
+	tokens           map[string]string   // Token UUID to user UUID
+	familyOf         map[string]string   // Token UUID to family ID
+	families         map[string][]string // Family ID to token UUIDs
+	sessions         map[string]*entity.Session
+	webAuthnSessions map[string][]byte
+}
+
+func newMockRedisUserRepository() *mockRedisUserRepository {
+	return &mockRedisUserRepository{
+		tokens:           map[string]string{},
+		familyOf:         map[string]string{},
+		families:         map[string][]string{},
+		sessions:         map[string]*entity.Session{},
+		webAuthnSessions: map[string][]byte{},
+	}
+}
+
+func (m *mockRedisUserRepository) SetUserUUID(tokenUUID string, userUUID string, expiresIn int64) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[tokenUUID] = userUUID
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetUserUUID(tokenUUID string) (string, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	userUUID, ok := m.tokens[tokenUUID]
+	if !ok {
+		return "", restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+	return userUUID, nil
+}
+
+func (m *mockRedisUserRepository) DelUserUUID(tokenUUID string, accessTokenUUID string) (int64, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, tokenUUID)
+	delete(m.tokens, accessTokenUUID)
+	return 2, nil
+}
+
+func (m *mockRedisUserRepository) SetTokenFamily(familyID string, tokenUUID string, expiresIn int64) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.families[familyID] = append(m.families[familyID], tokenUUID)
+	m.familyOf[tokenUUID] = familyID
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetTokenFamily(tokenUUID string) (string, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	familyID, ok := m.familyOf[tokenUUID]
+	if !ok {
+		return "", restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+	return familyID, nil
+}
+
+func (m *mockRedisUserRepository) ConsumeRefreshToken(tokenUUID string) (string, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	userUUID, ok := m.tokens[tokenUUID]
+	if !ok {
+		return "", restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+	delete(m.tokens, tokenUUID)
+	return userUUID, nil
+}
+
+func (m *mockRedisUserRepository) DelTokenFamily(familyID string) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delTokenFamily(familyID)
+	return nil
+}
+
+func (m *mockRedisUserRepository) delTokenFamily(familyID string) {
+	for _, tokenUUID := range m.families[familyID] {
+		delete(m.tokens, tokenUUID)
+		delete(m.familyOf, tokenUUID)
+	}
+	delete(m.families, familyID)
+	delete(m.sessions, familyID)
+}
+
+func (m *mockRedisUserRepository) SetSession(session *entity.Session, expiresIn int64) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *mockRedisUserRepository) GetSession(familyID string) (*entity.Session, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[familyID]
+	if !ok {
+		return nil, restErr.NewNotFoundError(restErr.ErrMsgSessionNotFound)
+	}
+	return session, nil
+}
+
+func (m *mockRedisUserRepository) DelUserSessions(userUUID string, exceptFamilyIDs ...string) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for familyID, session := range m.sessions {
+		if session.UserUUID == userUUID && !slices.Contains(exceptFamilyIDs, familyID) {
+			m.delTokenFamily(familyID)
+		}
+	}
+	return nil
+}
+
+func (m *mockRedisUserRepository) SetWebAuthnSession(key string, sessionData []byte,
+	expiresIn int64) *restErr.RestErr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webAuthnSessions[key] = sessionData
+	return nil
+}
+
+func (m *mockRedisUserRepository) ConsumeWebAuthnSession(key string) ([]byte, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessionData, ok := m.webAuthnSessions[key]
+	if !ok {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+	}
+	delete(m.webAuthnSessions, key)
+	return sessionData, nil
+}
+
+// mockTokenService issues opaque tokens that are only valid with the key ring they were created with.
+type mockTokenService struct {
+	domainSvc.TokenService
+	mu       sync.Mutex
+	tokens   map[string]*entity.Token
+	keyRings map[string]*entity.JWTKeyRing
+}
+
+func newMockTokenService() *mockTokenService {
+	return &mockTokenService{tokens: map[string]*entity.Token{}, keyRings: map[string]*entity.JWTKeyRing{}}
+}
+
+func (m *mockTokenService) CreateToken(userUuid string, scope []string, roles []string, ttl time.Duration,
+	keyRing *entity.JWTKeyRing) (*entity.Token, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenUUID := uuid.New().String()
+	token := "mock-token-" + tokenUUID
+	expiresIn := time.Now().Add(ttl).Unix()
+	tokenDetails := &entity.Token{
+		Token: &token, TokenUUID: tokenUUID, UserUUID: userUuid, ExpiresIn: &expiresIn, Scope: scope, Roles: roles,
+	}
+
+	m.tokens[token] = tokenDetails
+	m.keyRings[token] = keyRing
+	return tokenDetails, nil
+}
+
+func (m *mockTokenService) ValidateToken(token string, keyRing *entity.JWTKeyRing) (
+	*entity.Token, *restErr.RestErr) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenDetails, ok := m.tokens[token]
+	if !ok || m.keyRings[token] != keyRing {
+		return nil, restErr.NewUnauthorizedError(restErr.ErrMsgPleaseLoginAgain)
+	}
+	return tokenDetails, nil
+}
+
+type mockUserService struct {
+	appSvc.UserService
+	jwtConfig *entity.JWTConfig
+	users     map[string]*entity.User
+}
+
+func newMockUserService(users ...*entity.User) *mockUserService {
+	m := &mockUserService{
+		jwtConfig: &entity.JWTConfig{
+			Domain:                "localhost",
+			HttpOnly:              true,
+			DefaultScope:          []string{"openid"},
+			AccessTokenKeyRing:    &entity.JWTKeyRing{},
+			AccessTokenExpiredIn:  15 * time.Minute,
+			AccessTokenMaxAge:     15,
+			RefreshTokenKeyRing:   &entity.JWTKeyRing{},
+			RefreshTokenExpiredIn: time.Hour,
+			RefreshTokenMaxAge:    60,
+		},
+		users: map[string]*entity.User{},
+	}
+
+	for _, user := range users {
+		m.users[user.UUID.String()] = user
+	}
+	return m
+}
+
+func (m *mockUserService) GetJWTConfig() *entity.JWTConfig {
+	return m.jwtConfig
+}
+
+func (m *mockUserService) GetUserByUUID(userUuid string) (*entity.User, *restErr.RestErr) {
+	user, ok := m.users[userUuid]
+	if !ok {
+		return nil, restErr.NewNotFoundError(restErr.ErrMsgUserNotFound)
+	}
+	return user, nil
+}
+
+func (m *mockUserService) ListUsers(payload dto.ListUsersInput) (*dto.UserPage, *restErr.RestErr) {
+	return &dto.UserPage{}, nil
+}
+
+func (m *mockUserService) GetUserRoles(userUuid string) ([]string, *restErr.RestErr) {
+	return []string{"user"}, nil
+}
+
+/*
+mockWebAuthnService skips the cryptography of the ceremonies: the response of the authenticator is
+the UUID of the user it answers for, and it is only accepted with the session data of its ceremony.
+*/
+type mockWebAuthnService struct {
+	domainSvc.WebAuthnService
+}
+
+func (m *mockWebAuthnService) BeginRegistration(user *entity.User, credentials []*entity.WebAuthnCredential) (
+	*entity.WebAuthnCeremony, *restErr.RestErr) {
+	return &entity.WebAuthnCeremony{
+		Options: []byte(`{"publicKey":{}}`), SessionData: []byte(webAuthnRegistrationKey + user.UUID.String()),
+	}, nil
+}
+
+func (m *mockWebAuthnService) FinishRegistration(user *entity.User, credentials []*entity.WebAuthnCredential,
+	sessionData []byte, response []byte) (*entity.WebAuthnCredential, *restErr.RestErr) {
+	if !bytes.Equal(sessionData, []byte(webAuthnRegistrationKey+user.UUID.String())) {
+		return nil, restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+	}
+	return &entity.WebAuthnCredential{ID: response, UserUUID: user.UUID.String()}, nil
+}
+
+func (m *mockWebAuthnService) BeginLogin() (*entity.WebAuthnCeremony, *restErr.RestErr) {
+	return &entity.WebAuthnCeremony{Options: []byte(`{"publicKey":{}}`), SessionData: []byte(webAuthnLoginKey)}, nil
+}
+
+func (m *mockWebAuthnService) FinishLogin(sessionData []byte, response []byte,
+	findUser domainSvc.WebAuthnUserFinder) (*entity.User, *entity.WebAuthnCredential, *restErr.RestErr) {
+	if !bytes.Equal(sessionData, []byte(webAuthnLoginKey)) {
+		return nil, nil, restErr.NewBadRequestError(restErr.ErrMsgPasskeyExpired)
+	}
+
+	user, credentials, err := findUser(string(response))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(credentials) == 0 {
+		return nil, nil, restErr.NewUnauthorizedError(restErr.ErrMsgPasskeyExpired)
+	}
+	return user, credentials[0], nil
+}
+
+type mockWebAuthnRepository struct {
+	rp.PostgresWebAuthnRepository
+	credentials map[string][]*entity.WebAuthnCredential
+	updated     int
+}
+
+func (m *mockWebAuthnRepository) SaveCredential(credential *entity.WebAuthnCredential) *restErr.RestErr {
+	m.credentials[credential.UserUUID] = append(m.credentials[credential.UserUUID], credential)
+	return nil
+}
+
+func (m *mockWebAuthnRepository) GetCredentials(userUUID string) ([]*entity.WebAuthnCredential, *restErr.RestErr) {
+	return m.credentials[userUUID], nil
+}
+
+func (m *mockWebAuthnRepository) UpdateCredential(credential *entity.WebAuthnCredential) *restErr.RestErr {
+	m.updated++
+	return nil
+}
+
+// mockAPIKeyRepository keeps the API keys by the hash of their key, like the `Deserializer` looks them up.
+type mockAPIKeyRepository struct {
+	rp.PostgresAPIKeyRepository
+	keys map[string]*entity.APIKey
+}
+
+func newMockAPIKeyRepository(userUUID string, key string, scopes []string) *mockAPIKeyRepository {
+	return &mockAPIKeyRepository{keys: map[string]*entity.APIKey{
+		randtoken.Hash(key): {ID: uuid.NewString(), UserUUID: userUUID, Scopes: scopes},
+	}}
+}
+
+func (m *mockAPIKeyRepository) SaveAPIKey(apiKey *entity.APIKey) *restErr.RestErr {
+	apiKey.ID = uuid.NewString()
+	m.keys[apiKey.KeyHash] = apiKey
+	return nil
+}
+
+func (m *mockAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*entity.APIKey, *restErr.RestErr) {
+	apiKey, ok := m.keys[keyHash]
+	if !ok {
+		return nil, restErr.NewNotFoundError(restErr.ErrMsgAPIKeyNotFound)
+	}
+	return apiKey, nil
+}
+
+func (m *mockAPIKeyRepository) UpdateAPIKeyLastUsed(keyID string, ipAddress string) *restErr.RestErr {
+	return nil
+}
+
+// mockRoleRepository grants the permissions of `mockRolePermissions`.
+type mockRoleRepository struct {
+	rp.PostgresRoleRepository
+}
+
+func (m *mockRoleRepository) GetPermissions(roleNames []string) ([]string, *restErr.RestErr) {
+	permissions := []string{}
+	for _, roleName := range roleNames {
+		permissions = append(permissions, mockRolePermissions[roleName]...)
+	}
+	return permissions, nil
+}